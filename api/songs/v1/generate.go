@@ -0,0 +1,4 @@
+// Package songsv1 содержит gRPC API песен, сгенерированный из songs.proto
+package songsv1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative ../../../api/songs/v1/songs.proto
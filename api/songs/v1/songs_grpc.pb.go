@@ -0,0 +1,373 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.1
+// source: api/songs/v1/songs.proto
+
+package songsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SongService_List_FullMethodName      = "/songs.v1.SongService/List"
+	SongService_Get_FullMethodName       = "/songs.v1.SongService/Get"
+	SongService_GetLyrics_FullMethodName = "/songs.v1.SongService/GetLyrics"
+	SongService_Create_FullMethodName    = "/songs.v1.SongService/Create"
+	SongService_Update_FullMethodName    = "/songs.v1.SongService/Update"
+	SongService_Delete_FullMethodName    = "/songs.v1.SongService/Delete"
+	SongService_Export_FullMethodName    = "/songs.v1.SongService/Export"
+)
+
+// SongServiceClient is the client API for SongService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SongServiceClient interface {
+	// List returns a page of songs matching the filter.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Get returns a song by ID.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Song, error)
+	// GetLyrics returns a page of verses of a song.
+	GetLyrics(ctx context.Context, in *GetLyricsRequest, opts ...grpc.CallOption) (*GetLyricsResponse, error)
+	// Create adds a song.
+	Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Song, error)
+	// Update changes a song. Empty fields keep their values.
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Song, error)
+	// Delete removes a song.
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Export streams all songs matching the filter in the order of List.
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (SongService_ExportClient, error)
+}
+
+type songServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSongServiceClient(cc grpc.ClientConnInterface) SongServiceClient {
+	return &songServiceClient{cc}
+}
+
+func (c *songServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, SongService_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*Song, error) {
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) GetLyrics(ctx context.Context, in *GetLyricsRequest, opts ...grpc.CallOption) (*GetLyricsResponse, error) {
+	out := new(GetLyricsResponse)
+	err := c.cc.Invoke(ctx, SongService_GetLyrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) Create(ctx context.Context, in *CreateRequest, opts ...grpc.CallOption) (*Song, error) {
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*Song, error) {
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_Update_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, SongService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (SongService_ExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SongService_ServiceDesc.Streams[0], SongService_Export_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &songServiceExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SongService_ExportClient interface {
+	Recv() (*Song, error)
+	grpc.ClientStream
+}
+
+type songServiceExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *songServiceExportClient) Recv() (*Song, error) {
+	m := new(Song)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SongServiceServer is the server API for SongService service.
+// All implementations must embed UnimplementedSongServiceServer
+// for forward compatibility
+type SongServiceServer interface {
+	// List returns a page of songs matching the filter.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Get returns a song by ID.
+	Get(context.Context, *GetRequest) (*Song, error)
+	// GetLyrics returns a page of verses of a song.
+	GetLyrics(context.Context, *GetLyricsRequest) (*GetLyricsResponse, error)
+	// Create adds a song.
+	Create(context.Context, *CreateRequest) (*Song, error)
+	// Update changes a song. Empty fields keep their values.
+	Update(context.Context, *UpdateRequest) (*Song, error)
+	// Delete removes a song.
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	// Export streams all songs matching the filter in the order of List.
+	Export(*ExportRequest, SongService_ExportServer) error
+	mustEmbedUnimplementedSongServiceServer()
+}
+
+// UnimplementedSongServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSongServiceServer struct {
+}
+
+func (UnimplementedSongServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedSongServiceServer) Get(context.Context, *GetRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedSongServiceServer) GetLyrics(context.Context, *GetLyricsRequest) (*GetLyricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLyrics not implemented")
+}
+func (UnimplementedSongServiceServer) Create(context.Context, *CreateRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedSongServiceServer) Update(context.Context, *UpdateRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedSongServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedSongServiceServer) Export(*ExportRequest, SongService_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedSongServiceServer) mustEmbedUnimplementedSongServiceServer() {}
+
+// UnsafeSongServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SongServiceServer will
+// result in compilation errors.
+type UnsafeSongServiceServer interface {
+	mustEmbedUnimplementedSongServiceServer()
+}
+
+func RegisterSongServiceServer(s grpc.ServiceRegistrar, srv SongServiceServer) {
+	s.RegisterService(&SongService_ServiceDesc, srv)
+}
+
+func _SongService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).Get(ctx, req.(*GetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_GetLyrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLyricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).GetLyrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_GetLyrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).GetLyrics(ctx, req.(*GetLyricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).Create(ctx, req.(*CreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SongServiceServer).Export(m, &songServiceExportServer{stream})
+}
+
+type SongService_ExportServer interface {
+	Send(*Song) error
+	grpc.ServerStream
+}
+
+type songServiceExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *songServiceExportServer) Send(m *Song) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// SongService_ServiceDesc is the grpc.ServiceDesc for SongService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SongService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "songs.v1.SongService",
+	HandlerType: (*SongServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _SongService_List_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _SongService_Get_Handler,
+		},
+		{
+			MethodName: "GetLyrics",
+			Handler:    _SongService_GetLyrics_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _SongService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _SongService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _SongService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Export",
+			Handler:       _SongService_Export_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/songs/v1/songs.proto",
+}
@@ -5,25 +5,64 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/testTask/internal/config"
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/graph"
 	"github.com/testTask/internal/handlers"
+	"github.com/testTask/internal/metrics"
 	"github.com/testTask/internal/middleware"
 	"github.com/testTask/internal/repository"
 	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/tracing"
+	"github.com/testTask/internal/webhooks"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
+// serviceName имя сервиса в трейсах
+const serviceName = "music-library"
+
 // App структура приложения
 type App struct {
-	config     *config.Config
-	logger     *zap.Logger
-	db         *sql.DB
+	config *config.Config
+	logger *zap.Logger
+	pool   *pgxpool.Pool
+	db     *sql.DB
+	repo   repository.SongRepository
+	// webhookRepo вебхуки и очередь доставки событий в том же хранилище, что и песни
+	webhookRepo repository.WebhookRepository
+	// cache кэш поверх repo, nil если кэш выключен
+	cache      *repository.CachedSongRepository
+	events     *events.Bus
 	httpServer *http.Server
+	// grpcServer gRPC API песен, nil если он выключен
+	grpcServer *grpc.Server
+	// stopGRPCServer останавливает gRPC сервер, запущенный в Run
+	stopGRPCServer func(ctx context.Context)
+
+	shutdownTracing tracing.ShutdownFunc
+	metricsHandler  http.Handler
+	// stopListener останавливает слушателя изменений песен, запущенного в Run
+	stopListener func()
+	// dispatcher доставляет события на вебхуки, nil если доставка выключена
+	dispatcher *webhooks.Dispatcher
+	// stopDispatcher останавливает диспетчер, запущенный в Run
+	stopDispatcher func()
+	// sync отдает изменения для синхронизации и забывает старые удаления
+	sync *service.SyncService
+	// stopPruner останавливает чистку старых удалений, запущенную в Run
+	stopPruner func()
+
+	// expectedMigrationVersion последняя версия миграций, известная приложению
+	expectedMigrationVersion uint
+	// shuttingDown выставляется в начале остановки, чтобы /readyz сразу отвечал отказом
+	shuttingDown atomic.Bool
 }
 
 // New конструктор нового экземпляра приложения
@@ -31,86 +70,237 @@ func New(cfg *config.Config, logger *zap.Logger) *App {
 	return &App{
 		config: cfg,
 		logger: logger,
+		events: events.NewBus(logger),
 	}
 }
 
 // Initialize инициализирует компоненты приложения
 func (a *App) Initialize() error {
-	// Создаем базу данных, если она не существует
-	if err := a.createDatabaseIfNotExists(); err != nil {
-		return fmt.Errorf("failed to create database: %w", err)
+	if err := a.initTracing(); err != nil {
+		return fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+
+	metricsHandler, err := metrics.Init(serviceName)
+	if err != nil {
+		return fmt.Errorf("failed to initialize metrics: %w", err)
 	}
+	a.metricsHandler = metricsHandler
 
-	if err := a.initDatabase(); err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+	if err := a.InitStorage(); err != nil {
+		return err
 	}
 
-	if err := a.runMigrations(); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+	// Схема проверяется только у баз данных, памяти миграции не нужны
+	if a.config.Storage != config.StorageMemory {
+		if err := a.prepareSchema(); err != nil {
+			return err
+		}
+	}
+
+	if a.config.Webhooks.Enabled {
+		dispatcher, err := webhooks.NewDispatcher(a.webhookRepo, webhooks.Config{
+			PollInterval:    a.config.Webhooks.PollInterval,
+			Timeout:         a.config.Webhooks.Timeout,
+			MaxAttempts:     a.config.Webhooks.MaxAttempts,
+			RetryBackoff:    a.config.Webhooks.RetryBackoff,
+			RetryMaxBackoff: a.config.Webhooks.RetryMaxBackoff,
+		}, a.logger)
+		if err != nil {
+			return fmt.Errorf("failed to initialize webhook dispatcher: %w", err)
+		}
+		a.dispatcher = dispatcher
 	}
 
 	if err := a.initHTTPServer(); err != nil {
 		return fmt.Errorf("failed to initialize HTTP server: %w", err)
 	}
+	if a.config.GRPC.Enabled {
+		if err := a.initGRPCServer(); err != nil {
+			return fmt.Errorf("failed to initialize gRPC server: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// initTracing настраивает экспорт трейсов OpenTelemetry
+func (a *App) initTracing() error {
+	shutdown, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName:  serviceName,
+		Exporter:     a.config.Tracing.Exporter,
+		OTLPEndpoint: a.config.Tracing.OTLPEndpoint,
+		OTLPInsecure: a.config.Tracing.OTLPInsecure,
+		OutputFile:   a.config.Tracing.OutputFile,
+	})
+	if err != nil {
+		return err
+	}
+	a.shutdownTracing = shutdown
+	a.logger.Info("Tracing initialized", zap.String("exporter", a.config.Tracing.Exporter))
+	return nil
+}
+
 // initHTTPServer инициализирует HTTP сервер
 func (a *App) initHTTPServer() error {
 
 	// Инициализируем репозиторий, сервис и обработчики
-	repo := repository.NewPostgresSongRepository(a.db)
-	svc := service.NewSongService(repo, a.logger)
+	svc := service.NewSongService(a.repo, a.logger)
 	handler := handlers.NewSongHandler(svc, a.logger)
+	webhookHandler := handlers.NewWebhookHandler(service.NewWebhookService(a.webhookRepo, a.logger), a.logger)
+	eventHandler := handlers.NewEventHandler(service.NewEventService(a.repo, a.logger), a.events, handlers.EventStreamOptions{
+		PollInterval:      a.config.Events.PollInterval,
+		HeartbeatInterval: a.config.Events.HeartbeatInterval,
+	}, a.logger)
+	a.sync = service.NewSyncService(a.repo, a.logger)
+	syncHandler := handlers.NewSyncHandler(a.sync, a.logger)
+	executor, err := graph.NewExecutor(svc, graph.Limits{
+		MaxDepth:      a.config.GraphQL.MaxDepth,
+		MaxComplexity: a.config.GraphQL.MaxComplexity,
+	}, a.logger)
+	if err != nil {
+		return fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	graphqlHandler := handlers.NewGraphQLHandler(executor, a.logger)
+	healthHandler := handlers.NewHealthHandler(a.healthChecks(), a.shuttingDown.Load, a.logger)
 
 	// Создаем роутер и регистрируем маршруты
 	r := mux.NewRouter()
 
-	// Добавляем middleware для логирования
+	// Добавляем middleware для трассировки, идентификатора запроса и логирования
+	r.Use(middleware.TracingMiddleware())
+	r.Use(middleware.RequestIDMiddleware(a.logger))
 	r.Use(middleware.LoggingMiddleware(a.logger))
 
+	// Проверки живости и готовности для оркестратора
+	r.HandleFunc("/healthz", healthHandler.Liveness).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods(http.MethodGet)
+	r.Handle("/metrics", a.metricsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/graphql", graphqlHandler.Execute).Methods(http.MethodPost)
+
 	api := r.PathPrefix("/api/v1").Subrouter()
 	api.HandleFunc("/songs", handler.GetSongs).Methods(http.MethodGet)
+	// by-key регистрируется раньше /songs/{id}, иначе "by-key" попадет в {id}
+	api.HandleFunc("/songs/by-key", handler.GetSongByKey).Methods(http.MethodGet)
+	api.HandleFunc("/songs/by-key", handler.UpsertSongByKey).Methods(http.MethodPut)
+	api.HandleFunc("/songs/{id}", handler.GetSong).Methods(http.MethodGet)
 	api.HandleFunc("/songs/{id}/lyrics", handler.GetLyrics).Methods(http.MethodGet)
 	api.HandleFunc("/songs", handler.CreateSong).Methods(http.MethodPost)
 	api.HandleFunc("/songs/{id}", handler.UpdateSong).Methods(http.MethodPut)
 	api.HandleFunc("/songs/{id}", handler.DeleteSong).Methods(http.MethodDelete)
 
+	api.HandleFunc("/events", eventHandler.StreamEvents).Methods(http.MethodGet)
+	api.HandleFunc("/sync", syncHandler.GetChanges).Methods(http.MethodGet)
+
+	api.HandleFunc("/webhooks", webhookHandler.GetWebhooks).Methods(http.MethodGet)
+	api.HandleFunc("/webhooks", webhookHandler.CreateWebhook).Methods(http.MethodPost)
+	api.HandleFunc("/webhooks/{id}", webhookHandler.DeleteWebhook).Methods(http.MethodDelete)
+	api.HandleFunc("/webhooks/{id}/deliveries", webhookHandler.GetDeliveries).Methods(http.MethodGet)
+	api.HandleFunc("/webhooks/{id}/replay", webhookHandler.ReplayDeliveries).Methods(http.MethodPost)
+
 	// Swagger
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
 	// Создаем HTTP сервер
 	a.httpServer = &http.Server{
-		Addr:         ":" + a.config.ServerPort,
+		Addr:         a.config.Server.Addr(),
 		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  a.config.Server.ReadTimeout,
+		WriteTimeout: a.config.Server.WriteTimeout,
+		IdleTimeout:  a.config.Server.IdleTimeout,
 	}
+	// Потоки событий не завершаются сами, Shutdown их закрывает, а не ждет
+	a.httpServer.RegisterOnShutdown(eventHandler.Close)
 
 	return nil
 }
 
+// Handler возвращает обработчик всех HTTP маршрутов приложения, доступен после Initialize
+func (a *App) Handler() http.Handler {
+	return a.httpServer.Handler
+}
+
 // Run запуск приложения
 func (a *App) Run() error {
-	a.logger.Info("Starting server", zap.String("port", a.config.ServerPort))
-	if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	a.logger.Info("Starting server",
+		zap.Int("port", a.config.Server.Port),
+		zap.Bool("tls", a.config.Server.TLSEnabled()))
+
+	// Изменения от других экземпляров приходят через LISTEN/NOTIFY, это есть только в PostgreSQL
+	if a.config.Storage == config.StoragePostgres {
+		a.startListener()
+	}
+	if a.dispatcher != nil {
+		a.startDispatcher()
+	}
+	if a.config.Sync.TombstoneRetention > 0 {
+		a.startPruner()
+	}
+	if a.grpcServer != nil {
+		if err := a.startGRPCServer(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if a.config.Server.TLSEnabled() {
+		err = a.httpServer.ListenAndServeTLS(a.config.Server.TLSCertFile, a.config.Server.TLSKeyFile)
+	} else {
+		err = a.httpServer.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
 	return nil
 }
 
+// Close закрывает подключение к базе данных и трассировку.
+// Используется командами, которые не запускают HTTP сервер.
+func (a *App) Close(ctx context.Context) error {
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			return fmt.Errorf("failed to close database connection: %w", err)
+		}
+	}
+	if a.pool != nil {
+		a.pool.Close()
+	}
+	if a.shutdownTracing != nil {
+		if err := a.shutdownTracing(ctx); err != nil {
+			return fmt.Errorf("failed to shutdown tracing: %w", err)
+		}
+	}
+	return nil
+}
+
 // Shutdown gracefully останавливает приложение
 func (a *App) Shutdown(ctx context.Context) error {
 	a.logger.Info("Shutting down server...")
 
+	// Сначала переводим /readyz в отказ и даем балансировщику время снять трафик
+	a.shuttingDown.Store(true)
+	if delay := a.config.Server.ShutdownDrainDelay; delay > 0 {
+		a.logger.Info("Waiting for load balancers to drain traffic", zap.Duration("delay", delay))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
 	if err := a.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
-
-	if err := a.db.Close(); err != nil {
-		return fmt.Errorf("failed to close database connection: %w", err)
+	if a.stopGRPCServer != nil {
+		a.stopGRPCServer(ctx)
+	}
+	if a.stopListener != nil {
+		a.stopListener()
+	}
+	if a.stopDispatcher != nil {
+		a.stopDispatcher()
+	}
+	if a.stopPruner != nil {
+		a.stopPruner()
 	}
 
-	return nil
+	return a.Close(ctx)
 }
@@ -1,93 +1,263 @@
 package app
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"math"
+	"time"
+
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pkg/errors"
+	"github.com/testTask/internal/config"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/migrations"
 	"go.uber.org/zap"
 )
 
-// initDatabase инициализирует подключение к базе данных
+// Параметры ожидания базы данных при запуске
+const (
+	connectInitialBackoff = 500 * time.Millisecond
+	connectMaxBackoff     = 10 * time.Second
+)
+
+// statementCacheCapacity сколько подготовленных выражений хранить на соединении
+const statementCacheCapacity = 512
+
+// InitDatabase создает базу данных, если это включено, открывает пул соединений,
+// дожидается доступности базы и запоминает ожидаемую версию схемы
+func (a *App) InitDatabase() error {
+	if a.config.DB.CreateDatabase {
+		if err := a.createDatabaseIfNotExists(); err != nil {
+			return fmt.Errorf("failed to create database: %w", err)
+		}
+	}
+
+	if err := a.initDatabase(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+
+	// Запоминаем ожидаемую версию схемы для проверки готовности
+	version, err := latestMigrationVersion(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+	a.expectedMigrationVersion = version
+
+	return nil
+}
+
+// prepareSchema применяет миграции или проверяет, что они применены, и сверяет схему
+func (a *App) prepareSchema() error {
+	// Миграции при старте применяются только если это разрешено конфигурацией,
+	// иначе их запускают отдельно командой migrate
+	if a.config.DB.AutoMigrate {
+		if err := a.runMigrations(); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+	} else if _, err := a.checkMigrations(context.Background()); err != nil {
+		// Без автоматических миграций схема должна быть уже обновлена командой migrate up
+		return fmt.Errorf("database schema is not up to date, run \"migrate up\": %w", err)
+	}
+
+	// Расхождение схемы проверяется только для PostgreSQL, у SQLite своя схема
+	if a.config.Storage == config.StoragePostgres {
+		if err := a.CheckSchemaDrift(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postgresRepository возвращает репозиторий песен поверх открытого пула соединений
+func (a *App) postgresRepository() repository.SongRepository {
+	return repository.NewPostgresSongRepository(a.pool, a.logger,
+		repository.WithIsolation(repository.IsolationLevel(a.config.DB.TxIsolation)),
+		repository.WithMaxRetries(a.config.DB.TxMaxRetries),
+	)
+}
+
+// initDatabase открывает пул соединений pgx и дожидается доступности базы.
+// Для миграций и служебных запросов поверх пула открывается database/sql обертка.
 func (a *App) initDatabase() error {
-	connStr := a.config.GetDBConnString()
-	db, err := sql.Open("postgres", connStr)
+	pool, err := a.newPool(a.config.GetDBConnString())
 	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+		return err
 	}
-	a.db = db
+
+	// Пул подключается лениво, поэтому проверяем доступность явно
+	if err := a.waitForDatabase(context.Background(), a.config.DB.Name, pool.Ping); err != nil {
+		pool.Close()
+		return err
+	}
+
+	a.pool = pool
+	a.db = stdlib.OpenDBFromPool(pool)
 	return nil
 }
 
-// runMigrations запускает миграции базы данных
-func (a *App) runMigrations() error {
-	driver, err := postgres.WithInstance(a.db, &postgres.Config{})
+// newPool создает пул соединений pgx с настройками из конфигурации
+func (a *App) newPool(connString string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		return fmt.Errorf("failed to create database driver: %w", err)
+		return nil, fmt.Errorf("failed to parse database connection string: %w", err)
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"postgres", driver)
+	poolConfig.MaxConns = int32(a.config.DB.MaxOpenConns)
+	poolConfig.MinConns = int32(a.config.DB.MinConns)
+	poolConfig.MaxConnLifetime = unlimitedIfZero(a.config.DB.ConnMaxLifetime)
+	poolConfig.MaxConnIdleTime = unlimitedIfZero(a.config.DB.ConnMaxIdleTime)
+	// Каждое выражение готовится на соединении один раз и берется из кэша
+	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	poolConfig.ConnConfig.StatementCacheCapacity = statementCacheCapacity
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		a.logger.Error("Failed to create migration instance", zap.Error(err))
-		// Продолжим работу, даже если есть проблемы с миграциями
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	return pool, nil
+}
+
+// unlimitedIfZero переводит 0 из конфигурации в отсутствие ограничения,
+// для pgxpool нулевое время жизни означает немедленное закрытие соединения
+func unlimitedIfZero(d time.Duration) time.Duration {
+	if d == 0 {
+		return math.MaxInt64
+	}
+	return d
+}
+
+// waitForDatabase пингует базу с экспоненциальной задержкой, пока она не ответит
+// или не истечет db.connect_retry_timeout
+func (a *App) waitForDatabase(ctx context.Context, name string, ping func(context.Context) error) error {
+	retryTimeout := a.config.DB.ConnectRetryTimeout
+	if retryTimeout == 0 {
+		if err := ping(ctx); err != nil {
+			return fmt.Errorf("database %s is unreachable: %w", name, err)
+		}
 		return nil
 	}
 
-	err = m.Up()
-	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
-		a.logger.Error("Failed to apply migrations", zap.Error(err))
-		// Проверим, есть ли таблица songs
-		var exists bool
-		err = a.db.QueryRow(checkTableExistsQuery).Scan(&exists)
+	ctx, cancel := context.WithTimeout(ctx, retryTimeout)
+	defer cancel()
 
-		if err != nil {
-			a.logger.Error("Failed to check if songs table exists", zap.Error(err))
-		} else if !exists {
-			// Если таблицы нет, создаем её вручную
-			_, err = a.db.Exec(createSongsTableQuery)
-			if err != nil {
-				a.logger.Error("Failed to create songs table", zap.Error(err))
-				return fmt.Errorf("failed to create songs table: %w", err)
-			}
-			a.logger.Info("Created songs table manually")
+	backoff := connectInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		wait := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return fmt.Errorf("database %s is unreachable after %d attempts: %w", name, attempt, err)
+		case <-wait.C:
 		}
+
+		a.logger.Warn("Database is not reachable yet, retrying",
+			zap.String("database", name),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		backoff = min(backoff*2, connectMaxBackoff)
 	}
+}
+
+// NewMigrator создает экземпляр golang-migrate для выбранного хранилища.
+// Для PostgreSQL мигратор работает на отдельном соединении, и его закрытие
+// освобождает только это соединение, пул остается открытым.
+func (a *App) NewMigrator(ctx context.Context) (*migrate.Migrate, error) {
+	if a.config.Storage == config.StorageSQLite {
+		return a.newSQLiteMigrator()
+	}
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+
+	conn, err := a.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create database driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+	return m, nil
+}
+
+// runMigrations применяет миграции базы данных. Любая ошибка, включая
+// dirty состояние после неудачной миграции, останавливает запуск.
+func (a *App) runMigrations() error {
+	m, err := a.NewMigrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("database is in dirty state at migration version %d, fix the schema manually and run \"migrate force\"", version)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	a.logger.Info("Migrations applied", zap.Uint("version", a.expectedMigrationVersion))
 	return nil
 }
 
 // createDatabaseIfNotExists проверяет существование базы данных и создает её, если она не существует
 func (a *App) createDatabaseIfNotExists() error {
-	// Подключаемся к базе postgres для создания нашей базы данных
-	postgresConnStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-		a.config.DBHost,
-		a.config.DBPort,
-		a.config.DBUser,
-		a.config.DBPassword,
-	)
+	ctx := context.Background()
 
-	postgresDB, err := sql.Open("postgres", postgresConnStr)
+	// Подключаемся к базе postgres для создания нашей базы данных
+	postgresPool, err := a.newPool(a.config.GetDBConnStringWithoutDatabase())
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
-	defer postgresDB.Close()
+	defer postgresPool.Close()
+
+	if err := a.waitForDatabase(ctx, "postgres", postgresPool.Ping); err != nil {
+		return err
+	}
 
 	// Проверяем существование базы данных
 	var exists bool
-	err = postgresDB.QueryRow(checkDatabaseExistsQuery, a.config.DBName).Scan(&exists)
+	err = postgresPool.QueryRow(ctx, checkDatabaseExistsQuery, a.config.DB.Name).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("failed to check database existence: %w", err)
 	}
 
 	// Если база данных не существует, создаем её
 	if !exists {
-		_, err = postgresDB.Exec(fmt.Sprintf("CREATE DATABASE %s", a.config.DBName))
+		// Имя базы нельзя передать параметром, поэтому экранируем его как идентификатор
+		_, err = postgresPool.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{a.config.DB.Name}.Sanitize())
 		if err != nil {
 			return fmt.Errorf("failed to create database: %w", err)
 		}
-		a.logger.Info(fmt.Sprintf("Database %s created successfully", a.config.DBName))
+		a.logger.Info(fmt.Sprintf("Database %s created successfully", a.config.DB.Name))
 	}
 
 	return nil
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/testTask/internal/rpc"
+	"github.com/testTask/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// initGRPCServer создает gRPC сервер песен. С TLS файлами HTTP сервера он тоже принимает только TLS.
+func (a *App) initGRPCServer() error {
+	var options []grpc.ServerOption
+	if a.config.Server.TLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(a.config.Server.TLSCertFile, a.config.Server.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+
+	songs := rpc.NewSongServer(service.NewSongService(a.repo, a.logger), a.logger)
+	a.grpcServer = rpc.NewServer(songs, a.logger, options...)
+	return nil
+}
+
+// startGRPCServer занимает порт gRPC и принимает вызовы в отдельной горутине
+func (a *App) startGRPCServer() error {
+	listener, err := net.Listen("tcp", a.config.GRPC.Addr())
+	if err != nil {
+		return fmt.Errorf("failed to start gRPC server: %w", err)
+	}
+	a.logger.Info("Starting gRPC server", zap.Int("port", a.config.GRPC.Port))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := a.grpcServer.Serve(listener); err != nil {
+			a.logger.Error("gRPC server failed", zap.Error(err))
+		}
+	}()
+
+	a.stopGRPCServer = func(ctx context.Context) {
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			a.grpcServer.GracefulStop()
+		}()
+		// Выгрузки могут идти долго, не успевшие до конца времени остановки обрываются
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			a.grpcServer.Stop()
+			<-stopped
+		}
+		<-done
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/pkg/errors"
+	"github.com/testTask/internal/config"
+	"github.com/testTask/internal/handlers"
+)
+
+// healthChecks возвращает проверки зависимостей для /readyz
+func (a *App) healthChecks() []handlers.HealthCheck {
+	if a.config.Storage == config.StorageMemory {
+		return []handlers.HealthCheck{
+			{Name: "storage", Check: checkMemoryStorage},
+		}
+	}
+	return []handlers.HealthCheck{
+		{Name: "database", Check: a.checkDatabase},
+		{Name: "migrations", Check: a.checkMigrations},
+	}
+}
+
+// checkMemoryStorage хранилище в памяти доступно всегда
+func checkMemoryStorage(context.Context) (string, error) {
+	return "in-memory", nil
+}
+
+// checkDatabase проверяет доступность базы данных
+func (a *App) checkDatabase(ctx context.Context) (string, error) {
+	if a.pool == nil {
+		// SQLite работает через database/sql без пула pgx
+		if err := a.db.PingContext(ctx); err != nil {
+			return "", fmt.Errorf("database is unreachable: %w", err)
+		}
+		stats := a.db.Stats()
+		return fmt.Sprintf("open connections: %d, in use: %d", stats.OpenConnections, stats.InUse), nil
+	}
+
+	if err := a.pool.Ping(ctx); err != nil {
+		return "", fmt.Errorf("database is unreachable: %w", err)
+	}
+	stats := a.pool.Stat()
+	return fmt.Sprintf("open connections: %d, in use: %d", stats.TotalConns(), stats.AcquiredConns()), nil
+}
+
+// checkMigrations проверяет, что схема базы данных на ожидаемой версии миграций
+func (a *App) checkMigrations(ctx context.Context) (string, error) {
+	if a.expectedMigrationVersion == 0 {
+		return "", fmt.Errorf("expected migration version is unknown")
+	}
+
+	var version uint
+	var dirty bool
+	err := a.db.QueryRowContext(ctx, getMigrationVersionQuery).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("no migrations applied, expected version %d", a.expectedMigrationVersion)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if dirty {
+		return "", fmt.Errorf("migration version %d is dirty", version)
+	}
+	if version != a.expectedMigrationVersion {
+		return "", fmt.Errorf("migration version %d, expected %d", version, a.expectedMigrationVersion)
+	}
+
+	return fmt.Sprintf("version %d", version), nil
+}
+
+// latestMigrationVersion возвращает номер последней встроенной миграции из каталога dir
+func latestMigrationVersion(fsys fs.FS, dir string) (uint, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read first migration: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/testTask/internal/events"
+	"go.uber.org/zap"
+)
+
+// songChangesChannel канал NOTIFY, в который триггер songs_notify_change
+// публикует изменения песен, см. миграцию 000004
+const songChangesChannel = "song_changes"
+
+// songChangeOperations виды изменений по операции триггера
+var songChangeOperations = map[string]events.Type{
+	"INSERT": events.SongCreated,
+	"UPDATE": events.SongUpdated,
+	"DELETE": events.SongDeleted,
+}
+
+// songChangePayload содержимое уведомления об изменении песни
+type songChangePayload struct {
+	Op        string `json:"op"`
+	ID        int    `json:"id"`
+	GroupName string `json:"group_name"`
+}
+
+// Events возвращает шину событий об изменении песен на всех экземплярах сервиса
+func (a *App) Events() *events.Bus {
+	return a.events
+}
+
+// startListener запускает слушателя изменений песен в отдельной горутине
+func (a *App) startListener() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.listenSongChanges(ctx)
+	}()
+
+	a.stopListener = func() {
+		cancel()
+		<-done
+	}
+}
+
+// startDispatcher запускает доставку событий на вебхуки в отдельной горутине
+func (a *App) startDispatcher() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.dispatcher.Run(ctx)
+	}()
+
+	a.stopDispatcher = func() {
+		cancel()
+		<-done
+	}
+}
+
+// listenSongChanges слушает изменения песен до отмены ctx.
+// При обрыве соединения переподключается с экспоненциальной задержкой.
+func (a *App) listenSongChanges(ctx context.Context) {
+	backoff := connectInitialBackoff
+	for {
+		err := a.listenOnce(ctx, func() { backoff = connectInitialBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+
+		a.logger.Warn("Song change listener disconnected, reconnecting",
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		wait := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return
+		case <-wait.C:
+		}
+		backoff = min(backoff*2, connectMaxBackoff)
+	}
+}
+
+// listenOnce открывает отдельное от пула соединение, подписывается на канал
+// и обрабатывает уведомления, пока соединение живо
+func (a *App) listenOnce(ctx context.Context, connected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, a.pool.Config().ConnConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+songChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen channel %s: %w", songChangesChannel, err)
+	}
+	connected()
+	a.logger.Info("Listening for song changes", zap.String("channel", songChangesChannel))
+
+	// Пока соединения не было, уведомления могли потеряться,
+	// поэтому кэш сбрасывается целиком
+	if a.cache != nil {
+		a.cache.Purge()
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		event, err := parseSongChange(notification.Payload)
+		if err != nil {
+			a.logger.Warn("Invalid song change notification",
+				zap.String("payload", notification.Payload),
+				zap.Error(err))
+			continue
+		}
+
+		// Свои изменения тоже приходят сюда, повторный сброс кэша безвреден
+		if a.cache != nil {
+			a.cache.Invalidate(event.SongID)
+		}
+		a.events.Publish(event)
+		// Вместе с изменением песни в outbox записано событие, его можно доставлять сразу
+		if a.dispatcher != nil {
+			a.dispatcher.Wake()
+		}
+	}
+}
+
+// parseSongChange разбирает уведомление триггера songs_notify_change
+func parseSongChange(payload string) (events.Event, error) {
+	var p songChangePayload
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		return events.Event{}, err
+	}
+	eventType, ok := songChangeOperations[p.Op]
+	if !ok {
+		return events.Event{}, fmt.Errorf("unknown operation %q", p.Op)
+	}
+	return events.Event{Type: eventType, SongID: p.ID, GroupName: p.GroupName}, nil
+}
@@ -10,28 +10,14 @@ const (
 			WHERE datname = $1
 		)`
 
-	// checkTableExistsQuery проверяет существование таблицы songs
-	checkTableExistsQuery = `
-		SELECT EXISTS (
-			SELECT 1 FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'songs'
-		)`
+	// getMigrationVersionQuery возвращает текущую версию миграций golang-migrate
+	getMigrationVersionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
 
-	// createSongsTableQuery создает таблицу songs, если она не существует.
-	// Этот запрос дублирует миграцию 000001_init_schema.up.sql и используется
-	// только как резервный механизм, если миграции не удалось применить.
-	// В нормальном режиме таблица создается через миграции.
-	createSongsTableQuery = `
-		CREATE TABLE IF NOT EXISTS songs (
-			id SERIAL PRIMARY KEY,
-			group_name VARCHAR(255) NOT NULL,
-			song_name VARCHAR(255) NOT NULL,
-			release_date TIMESTAMP NOT NULL,
-			text TEXT,
-			link VARCHAR(255),
-			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
-			UNIQUE(group_name, song_name)
-		)`
+	// getTableColumnsQuery возвращает колонки таблицы для проверки расхождения схемы
+	getTableColumnsQuery = `
+		SELECT column_name, data_type, COALESCE(character_maximum_length, 0), is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema = current_schema()
+		AND table_name = $1
+		ORDER BY ordinal_position`
 )
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/testTask/internal/config"
+	"github.com/testTask/migrations"
+)
+
+// songsTable таблица, схема которой сверяется с миграциями
+const songsTable = "songs"
+
+// CheckSchemaDrift сравнивает живую таблицу songs с тем, что должны дать миграции,
+// и возвращает ошибку со списком всех расхождений
+func (a *App) CheckSchemaDrift(ctx context.Context) error {
+	if a.config.Storage != config.StoragePostgres {
+		return fmt.Errorf("schema drift check is available only for %s storage", config.StoragePostgres)
+	}
+
+	live, err := a.tableColumns(ctx, songsTable)
+	if err != nil {
+		return err
+	}
+
+	var drift []string
+	expected := make(map[string]bool, len(migrations.SongsColumns))
+	for _, want := range migrations.SongsColumns {
+		expected[want.Name] = true
+		got, ok := live[want.Name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("column %s is missing", want.Name))
+			continue
+		}
+		if got != want {
+			drift = append(drift, fmt.Sprintf("column %s is %s, expected %s", want.Name, describeColumn(got), describeColumn(want)))
+		}
+	}
+	for name, got := range live {
+		if !expected[name] {
+			drift = append(drift, fmt.Sprintf("unexpected column %s %s", name, describeColumn(got)))
+		}
+	}
+
+	if len(drift) > 0 {
+		return fmt.Errorf("schema of table %s differs from migrations: %s", songsTable, strings.Join(drift, "; "))
+	}
+	return nil
+}
+
+// tableColumns читает колонки таблицы из information_schema
+func (a *App) tableColumns(ctx context.Context, table string) (map[string]migrations.Column, error) {
+	rows, err := a.db.QueryContext(ctx, getTableColumnsQuery, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]migrations.Column)
+	for rows.Next() {
+		var c migrations.Column
+		if err := rows.Scan(&c.Name, &c.DataType, &c.MaxLength, &c.Nullable); err != nil {
+			return nil, fmt.Errorf("failed to scan column of table %s: %w", table, err)
+		}
+		columns[c.Name] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	return columns, nil
+}
+
+// describeColumn форматирует колонку для сообщения о расхождении
+func describeColumn(c migrations.Column) string {
+	description := c.DataType
+	if c.MaxLength > 0 {
+		description = fmt.Sprintf("%s(%d)", description, c.MaxLength)
+	}
+	if !c.Nullable {
+		description += " NOT NULL"
+	}
+	return description
+}
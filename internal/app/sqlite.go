@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/testTask/migrations"
+	"go.uber.org/zap"
+)
+
+// sqliteDriver имя драйвера modernc.org/sqlite в database/sql
+const sqliteDriver = "sqlite"
+
+// initSQLite открывает файл SQLite и запоминает ожидаемую версию его схемы
+func (a *App) initSQLite() error {
+	db, err := sql.Open(sqliteDriver, a.config.GetSQLiteDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	if err := db.PingContext(context.Background()); err != nil {
+		_ = db.Close()
+		return fmt.Errorf("failed to open SQLite database %s: %w", a.config.SQLite.Path, err)
+	}
+	a.db = db
+
+	version, err := latestMigrationVersion(migrations.SQLiteFS, migrations.SQLiteDir)
+	if err != nil {
+		return err
+	}
+	a.expectedMigrationVersion = version
+
+	a.logger.Info("SQLite database opened", zap.String("path", a.config.SQLite.Path))
+	return nil
+}
+
+// newSQLiteMigrator создает мигратор SQLite. Драйвер миграций закрывает базу
+// вместе с собой, поэтому для него открывается отдельное подключение к файлу.
+func (a *App) newSQLiteMigrator() (*migrate.Migrate, error) {
+	src, err := iofs.New(migrations.SQLiteFS, migrations.SQLiteDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded migrations: %w", err)
+	}
+
+	db, err := sql.Open(sqliteDriver, a.config.GetSQLiteDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create database driver: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite", driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, fmt.Errorf("failed to create migration instance: %w", err)
+	}
+	return m, nil
+}
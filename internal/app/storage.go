@@ -0,0 +1,52 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/testTask/internal/config"
+	"github.com/testTask/internal/repository"
+)
+
+// InitStorage открывает хранилище песен, выбранное настройкой storage.
+// Для баз данных подключается к ним, но миграции не применяет.
+func (a *App) InitStorage() error {
+	switch a.config.Storage {
+	case config.StorageMemory:
+		a.logger.Warn("Using in-memory storage, songs are lost when the process stops")
+		songs := repository.NewMemorySongRepository()
+		a.repo = songs
+		a.webhookRepo = repository.NewMemoryWebhookRepository(songs)
+	case config.StorageSQLite:
+		if err := a.initSQLite(); err != nil {
+			return err
+		}
+		a.repo = repository.NewSQLiteSongRepository(a.db, a.logger,
+			repository.WithMaxRetries(a.config.DB.TxMaxRetries))
+		a.webhookRepo = repository.NewSQLiteWebhookRepository(a.db, a.logger)
+	default:
+		if err := a.InitDatabase(); err != nil {
+			return err
+		}
+		a.repo = a.postgresRepository()
+		a.webhookRepo = repository.NewPostgresWebhookRepository(a.pool, a.logger)
+	}
+
+	// Хранилищу в памяти кэш не нужен
+	if a.config.Cache.Enabled && a.config.Storage != config.StorageMemory {
+		cache, err := repository.NewCachedSongRepository(a.repo, repository.CacheOptions{
+			Size: a.config.Cache.Size,
+			TTL:  a.config.Cache.TTL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize cache: %w", err)
+		}
+		a.cache = cache
+		a.repo = cache
+	}
+	return nil
+}
+
+// SongRepository возвращает репозиторий открытого хранилища
+func (a *App) SongRepository() repository.SongRepository {
+	return a.repo
+}
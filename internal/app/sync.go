@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// tombstonePruneInterval как часто забывать удаления песен старше sync.tombstone_retention
+const tombstonePruneInterval = time.Hour
+
+// startPruner запускает чистку старых удалений песен в отдельной горутине
+func (a *App) startPruner() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.pruneTombstones(ctx)
+	}()
+
+	a.stopPruner = func() {
+		cancel()
+		<-done
+	}
+}
+
+// pruneTombstones забывает старые удаления при запуске и затем раз в tombstonePruneInterval
+// до отмены ctx. Несколько экземпляров могут чистить одновременно, это безвредно.
+func (a *App) pruneTombstones(ctx context.Context) {
+	ticker := time.NewTicker(tombstonePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.sync.PruneTombstones(ctx, a.config.Sync.TombstoneRetention); err != nil && ctx.Err() == nil {
+			a.logger.Warn("Failed to prune song tombstones", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// runCheckConfig выводит итоговые значения настроек. Некорректная конфигурация
+// отсекается еще в bootstrap, поэтому сюда доходит только валидная.
+func runCheckConfig(env *environment, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("check-config takes no arguments, got %q", args)
+	}
+
+	settings := env.config.Redacted()
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "profile\t%s\t\n", env.config.Profile)
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t(%s)\n", key, settings[key].Value, settings[key].Source)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nConfiguration is valid")
+	return nil
+}
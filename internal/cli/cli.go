@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"github.com/testTask/internal/config"
+	"github.com/testTask/internal/logging"
+	"go.uber.org/zap"
+)
+
+// defaultCommand команда, если она не указана явно
+const defaultCommand = "serve"
+
+// command подкоманда бинарника
+type command struct {
+	name  string
+	args  string
+	usage string
+	// run выполняет команду, args - позиционные аргументы после флагов конфигурации
+	run func(env *environment, args []string) error
+}
+
+// environment общее окружение команд: конфигурация и логгер
+type environment struct {
+	config *config.Config
+	logger *zap.Logger
+}
+
+// commands все подкоманды в порядке вывода в справке
+var commands = []command{
+	{name: "serve", usage: "start the HTTP server (default)", run: runServe},
+	{name: "migrate", args: "up | down [N] | goto V | version | force V | check", usage: "manage database migrations", run: runMigrate},
+	{name: "import", args: "FILE", usage: "import songs from a .json or .csv file, - for JSON on stdin", run: runImport},
+	{name: "export", args: "FILE", usage: "export songs to a .json or .csv file, - for JSON on stdout", run: runExport},
+	{name: "seed", usage: "insert fixture songs for development", run: runSeed},
+	{name: "check-config", usage: "validate configuration and print effective settings", run: runCheckConfig},
+}
+
+// Run выполняет подкоманду и возвращает код выхода процесса
+func Run(args []string) int {
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	env, rest, err := bootstrap(args)
+	if errors.Is(err, config.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	// у логгеров тоже бывают проблемы. Sync для stderr на терминале или в пайпе
+	// возвращает EINVAL/ENOTTY, это не потеря логов, поэтому такие ошибки не выводим.
+	defer func(logger *zap.Logger) {
+		err := logger.Sync()
+		if err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTTY) {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to sync logger: %v\n", err)
+		}
+	}(env.logger)
+
+	if err := cmd.run(env, rest); err != nil {
+		env.logger.Error("Command failed", zap.String("command", name), zap.Error(err))
+		return 1
+	}
+	return 0
+}
+
+// bootstrap загружает .env, конфигурацию и создает логгер, общие для всех команд
+func bootstrap(args []string) (*environment, []string, error) {
+	// Загружаем переменные из .env файла, если он есть. В контейнерах
+	// конфигурация обычно приходит из окружения, и файла может не быть.
+	envFileLoaded := true
+	if err := godotenv.Load(); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, nil, fmt.Errorf("error loading .env file: %w", err)
+		}
+		envFileLoaded = false
+	}
+
+	// Загружаем конфигурацию: умолчания, профиль, файл, окружение и флаги
+	cfg, rest, err := config.Load(args)
+	if err != nil {
+		if errors.Is(err, config.ErrHelp) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	// Инициализируем логгер
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	// Логируем итоговые значения с их источниками, секреты скрыты
+	logger.Info("Configuration loaded",
+		zap.String("profile", cfg.Profile),
+		zap.Bool("env_file_loaded", envFileLoaded),
+		zap.Any("settings", cfg.Redacted()),
+	)
+
+	return &environment{config: cfg, logger: logger}, rest, nil
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: music-library [command] [config flags] [arguments]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.usage)
+		if cmd.args != "" {
+			_, _ = fmt.Fprintf(w, "  %-14s   %s %s\n", "", cmd.name, cmd.args)
+		}
+	}
+	_, _ = fmt.Fprintln(w, "\nConfig flags are shared by all commands, see music-library check-config --help.")
+}
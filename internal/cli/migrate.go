@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/testTask/internal/app"
+	"github.com/testTask/internal/config"
+	"go.uber.org/zap"
+)
+
+// runMigrate управляет миграциями базы данных
+func runMigrate(env *environment, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate requires a subcommand: up, down [N], goto V, version, force V, check")
+	}
+
+	if env.config.Storage == config.StorageMemory {
+		return fmt.Errorf("migrate is not available for %s storage", config.StorageMemory)
+	}
+
+	ctx := context.Background()
+	application := app.New(env.config, env.logger)
+	if err := application.InitStorage(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := application.Close(ctx); err != nil {
+			env.logger.Error("Failed to close application", zap.Error(err))
+		}
+	}()
+
+	m, err := application.NewMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	m.Log = &migrateLogger{logger: env.logger}
+
+	sub, subArgs := args[0], args[1:]
+	switch sub {
+	case "up":
+		err = m.Up()
+	case "down":
+		steps := 1
+		if len(subArgs) > 0 {
+			if steps, err = strconv.Atoi(subArgs[0]); err != nil || steps <= 0 {
+				return fmt.Errorf("migrate down: invalid number of steps %q", subArgs[0])
+			}
+		}
+		err = m.Steps(-steps)
+	case "goto":
+		version, parseErr := versionArg(sub, subArgs)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Migrate(uint(version))
+	case "force":
+		version, parseErr := versionArg(sub, subArgs)
+		if parseErr != nil {
+			return parseErr
+		}
+		err = m.Force(version)
+	case "version":
+		// Только выводим текущую версию ниже
+	case "check":
+		if err := application.CheckSchemaDrift(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Schema matches migrations")
+	default:
+		return fmt.Errorf("unknown migrate subcommand %q", sub)
+	}
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrate %s: %w", sub, err)
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("No migrations applied")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	fmt.Printf("Version: %d, dirty: %t\n", version, dirty)
+	return nil
+}
+
+// versionArg разбирает обязательный номер версии
+func versionArg(sub string, args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("migrate %s requires a version", sub)
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("migrate %s: invalid version %q", sub, args[0])
+	}
+	return version, nil
+}
+
+// migrateLogger передает вывод golang-migrate в zap
+type migrateLogger struct {
+	logger *zap.Logger
+}
+
+func (l *migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.Info(fmt.Sprintf(format, v...))
+}
+
+func (l *migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/testTask/internal/app"
+	"go.uber.org/zap"
+)
+
+// runServe запускает HTTP сервер и ждет сигнала остановки
+func runServe(env *environment, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("serve takes no arguments, got %q", args)
+	}
+
+	// Создаем приложение
+	application := app.New(env.config, env.logger)
+
+	// Инициализируем компоненты
+	if err := application.Initialize(); err != nil {
+		return fmt.Errorf("failed to initialize application: %w", err)
+	}
+
+	// Запускаем приложение в горутине
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- application.Run()
+	}()
+
+	// Ожидаем сигнал для graceful shutdown или падение сервера
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case <-quit:
+	case err := <-runErr:
+		if err != nil {
+			return fmt.Errorf("failed to run application: %w", err)
+		}
+	}
+
+	// Graceful shutdown с ограничением по времени из конфигурации
+	ctx, cancel := context.WithTimeout(context.Background(), env.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Останавливаем приложение
+	if err := application.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown application: %w", err)
+	}
+
+	env.logger.Info("Application stopped", zap.String("command", "serve"))
+	return nil
+}
@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"context"
+	"embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/testTask/internal/app"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+)
+
+// fixtures тестовые данные для команды seed
+//
+//go:embed fixtures/songs.json
+var fixtures embed.FS
+
+// exportPageSize размер страницы при выгрузке песен
+const exportPageSize = validation.MaxPageSize
+
+// dateLayout формат даты выпуска в файлах импорта и экспорта
+const dateLayout = "2006-01-02"
+
+// csvHeader колонки CSV файлов импорта и экспорта
+var csvHeader = []string{"group_name", "song_name", "release_date", "text", "link"}
+
+// songRecord песня в файлах импорта и экспорта
+type songRecord struct {
+	GroupName   string `json:"group_name"`
+	SongName    string `json:"song_name"`
+	ReleaseDate string `json:"release_date"`
+	Text        string `json:"text"`
+	Link        string `json:"link"`
+}
+
+// importStats итоги импорта
+type importStats struct {
+	created int
+	skipped int
+	failed  int
+}
+
+// runImport загружает песни из файла в базу данных
+func runImport(env *environment, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("import requires exactly one FILE argument")
+	}
+	path := args[0]
+
+	records, err := readRecords(path)
+	if err != nil {
+		return err
+	}
+
+	return withRepository(env, func(ctx context.Context, repo repository.SongRepository) error {
+		return importRecords(ctx, env.logger, repo, records)
+	})
+}
+
+// runSeed загружает тестовые песни из встроенных фикстур
+func runSeed(env *environment, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("seed takes no arguments, got %q", args)
+	}
+
+	content, err := fixtures.ReadFile("fixtures/songs.json")
+	if err != nil {
+		return fmt.Errorf("failed to read fixtures: %w", err)
+	}
+	var records []songRecord
+	if err := json.Unmarshal(content, &records); err != nil {
+		return fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+
+	return withRepository(env, func(ctx context.Context, repo repository.SongRepository) error {
+		return importRecords(ctx, env.logger, repo, records)
+	})
+}
+
+// runExport выгружает все песни из базы данных в файл
+func runExport(env *environment, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("export requires exactly one FILE argument")
+	}
+	path := args[0]
+
+	return withRepository(env, func(ctx context.Context, repo repository.SongRepository) error {
+		records, err := exportRecords(ctx, repo)
+		if err != nil {
+			return err
+		}
+		if err := writeRecords(path, records); err != nil {
+			return err
+		}
+		env.logger.Info("Export finished", zap.String("file", path), zap.Int("songs", len(records)))
+		return nil
+	})
+}
+
+// withRepository открывает хранилище на время выполнения fn
+func withRepository(env *environment, fn func(ctx context.Context, repo repository.SongRepository) error) error {
+	ctx := context.Background()
+	application := app.New(env.config, env.logger)
+	if err := application.InitStorage(); err != nil {
+		return err
+	}
+	defer func() {
+		if err := application.Close(ctx); err != nil {
+			env.logger.Error("Failed to close application", zap.Error(err))
+		}
+	}()
+
+	return fn(ctx, application.SongRepository())
+}
+
+// importRecords проверяет записи и загружает корректные одной пачкой,
+// уже существующие песни пропускаются
+func importRecords(ctx context.Context, logger *zap.Logger, repo repository.SongRepository, records []songRecord) error {
+	var stats importStats
+	songs := make([]models.Song, 0, len(records))
+	for i, record := range records {
+		song, err := record.toSong()
+		if err != nil {
+			stats.failed++
+			logger.Warn("Failed to import song",
+				zap.Int("record", i+1),
+				zap.String("group", record.GroupName),
+				zap.String("song", record.SongName),
+				zap.Error(err))
+			continue
+		}
+		songs = append(songs, *song)
+	}
+
+	if len(songs) > 0 {
+		created, err := repo.CreateSongs(ctx, songs)
+		if err != nil {
+			return fmt.Errorf("failed to import songs: %w", err)
+		}
+		stats.created = created
+		stats.skipped = len(songs) - created
+	}
+
+	logger.Info("Import finished",
+		zap.Int("created", stats.created),
+		zap.Int("skipped", stats.skipped),
+		zap.Int("failed", stats.failed))
+
+	if stats.failed > 0 {
+		return fmt.Errorf("%d of %d songs failed to import", stats.failed, len(records))
+	}
+	return nil
+}
+
+// exportRecords читает все песни постранично
+func exportRecords(ctx context.Context, repo repository.SongRepository) ([]songRecord, error) {
+	records := make([]songRecord, 0)
+	for page := 1; ; page++ {
+		response, err := repo.GetSongs(ctx, &models.SongFilter{Page: page, PageSize: exportPageSize})
+		// Для пустой таблицы репозиторий сообщает, что первой страницы нет
+		if appErr, ok := errors.As(err); ok && appErr.Type == errors.NotFound && page == 1 {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, song := range response.Songs {
+			records = append(records, newSongRecord(&song))
+		}
+		if page >= response.TotalPages {
+			return records, nil
+		}
+	}
+}
+
+// toSong проверяет запись и превращает ее в модель песни
+func (r songRecord) toSong() (*models.Song, error) {
+	v := validation.New()
+	validation.SongRequest(v, &models.SongRequest{
+		GroupName: r.GroupName,
+		SongName:  r.SongName,
+		Text:      r.Text,
+		Link:      r.Link,
+	}, false)
+
+	releaseDate := time.Now()
+	if r.ReleaseDate != "" {
+		parsed, err := time.Parse(dateLayout, r.ReleaseDate)
+		if err != nil {
+			v.Add("release_date", "must be a date in format YYYY-MM-DD")
+		}
+		releaseDate = parsed
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.Song{
+		GroupName:   r.GroupName,
+		SongName:    r.SongName,
+		ReleaseDate: releaseDate,
+		Text:        r.Text,
+		Link:        r.Link,
+	}, nil
+}
+
+func newSongRecord(song *models.Song) songRecord {
+	return songRecord{
+		GroupName:   song.GroupName,
+		SongName:    song.SongName,
+		ReleaseDate: song.ReleaseDate.Format(dateLayout),
+		Text:        song.Text,
+		Link:        song.Link,
+	}
+}
+
+// readRecords читает песни из JSON или CSV файла, "-" - JSON из stdin
+func readRecords(path string) ([]songRecord, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	if isCSV(path) {
+		return readCSV(r)
+	}
+
+	var records []songRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return records, nil
+}
+
+// writeRecords пишет песни в JSON или CSV файл, "-" - JSON в stdout
+func writeRecords(path string, records []songRecord) (err error) {
+	var w io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = file
+	}
+
+	if isCSV(path) {
+		return writeCSV(w, records)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
+
+func readCSV(r io.Reader) ([]songRecord, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, fmt.Errorf("CSV header must be %s", strings.Join(csvHeader, ","))
+	}
+
+	records := make([]songRecord, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		records = append(records, songRecord{
+			GroupName:   row[0],
+			SongName:    row[1],
+			ReleaseDate: row[2],
+			Text:        row[3],
+			Link:        row[4],
+		})
+	}
+	return records, nil
+}
+
+func writeCSV(w io.Writer, records []songRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := cw.Write([]string{r.GroupName, r.SongName, r.ReleaseDate, r.Text, r.Link}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func isCSV(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".csv")
+}
@@ -2,53 +2,320 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"net/url"
+	"strings"
+	"time"
 )
 
 // Config содержит конфигурацию приложения
 type Config struct {
-	DBHost     string
-	DBPort     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	ServerPort string
-}
-
-// Load загружает конфигурацию из .env файла
-func Load() (*Config, error) {
-	return LoadConfig()
-}
-
-func LoadConfig() (*Config, error) {
-	config := &Config{
-		DBHost:     getEnvOrDefault("DB_HOST", "localhost"),
-		DBPort:     getEnvOrDefault("DB_PORT", "5432"),
-		DBUser:     getEnvOrDefault("DB_USER", "postgres"),
-		DBPassword: getEnvOrDefault("DB_PASSWORD", "postgres"),
-		DBName:     getEnvOrDefault("DB_NAME", "music_library"),
-		ServerPort: getEnvOrDefault("SERVER_PORT", "8080"),
+	// Profile выбранный профиль: dev, test или prod
+	Profile string
+	// Storage хранилище песен: postgres, sqlite или memory
+	Storage string
+
+	Server   ServerConfig
+	GRPC     GRPCConfig
+	DB       DBConfig
+	SQLite   SQLiteConfig
+	Cache    CacheConfig
+	Webhooks WebhooksConfig
+	Events   EventsConfig
+	Sync     SyncConfig
+	GraphQL  GraphQLConfig
+	Log      LogConfig
+	Tracing  TracingConfig
+
+	// sources откуда взято итоговое значение каждой настройки
+	sources map[string]string
+}
+
+// Хранилища песен
+const (
+	StoragePostgres = "postgres"
+	// StorageSQLite файл SQLite для небольших установок и офлайн утилит
+	StorageSQLite = "sqlite"
+	// StorageMemory держит данные в памяти процесса, база данных не нужна
+	StorageMemory = "memory"
+)
+
+// ServerConfig настройки HTTP сервера
+type ServerConfig struct {
+	Port         int
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownTimeout общее время на graceful shutdown
+	ShutdownTimeout time.Duration
+	// ShutdownDrainDelay пауза между отказом /readyz и остановкой HTTP сервера
+	ShutdownDrainDelay time.Duration
+	// TLS включается, если заданы оба файла
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// GRPCConfig настройки gRPC сервера. TLS он берет у HTTP сервера.
+type GRPCConfig struct {
+	Enabled bool
+	Port    int
+}
+
+// DBConfig настройки подключения к PostgreSQL
+type DBConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	// AutoMigrate применять миграции при запуске сервера
+	AutoMigrate bool
+	// CreateDatabase создавать базу Name при запуске, если ее нет
+	CreateDatabase bool
+	// MaxOpenConns максимальный размер пула, MinConns сколько соединений держать открытыми
+	MaxOpenConns    int
+	MinConns        int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// SSLMode режим sslmode libpq: disable, allow, prefer, require, verify-ca, verify-full
+	SSLMode string
+	// SSLRootCert CA сертификат для проверки сервера, SSLCert и SSLKey клиентский сертификат
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// StatementTimeout ограничение времени выполнения одного запроса, 0 без ограничения
+	StatementTimeout time.Duration
+	// ConnectTimeout ограничение времени установки одного соединения
+	ConnectTimeout time.Duration
+	// ConnectRetryTimeout сколько ждать доступности базы при запуске
+	ConnectRetryTimeout time.Duration
+
+	// TxIsolation уровень изоляции транзакций: read_committed, repeatable_read, serializable
+	TxIsolation string
+	// TxMaxRetries сколько раз повторять транзакцию после конфликта сериализации
+	TxMaxRetries int
+}
+
+// SQLiteConfig настройки хранилища SQLite
+type SQLiteConfig struct {
+	// Path путь к файлу базы, создается при первом запуске
+	Path string
+	// BusyTimeout сколько ждать, пока другая транзакция освободит базу
+	BusyTimeout time.Duration
+}
+
+// CacheConfig настройки кэша песен в памяти процесса
+type CacheConfig struct {
+	Enabled bool
+	// Size максимальное количество песен и отдельно страниц списка
+	Size int
+	TTL  time.Duration
+}
+
+// WebhooksConfig настройки доставки событий на вебхуки
+type WebhooksConfig struct {
+	// Enabled запускать диспетчер доставки, события в outbox пишутся всегда
+	Enabled bool
+	// PollInterval как часто проверять новые события и повторы
+	PollInterval time.Duration
+	// Timeout ограничение времени одного запроса к получателю
+	Timeout time.Duration
+	// MaxAttempts после стольких неудачных попыток доставка считается проваленной
+	MaxAttempts int
+	// RetryBackoff задержка перед первым повтором, дальше она удваивается до RetryMaxBackoff
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+}
+
+// EventsConfig настройки потока событий GET /api/v1/events
+type EventsConfig struct {
+	// PollInterval как часто проверять новые события. С PostgreSQL поток
+	// узнает о них и сразу через LISTEN/NOTIFY, интервал нужен на случай обрыва.
+	PollInterval time.Duration
+	// HeartbeatInterval как часто отправлять комментарий, чтобы прокси не закрыли тихое соединение
+	HeartbeatInterval time.Duration
+}
+
+// SyncConfig настройки синхронизации GET /api/v1/sync
+type SyncConfig struct {
+	// TombstoneRetention сколько помнить удаления песен. Клиент, не синхронизировавшийся
+	// дольше, получает требование полной синхронизации. 0 хранит удаления вечно.
+	TombstoneRetention time.Duration
+}
+
+// GraphQLConfig ограничения запросов POST /graphql
+type GraphQLConfig struct {
+	// MaxDepth наибольшая вложенность полей запроса
+	MaxDepth int
+	// MaxComplexity наибольшая стоимость запроса: поле стоит 1, поля внутри
+	// страницы песен умножаются на ее размер
+	MaxComplexity int
+}
+
+// LogConfig настройки логирования
+type LogConfig struct {
+	Level  string
+	Format string
+}
+
+// TracingConfig настройки трассировки OpenTelemetry
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	OutputFile   string
+}
+
+// defaults возвращает конфигурацию со значениями по умолчанию
+func defaults() *Config {
+	return &Config{
+		Storage: StoragePostgres,
+		Server: ServerConfig{
+			Port:               8080,
+			ReadTimeout:        15 * time.Second,
+			WriteTimeout:       15 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    30 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled: true,
+			Port:    9090,
+		},
+		DB: DBConfig{
+			Host:                "localhost",
+			Port:                5432,
+			User:                "postgres",
+			Password:            "postgres",
+			Name:                "music_library",
+			AutoMigrate:         true,
+			MaxOpenConns:        25,
+			MinConns:            2,
+			ConnMaxLifetime:     30 * time.Minute,
+			ConnMaxIdleTime:     5 * time.Minute,
+			SSLMode:             "disable",
+			ConnectTimeout:      5 * time.Second,
+			ConnectRetryTimeout: 30 * time.Second,
+			TxIsolation:         "read_committed",
+			TxMaxRetries:        3,
+		},
+		SQLite: SQLiteConfig{
+			Path:        "music_library.db",
+			BusyTimeout: 5 * time.Second,
+		},
+		Cache: CacheConfig{
+			Enabled: true,
+			Size:    1000,
+			TTL:     time.Minute,
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:         true,
+			PollInterval:    time.Second,
+			Timeout:         10 * time.Second,
+			MaxAttempts:     10,
+			RetryBackoff:    10 * time.Second,
+			RetryMaxBackoff: time.Hour,
+		},
+		Events: EventsConfig{
+			PollInterval:      time.Second,
+			HeartbeatInterval: 15 * time.Second,
+		},
+		Sync: SyncConfig{
+			TombstoneRetention: 30 * 24 * time.Hour,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      15,
+			MaxComplexity: 2000,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+		},
+		sources: make(map[string]string),
 	}
+}
+
+// TLSEnabled сообщает, нужно ли поднимать HTTPS
+func (s ServerConfig) TLSEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
 
-	return config, nil
+// Addr адрес, на котором слушает HTTP сервер
+func (s ServerConfig) Addr() string {
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+// Addr адрес, на котором слушает gRPC сервер
+func (g GRPCConfig) Addr() string {
+	return fmt.Sprintf(":%d", g.Port)
 }
 
 // GetDBConnString возвращает строку подключения к базе данных
 func (c *Config) GetDBConnString() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
+	return c.DB.connString(c.DB.Name)
 }
 
-// GetDBConnStringWithoutDatabase возвращает строку подключения к postgres без указания базы данных
+// GetDBConnStringWithoutDatabase возвращает строку подключения к служебной базе postgres
 func (c *Config) GetDBConnStringWithoutDatabase() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword)
+	return c.DB.connString("postgres")
+}
+
+// GetSQLiteDSN возвращает строку подключения к файлу SQLite. Внешние ключи
+// включаются явно, WAL позволяет читать во время записи, а транзакции сразу
+// берут блокировку записи, чтобы не получать SQLITE_BUSY при ее повышении.
+func (c *Config) GetSQLiteDSN() string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.SQLite.BusyTimeout.Milliseconds()))
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return "file:" + c.SQLite.Path + "?" + params.Encode()
+}
+
+// connString собирает строку подключения в формате key=value к базе dbname
+func (d DBConfig) connString(dbname string) string {
+	params := [][2]string{
+		{"host", d.Host},
+		{"port", fmt.Sprint(d.Port)},
+		{"user", d.User},
+		{"password", d.Password},
+		{"dbname", dbname},
+		{"sslmode", d.SSLMode},
+		{"sslrootcert", d.SSLRootCert},
+		{"sslcert", d.SSLCert},
+		{"sslkey", d.SSLKey},
+	}
+	if d.ConnectTimeout > 0 {
+		// connect_timeout задается в целых секундах, меньше секунды округляем вверх
+		seconds := (d.ConnectTimeout + time.Second - 1) / time.Second
+		params = append(params, [2]string{"connect_timeout", fmt.Sprint(int64(seconds))})
+	}
+	if d.StatementTimeout > 0 {
+		// Неизвестные драйверу параметры передаются серверу как параметры сессии
+		params = append(params, [2]string{"statement_timeout", fmt.Sprint(d.StatementTimeout.Milliseconds())})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p[1] == "" {
+			continue
+		}
+		parts = append(parts, p[0]+"="+quoteConnValue(p[1]))
+	}
+	return strings.Join(parts, " ")
 }
 
-// getEnvOrDefault возвращает значение переменной окружения или значение по умолчанию
-func getEnvOrDefault(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// quoteConnValue экранирует значение строки подключения, чтобы пробелы
+// и кавычки в пароле не ломали разбор
+func quoteConnValue(v string) string {
+	if !strings.ContainsAny(v, ` '\`) {
+		return v
 	}
-	return defaultValue
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
 }
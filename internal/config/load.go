@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileEnv переменная окружения с путем к файлу конфигурации
+const configFileEnv = "CONFIG_FILE"
+
+// secretFileSuffix суффикс переменной окружения, указывающей на файл с секретом
+const secretFileSuffix = "_FILE"
+
+// ErrHelp возвращается из Load, когда пользователь запросил справку по флагам
+var ErrHelp = flag.ErrHelp
+
+// Load собирает конфигурацию по слоям: значения по умолчанию, профиль, YAML файл,
+// переменные окружения и флаги командной строки, каждый следующий слой важнее.
+// Все ошибки разбора и проверки возвращаются одной ошибкой.
+// Вторым значением возвращаются позиционные аргументы после флагов.
+func Load(args []string) (*Config, []string, error) {
+	cfg := defaults()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet("music-library", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(configFileEnv), "path to YAML configuration file (env "+configFileEnv+")")
+	profile := fs.String("profile", envOrDefault(profileEnv, defaultProfile), "configuration profile: dev, test, prod (env "+profileEnv+")")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		defaultValue := s.value.String()
+		if s.secret {
+			defaultValue = ""
+		}
+		flagValues[s.key] = fs.String(s.key, defaultValue, fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cfg.applyProfile(settings, *profile); err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+
+	if *configPath != "" {
+		fileValues, err := readFile(*configPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		errs = append(errs, cfg.applyFile(settings, fileValues, *configPath)...)
+	}
+
+	errs = append(errs, cfg.applyEnv(settings)...)
+
+	// Применяем только явно переданные флаги, иначе значения по умолчанию затрут файл и окружение
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.key == f.Name {
+				if err := cfg.set(s, *flagValues[s.key], "flag --"+s.key); err != nil {
+					errs = append(errs, fmt.Errorf("flag --%s: invalid value %q: %w", s.key, f.Value.String(), err))
+				}
+			}
+		}
+	})
+
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// applyFile применяет значения из файла конфигурации
+func (c *Config) applyFile(settings []setting, fileValues map[string]string, path string) []error {
+	var errs []error
+
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+		raw, ok := fileValues[s.key]
+		if !ok {
+			continue
+		}
+		if err := c.set(s, raw, "file "+path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: invalid value %q: %w", path, s.key, raw, err))
+		}
+	}
+
+	// Неизвестные ключи обычно означают опечатку, поэтому не игнорируем их
+	unknown := make([]string, 0)
+	for key := range fileValues {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+	}
+
+	return errs
+}
+
+// applyEnv применяет значения из переменных окружения и файлов с секретами
+func (c *Config) applyEnv(settings []setting) []error {
+	var errs []error
+	for _, s := range settings {
+		raw, source, ok, err := lookupEnv(s)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := c.set(s, raw, source); err != nil {
+			errs = append(errs, fmt.Errorf("env %s: invalid value %q: %w", s.env, raw, err))
+		}
+	}
+	return errs
+}
+
+// lookupEnv читает значение настройки из окружения, для секретов также из файла <env>_FILE.
+// Вместе со значением возвращает его источник для лога конфигурации.
+func lookupEnv(s setting) (string, string, bool, error) {
+	raw, ok := os.LookupEnv(s.env)
+	if !s.secret {
+		return raw, "env " + s.env, ok, nil
+	}
+
+	fileEnv := s.env + secretFileSuffix
+	path, fileOK := os.LookupEnv(fileEnv)
+	if !fileOK {
+		return raw, "env " + s.env, ok, nil
+	}
+	if ok {
+		return "", "", false, fmt.Errorf("env %s and %s are mutually exclusive", s.env, fileEnv)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", false, fmt.Errorf("env %s: failed to read secret file: %w", fileEnv, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), "env " + fileEnv, true, nil
+}
+
+// envOrDefault возвращает значение переменной окружения или значение по умолчанию
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// readFile читает YAML файл и разворачивает вложенные секции в ключи вида db.host
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]any
+	if err := yaml.Unmarshal(content, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", tree, values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten разворачивает дерево YAML в плоский набор ключей
+func flatten(prefix string, tree map[string]any, values map[string]string) error {
+	for key, node := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := node.(type) {
+		case map[string]any:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case []any:
+			return fmt.Errorf("%s: lists are not supported", key)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
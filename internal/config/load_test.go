@@ -0,0 +1,181 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/testTask/internal/config"
+)
+
+// writeFile пишет content во временный файл и возвращает путь к нему
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := "log:\n  level: warn\nserver:\n  port: 8081\n"
+
+	tests := []struct {
+		name string
+		// file содержимое YAML файла, пустое - без файла
+		file string
+		env  map[string]string
+		args []string
+
+		wantProfile        string
+		wantPort           int
+		wantLevel          string
+		wantDBName         string
+		wantCreateDatabase bool
+	}{
+		{
+			name:        "defaults and dev profile",
+			wantProfile: config.ProfileDev,
+			wantPort:    8080,
+			wantLevel:   "debug",
+			wantDBName:  "music_library",
+		},
+		{
+			name:               "test profile from env",
+			env:                map[string]string{"APP_PROFILE": config.ProfileTest},
+			wantProfile:        config.ProfileTest,
+			wantPort:           8080,
+			wantLevel:          "warn",
+			wantDBName:         "music_library_test",
+			wantCreateDatabase: true,
+		},
+		{
+			name:        "file over profile",
+			file:        file,
+			wantProfile: config.ProfileDev,
+			wantPort:    8081,
+			wantLevel:   "warn",
+			wantDBName:  "music_library",
+		},
+		{
+			name:               "env over file",
+			file:               file,
+			env:                map[string]string{"SERVER_PORT": "8082", "DB_CREATE_DATABASE": "true"},
+			wantProfile:        config.ProfileDev,
+			wantPort:           8082,
+			wantLevel:          "warn",
+			wantDBName:         "music_library",
+			wantCreateDatabase: true,
+		},
+		{
+			name:        "flags over env",
+			file:        file,
+			env:         map[string]string{"SERVER_PORT": "8082", "LOG_LEVEL": "error"},
+			args:        []string{"--server.port=8083", "--profile=prod", "--db.password=secret", "--db.name=songs"},
+			wantProfile: config.ProfileProd,
+			wantPort:    8083,
+			wantLevel:   "error",
+			wantDBName:  "songs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config=" + writeFile(t, "config.yaml", tt.file)}, args...)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, _, err := config.Load(args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Profile != tt.wantProfile || cfg.Server.Port != tt.wantPort || cfg.Log.Level != tt.wantLevel ||
+				cfg.DB.Name != tt.wantDBName || cfg.DB.CreateDatabase != tt.wantCreateDatabase {
+				t.Errorf("got profile %s, port %d, level %s, db %s, create database %v; want %s, %d, %s, %s, %v",
+					cfg.Profile, cfg.Server.Port, cfg.Log.Level, cfg.DB.Name, cfg.DB.CreateDatabase,
+					tt.wantProfile, tt.wantPort, tt.wantLevel, tt.wantDBName, tt.wantCreateDatabase)
+			}
+		})
+	}
+}
+
+func TestLoadSecretFile(t *testing.T) {
+	t.Run("password from file", func(t *testing.T) {
+		t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "s3cret\n"))
+		cfg, _, err := config.Load(nil)
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if cfg.DB.Password != "s3cret" {
+			t.Errorf("password %q, want s3cret without the trailing newline", cfg.DB.Password)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "both value and file",
+			env:     map[string]string{"DB_PASSWORD": "secret", "DB_PASSWORD_FILE": "/run/secrets/db_password"},
+			wantErr: "env DB_PASSWORD and DB_PASSWORD_FILE are mutually exclusive",
+		},
+		{
+			name:    "missing file",
+			env:     map[string]string{"DB_PASSWORD_FILE": filepath.Join(t.TempDir(), "missing")},
+			wantErr: "env DB_PASSWORD_FILE: failed to read secret file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if _, _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", "db:\n  hots: localhost\nserver:\n  port: 8081\n  timeout: 5s\n")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+
+	_, _, err := config.Load([]string{"--config=" + path, "--profile=prod"})
+	if err == nil {
+		t.Fatal("Load succeeded, want errors")
+	}
+	for _, want := range []string{
+		`unknown setting "db.hots"`,
+		`unknown setting "server.timeout"`,
+		`env SERVER_READ_TIMEOUT: invalid value "soon"`,
+		"db.password: is required in the prod profile",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load error %q does not report %q", err, want)
+		}
+	}
+}
+
+func TestLoadReportsSSLFilesInOrder(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	args := []string{"--db.sslrootcert=" + missing, "--db.sslcert=" + missing, "--db.sslkey=" + missing}
+
+	_, _, err := config.Load(args)
+	if err == nil {
+		t.Fatal("Load succeeded, want errors")
+	}
+	rootCert := strings.Index(err.Error(), "db.sslrootcert: open")
+	cert := strings.Index(err.Error(), "db.sslcert: open")
+	key := strings.Index(err.Error(), "db.sslkey: open")
+	if rootCert < 0 || !(rootCert < cert && cert < key) {
+		t.Errorf("Load error %q, want sslrootcert, sslcert and sslkey errors in this order", err)
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Профили конфигурации
+const (
+	ProfileDev  = "dev"
+	ProfileTest = "test"
+	ProfileProd = "prod"
+)
+
+// profileEnv переменная окружения с именем профиля
+const profileEnv = "APP_PROFILE"
+
+// defaultProfile профиль, если он не указан явно
+const defaultProfile = ProfileDev
+
+// profiles значения по умолчанию, которые профиль меняет относительно базовых
+var profiles = map[string]map[string]string{
+	ProfileDev: {
+		"log.level":                   "debug",
+		"log.format":                  "console",
+		"server.shutdown_drain_delay": "0s",
+	},
+	ProfileTest: {
+		"log.level":                   "warn",
+		"log.format":                  "console",
+		"db.name":                     "music_library_test",
+		"server.shutdown_timeout":     "5s",
+		"server.shutdown_drain_delay": "0s",
+		// Профиль test выбирается только явно, тестовая база создается сама
+		"db.create_database": "true",
+	},
+	ProfileProd: {
+		"log.level":  "info",
+		"log.format": "json",
+		// В проде пароль по умолчанию недопустим, его обязаны передать явно
+		"db.password": "",
+		// Миграции в проде запускаются отдельным шагом деплоя командой migrate up
+		"db.auto_migrate":             "false",
+		"server.shutdown_drain_delay": "10s",
+		"db.sslmode":                  "require",
+		"db.statement_timeout":        "30s",
+	},
+}
+
+// applyProfile применяет значения по умолчанию выбранного профиля
+func (c *Config) applyProfile(settings []setting, name string) error {
+	values, ok := profiles[name]
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	c.Profile = name
+	for _, s := range settings {
+		if raw, ok := values[s.key]; ok {
+			if err := c.set(s, raw, "profile "+name); err != nil {
+				return fmt.Errorf("profile %s: %s: %w", name, s.key, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// redactedValue заменяет значения секретов в выводе конфигурации
+const redactedValue = "******"
+
+// setting описывает одну настройку и то, откуда ее можно задать
+type setting struct {
+	// key ключ в файле конфигурации и имя CLI флага, например db.host
+	key string
+	// env имя переменной окружения
+	env string
+	// secret значение не выводится в логи и может читаться из файла <env>_FILE
+	secret bool
+	usage  string
+	value  value
+}
+
+// value типизированная ссылка на поле Config
+type value interface {
+	Set(raw string) error
+	String() string
+}
+
+// settings возвращает описание всех настроек, привязанных к полям c
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "storage", env: "STORAGE", usage: "song storage: postgres, sqlite, memory", value: (*stringValue)(&c.Storage)},
+
+		{key: "server.port", env: "SERVER_PORT", usage: "HTTP server port", value: (*intValue)(&c.Server.Port)},
+		{key: "server.read_timeout", env: "SERVER_READ_TIMEOUT", usage: "HTTP read timeout", value: (*durationValue)(&c.Server.ReadTimeout)},
+		{key: "server.write_timeout", env: "SERVER_WRITE_TIMEOUT", usage: "HTTP write timeout", value: (*durationValue)(&c.Server.WriteTimeout)},
+		{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", usage: "HTTP keep-alive idle timeout", value: (*durationValue)(&c.Server.IdleTimeout)},
+		{key: "server.shutdown_timeout", env: "SHUTDOWN_TIMEOUT", usage: "graceful shutdown timeout", value: (*durationValue)(&c.Server.ShutdownTimeout)},
+		{key: "server.shutdown_drain_delay", env: "SHUTDOWN_DRAIN_DELAY", usage: "delay between failing /readyz and stopping the server", value: (*durationValue)(&c.Server.ShutdownDrainDelay)},
+		{key: "server.tls_cert_file", env: "SERVER_TLS_CERT_FILE", usage: "TLS certificate file, enables HTTPS together with the key", value: (*stringValue)(&c.Server.TLSCertFile)},
+		{key: "server.tls_key_file", env: "SERVER_TLS_KEY_FILE", usage: "TLS private key file", value: (*stringValue)(&c.Server.TLSKeyFile)},
+
+		{key: "grpc.enabled", env: "GRPC_ENABLED", usage: "serve the gRPC API on a separate port", value: (*boolValue)(&c.GRPC.Enabled)},
+		{key: "grpc.port", env: "GRPC_PORT", usage: "gRPC server port, uses the TLS files of the HTTP server", value: (*intValue)(&c.GRPC.Port)},
+
+		{key: "db.host", env: "DB_HOST", usage: "database host", value: (*stringValue)(&c.DB.Host)},
+		{key: "db.port", env: "DB_PORT", usage: "database port", value: (*intValue)(&c.DB.Port)},
+		{key: "db.user", env: "DB_USER", usage: "database user", value: (*stringValue)(&c.DB.User)},
+		{key: "db.password", env: "DB_PASSWORD", secret: true, usage: "database password", value: (*stringValue)(&c.DB.Password)},
+		{key: "db.name", env: "DB_NAME", usage: "database name", value: (*stringValue)(&c.DB.Name)},
+		{key: "db.auto_migrate", env: "DB_AUTO_MIGRATE", usage: "apply migrations when the server starts", value: (*boolValue)(&c.DB.AutoMigrate)},
+		{key: "db.create_database", env: "DB_CREATE_DATABASE", usage: "create the database on startup if it does not exist", value: (*boolValue)(&c.DB.CreateDatabase)},
+		{key: "db.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum open connections", value: (*intValue)(&c.DB.MaxOpenConns)},
+		{key: "db.min_conns", env: "DB_MIN_CONNS", usage: "connections kept open even when idle", value: (*intValue)(&c.DB.MinConns)},
+		{key: "db.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum connection lifetime, 0 is unlimited", value: (*durationValue)(&c.DB.ConnMaxLifetime)},
+		{key: "db.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", usage: "maximum connection idle time, 0 is unlimited", value: (*durationValue)(&c.DB.ConnMaxIdleTime)},
+		{key: "db.sslmode", env: "DB_SSLMODE", usage: "SSL mode: disable, allow, prefer, require, verify-ca, verify-full", value: (*stringValue)(&c.DB.SSLMode)},
+		{key: "db.sslrootcert", env: "DB_SSLROOTCERT", usage: "CA certificate file to verify the server", value: (*stringValue)(&c.DB.SSLRootCert)},
+		{key: "db.sslcert", env: "DB_SSLCERT", usage: "client certificate file", value: (*stringValue)(&c.DB.SSLCert)},
+		{key: "db.sslkey", env: "DB_SSLKEY", usage: "client private key file", value: (*stringValue)(&c.DB.SSLKey)},
+		{key: "db.statement_timeout", env: "DB_STATEMENT_TIMEOUT", usage: "maximum query duration, 0 is unlimited", value: (*durationValue)(&c.DB.StatementTimeout)},
+		{key: "db.connect_timeout", env: "DB_CONNECT_TIMEOUT", usage: "timeout for establishing a single connection", value: (*durationValue)(&c.DB.ConnectTimeout)},
+		{key: "db.connect_retry_timeout", env: "DB_CONNECT_RETRY_TIMEOUT", usage: "how long to wait for the database on startup, 0 is a single attempt", value: (*durationValue)(&c.DB.ConnectRetryTimeout)},
+		{key: "db.tx_isolation", env: "DB_TX_ISOLATION", usage: "transaction isolation: read_committed, repeatable_read, serializable", value: (*stringValue)(&c.DB.TxIsolation)},
+		{key: "db.tx_max_retries", env: "DB_TX_MAX_RETRIES", usage: "retries of a transaction after a serialization failure or deadlock", value: (*intValue)(&c.DB.TxMaxRetries)},
+
+		{key: "sqlite.path", env: "SQLITE_PATH", usage: "SQLite database file", value: (*stringValue)(&c.SQLite.Path)},
+		{key: "sqlite.busy_timeout", env: "SQLITE_BUSY_TIMEOUT", usage: "how long to wait for a locked SQLite database", value: (*durationValue)(&c.SQLite.BusyTimeout)},
+
+		{key: "cache.enabled", env: "CACHE_ENABLED", usage: "cache songs and list pages in memory", value: (*boolValue)(&c.Cache.Enabled)},
+		{key: "cache.size", env: "CACHE_SIZE", usage: "maximum cached songs and, separately, list pages", value: (*intValue)(&c.Cache.Size)},
+		{key: "cache.ttl", env: "CACHE_TTL", usage: "how long a cached entry lives", value: (*durationValue)(&c.Cache.TTL)},
+
+		{key: "webhooks.enabled", env: "WEBHOOKS_ENABLED", usage: "deliver outbox events to registered webhooks", value: (*boolValue)(&c.Webhooks.Enabled)},
+		{key: "webhooks.poll_interval", env: "WEBHOOKS_POLL_INTERVAL", usage: "how often to look for new events and due retries", value: (*durationValue)(&c.Webhooks.PollInterval)},
+		{key: "webhooks.timeout", env: "WEBHOOKS_TIMEOUT", usage: "timeout of a single delivery request", value: (*durationValue)(&c.Webhooks.Timeout)},
+		{key: "webhooks.max_attempts", env: "WEBHOOKS_MAX_ATTEMPTS", usage: "attempts before a delivery is marked failed", value: (*intValue)(&c.Webhooks.MaxAttempts)},
+		{key: "webhooks.retry_backoff", env: "WEBHOOKS_RETRY_BACKOFF", usage: "delay before the first retry, doubled on each next one", value: (*durationValue)(&c.Webhooks.RetryBackoff)},
+		{key: "webhooks.retry_max_backoff", env: "WEBHOOKS_RETRY_MAX_BACKOFF", usage: "maximum delay between retries", value: (*durationValue)(&c.Webhooks.RetryMaxBackoff)},
+
+		{key: "events.poll_interval", env: "EVENTS_POLL_INTERVAL", usage: "how often the event stream looks for new events", value: (*durationValue)(&c.Events.PollInterval)},
+		{key: "events.heartbeat_interval", env: "EVENTS_HEARTBEAT_INTERVAL", usage: "how often the event stream sends a heartbeat comment", value: (*durationValue)(&c.Events.HeartbeatInterval)},
+		{key: "sync.tombstone_retention", env: "SYNC_TOMBSTONE_RETENTION", usage: "how long deleted songs are remembered for sync, 0 keeps them forever", value: (*durationValue)(&c.Sync.TombstoneRetention)},
+		{key: "graphql.max_depth", env: "GRAPHQL_MAX_DEPTH", usage: "maximum nesting of fields in a GraphQL query", value: (*intValue)(&c.GraphQL.MaxDepth)},
+		{key: "graphql.max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", usage: "maximum cost of a GraphQL query, fields of a song page count once per song", value: (*intValue)(&c.GraphQL.MaxComplexity)},
+
+		{key: "log.level", env: "LOG_LEVEL", usage: "log level: debug, info, warn, error", value: (*stringValue)(&c.Log.Level)},
+		{key: "log.format", env: "LOG_FORMAT", usage: "log format: json, console", value: (*stringValue)(&c.Log.Format)},
+
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "trace exporter: none, otlp, stdout", value: (*stringValue)(&c.Tracing.Exporter)},
+		{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", usage: "OTLP/HTTP collector host:port", value: (*stringValue)(&c.Tracing.OTLPEndpoint)},
+		{key: "tracing.otlp_insecure", env: "TRACING_OTLP_INSECURE", usage: "use plain HTTP for OTLP", value: (*boolValue)(&c.Tracing.OTLPInsecure)},
+		{key: "tracing.output_file", env: "TRACING_OUTPUT_FILE", usage: "file for the stdout exporter, empty is stdout", value: (*stringValue)(&c.Tracing.OutputFile)},
+	}
+}
+
+// sourceDefault источник значений, не переопределенных ни одним слоем
+const sourceDefault = "default"
+
+// EffectiveSetting итоговое значение настройки и его источник
+type EffectiveSetting struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Redacted возвращает итоговые значения всех настроек и их источники со скрытыми секретами
+func (c *Config) Redacted() map[string]EffectiveSetting {
+	result := make(map[string]EffectiveSetting)
+	for _, s := range c.settings() {
+		v := s.value.String()
+		if s.secret && v != "" {
+			v = redactedValue
+		}
+		source, ok := c.sources[s.key]
+		if !ok {
+			source = sourceDefault
+		}
+		result[s.key] = EffectiveSetting{Value: v, Source: source}
+	}
+	return result
+}
+
+// set присваивает значение настройке и запоминает его источник
+func (c *Config) set(s setting, raw, source string) error {
+	if err := s.value.Set(raw); err != nil {
+		return err
+	}
+	c.sources[s.key] = source
+	return nil
+}
+
+type stringValue string
+
+func (v *stringValue) Set(raw string) error {
+	*v = stringValue(raw)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(raw string) error {
+	parsed, err := strconv.Atoi(raw)
+	if err != nil {
+		return err
+	}
+	*v = intValue(parsed)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type boolValue bool
+
+func (v *boolValue) Set(raw string) error {
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(parsed)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(raw string) error {
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(parsed)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
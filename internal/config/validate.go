@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// validate проверяет итоговую конфигурацию и возвращает все найденные ошибки
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port: must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout: must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout: must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout: must be positive")
+	check(c.Server.ShutdownDrainDelay >= 0, "server.shutdown_drain_delay: must not be negative")
+	check(c.Server.ShutdownTimeout > c.Server.ShutdownDrainDelay,
+		"server.shutdown_timeout: must be greater than server.shutdown_drain_delay (%s)", c.Server.ShutdownDrainDelay)
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""),
+		"server.tls_cert_file and server.tls_key_file: must be set together")
+	if c.Server.TLSEnabled() {
+		errs = append(errs, checkReadable("server.tls_cert_file", c.Server.TLSCertFile)...)
+		errs = append(errs, checkReadable("server.tls_key_file", c.Server.TLSKeyFile)...)
+	}
+
+	if c.GRPC.Enabled {
+		check(c.GRPC.Port > 0 && c.GRPC.Port <= 65535, "grpc.port: must be between 1 and 65535, got %d", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port: must differ from server.port")
+	}
+
+	check(oneOf(c.Storage, StoragePostgres, StorageSQLite, StorageMemory),
+		"storage: must be one of %s, %s, %s, got %q", StoragePostgres, StorageSQLite, StorageMemory, c.Storage)
+
+	// Настройки базы данных проверяются, только если она используется
+	if c.Storage == StoragePostgres {
+		check(c.DB.Host != "", "db.host: is required")
+		check(c.DB.Port > 0 && c.DB.Port <= 65535, "db.port: must be between 1 and 65535, got %d", c.DB.Port)
+		check(c.DB.User != "", "db.user: is required")
+		check(c.DB.Name != "", "db.name: is required")
+		check(c.Profile != ProfileProd || c.DB.Password != "", "db.password: is required in the %s profile", ProfileProd)
+		check(c.DB.MaxOpenConns > 0, "db.max_open_conns: must be positive")
+		check(c.DB.MinConns >= 0, "db.min_conns: must not be negative")
+		check(c.DB.MinConns <= c.DB.MaxOpenConns,
+			"db.min_conns: must not exceed db.max_open_conns (%d)", c.DB.MaxOpenConns)
+		check(c.DB.ConnMaxLifetime >= 0, "db.conn_max_lifetime: must not be negative")
+		check(c.DB.ConnMaxIdleTime >= 0, "db.conn_max_idle_time: must not be negative")
+		check(oneOf(c.DB.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"),
+			"db.sslmode: must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", c.DB.SSLMode)
+		check(!oneOf(c.DB.SSLMode, "verify-ca", "verify-full") || c.DB.SSLRootCert != "",
+			"db.sslrootcert: is required for sslmode %s", c.DB.SSLMode)
+		check((c.DB.SSLCert == "") == (c.DB.SSLKey == ""), "db.sslcert and db.sslkey: must be set together")
+		// Срез, а не map: ошибки файлов должны идти в одном и том же порядке
+		for _, file := range [][2]string{
+			{"db.sslrootcert", c.DB.SSLRootCert},
+			{"db.sslcert", c.DB.SSLCert},
+			{"db.sslkey", c.DB.SSLKey},
+		} {
+			if file[1] != "" {
+				errs = append(errs, checkReadable(file[0], file[1])...)
+			}
+		}
+		check(c.DB.StatementTimeout >= 0, "db.statement_timeout: must not be negative")
+		check(c.DB.ConnectTimeout >= 0, "db.connect_timeout: must not be negative")
+		check(c.DB.ConnectRetryTimeout >= 0, "db.connect_retry_timeout: must not be negative")
+		check(oneOf(c.DB.TxIsolation, "read_committed", "repeatable_read", "serializable"),
+			"db.tx_isolation: must be one of read_committed, repeatable_read, serializable, got %q", c.DB.TxIsolation)
+	}
+	// Повторы транзакций используются и в SQLite
+	check(c.DB.TxMaxRetries >= 0, "db.tx_max_retries: must not be negative")
+	if c.Storage == StorageSQLite {
+		check(c.SQLite.Path != "", "sqlite.path: is required")
+		check(c.SQLite.BusyTimeout >= 0, "sqlite.busy_timeout: must not be negative")
+	}
+
+	if c.Cache.Enabled {
+		check(c.Cache.Size > 0, "cache.size: must be positive")
+		check(c.Cache.TTL > 0, "cache.ttl: must be positive")
+	}
+
+	if c.Webhooks.Enabled {
+		check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval: must be positive")
+		check(c.Webhooks.Timeout > 0, "webhooks.timeout: must be positive")
+		check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts: must be positive")
+		check(c.Webhooks.RetryBackoff > 0, "webhooks.retry_backoff: must be positive")
+		check(c.Webhooks.RetryMaxBackoff >= c.Webhooks.RetryBackoff, "webhooks.retry_max_backoff: must not be less than webhooks.retry_backoff")
+	}
+
+	check(c.Events.PollInterval > 0, "events.poll_interval: must be positive")
+	check(c.Events.HeartbeatInterval > 0, "events.heartbeat_interval: must be positive")
+	check(c.Sync.TombstoneRetention >= 0, "sync.tombstone_retention: must not be negative")
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth: must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity: must be positive")
+
+	check(oneOf(c.Log.Level, "debug", "info", "warn", "error"), "log.level: must be one of debug, info, warn, error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "json", "console"), "log.format: must be one of json, console, got %q", c.Log.Format)
+
+	check(oneOf(c.Tracing.Exporter, "none", "otlp", "stdout"), "tracing.exporter: must be one of none, otlp, stdout, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint: is required for the otlp exporter")
+
+	return errs
+}
+
+// checkReadable проверяет, что файл существует и доступен для чтения
+func checkReadable(key, path string) []error {
+	file, err := os.Open(path)
+	if err != nil {
+		return []error{fmt.Errorf("%s: %w", key, err)}
+	}
+	_ = file.Close()
+	return nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
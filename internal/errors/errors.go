@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	stderrors "errors"
+	"fmt"
+)
 
 type ErrorType string
 
@@ -10,12 +13,24 @@ const (
 	Internal      ErrorType = "INTERNAL"
 	Validation    ErrorType = "VALIDATION"
 	AlreadyExists ErrorType = "ALREADY_EXISTS"
+	// ResyncRequired токен синхронизации устарел, клиенту нужна полная синхронизация
+	ResyncRequired ErrorType = "RESYNC_REQUIRED"
 )
 
+// FieldError ошибка проверки отдельного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type Error struct {
 	Type    ErrorType
 	Message string
 	Err     error
+	// Fields ошибки по отдельным полям, заполняется для Validation
+	Fields []FieldError
+	// ExistingID идентификатор уже существующей записи, заполняется для AlreadyExists
+	ExistingID int
 }
 
 func (e *Error) Error() string {
@@ -25,6 +40,20 @@ func (e *Error) Error() string {
 	return e.Message
 }
 
+// Unwrap возвращает исходную ошибку для errors.Is и errors.As
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As ищет *Error в цепочке обернутых ошибок
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	if stderrors.As(err, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
 func NewNotFound(message string, err error) *Error {
 	return &Error{
 		Type:    NotFound,
@@ -65,6 +94,14 @@ func NewValidation(message string, err error) *Error {
 	}
 }
 
+func NewValidationFields(message string, fields []FieldError) *Error {
+	return &Error{
+		Type:    Validation,
+		Message: message,
+		Fields:  fields,
+	}
+}
+
 func NewAlreadyExists(message string, err error) *Error {
 	return &Error{
 		Type:    AlreadyExists,
@@ -72,3 +109,20 @@ func NewAlreadyExists(message string, err error) *Error {
 		Err:     err,
 	}
 }
+
+func NewResyncRequired(message string, err error) *Error {
+	return &Error{
+		Type:    ResyncRequired,
+		Message: message,
+		Err:     err,
+	}
+}
+
+func NewAlreadyExistsWithID(message string, existingID int, err error) *Error {
+	return &Error{
+		Type:       AlreadyExists,
+		Message:    message,
+		Err:        err,
+		ExistingID: existingID,
+	}
+}
@@ -0,0 +1,98 @@
+// Package events доставляет события об изменении песен подписчикам внутри процесса.
+package events
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Type вид изменения песни
+type Type string
+
+// Виды изменений песни
+const (
+	SongCreated Type = "song.created"
+	SongUpdated Type = "song.updated"
+	SongDeleted Type = "song.deleted"
+)
+
+// Event изменение песни на любом экземпляре сервиса
+type Event struct {
+	Type      Type   `json:"type"`
+	SongID    int    `json:"song_id"`
+	GroupName string `json:"group_name"`
+}
+
+// Bus рассылает события всем подписчикам. Публикация не блокируется:
+// если подписчик не успевает читать и его буфер заполнен, событие для него теряется.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	// wakeups подписчики, которым нужен только сигнал о новых событиях
+	wakeups map[chan struct{}]struct{}
+	logger  *zap.Logger
+}
+
+// NewBus создает шину без подписчиков
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		subscribers: make(map[chan Event]struct{}),
+		wakeups:     make(map[chan struct{}]struct{}),
+		logger:      logger,
+	}
+}
+
+// Subscribe подписывается на события с буфером на buffer событий.
+// Возвращенная функция отменяет подписку и закрывает канал, повторный вызов безопасен.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	return subscribe(b, b.subscribers, buffer)
+}
+
+// SubscribeWakeups подписывается на сигналы о новых событиях без самих событий.
+// Пока сигнал не прочитан, следующие с ним сливаются и не теряются.
+// Возвращенная функция отменяет подписку так же, как у Subscribe.
+func (b *Bus) SubscribeWakeups() (<-chan struct{}, func()) {
+	return subscribe(b, b.wakeups, 1)
+}
+
+func subscribe[T any](b *Bus, subscribers map[chan T]struct{}, buffer int) (<-chan T, func()) {
+	ch := make(chan T, buffer)
+
+	b.mu.Lock()
+	subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish отправляет событие всем текущим подписчикам
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Warn("Event subscriber is too slow, event dropped",
+				zap.String("type", string(event.Type)),
+				zap.Int("song_id", event.SongID))
+		}
+	}
+	for ch := range b.wakeups {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// Непрочитанный сигнал уже разбудит подписчика
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/testTask/internal/events"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestBus(t *testing.T) {
+	bus := events.NewBus(zap.NewNop())
+
+	first, unsubscribeFirst := bus.Subscribe(1)
+	second, unsubscribeSecond := bus.Subscribe(1)
+	defer unsubscribeSecond()
+
+	created := events.Event{Type: events.SongCreated, SongID: 1, GroupName: "Muse"}
+	bus.Publish(created)
+	for _, ch := range []<-chan events.Event{first, second} {
+		if got := <-ch; got != created {
+			t.Fatalf("got %+v, want %+v", got, created)
+		}
+	}
+
+	// Отписанный подписчик больше ничего не получает, его канал закрыт
+	unsubscribeFirst()
+	unsubscribeFirst()
+	if _, ok := <-first; ok {
+		t.Fatal("channel of unsubscribed subscriber is not closed")
+	}
+
+	// Заполненный буфер не блокирует публикацию, лишнее событие теряется
+	updated := events.Event{Type: events.SongUpdated, SongID: 1}
+	bus.Publish(updated)
+	bus.Publish(events.Event{Type: events.SongDeleted, SongID: 1})
+	if got := <-second; got != updated {
+		t.Fatalf("got %+v, want %+v", got, updated)
+	}
+	select {
+	case got := <-second:
+		t.Fatalf("unexpected event %+v", got)
+	default:
+	}
+}
+
+func TestBusWakeups(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	bus := events.NewBus(zap.New(core))
+
+	wake, unsubscribe := bus.SubscribeWakeups()
+	for i := 0; i < 3; i++ {
+		bus.Publish(events.Event{Type: events.SongUpdated, SongID: i})
+	}
+
+	// Сигналы непрочитанного подписчика сливаются в один без предупреждений
+	<-wake
+	select {
+	case <-wake:
+		t.Fatal("wakeups were not coalesced")
+	default:
+	}
+	if logs.Len() != 0 {
+		t.Errorf("logged %d warnings for coalesced wakeups, want none", logs.Len())
+	}
+
+	bus.Publish(events.Event{Type: events.SongDeleted, SongID: 1})
+	<-wake
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-wake; ok {
+		t.Fatal("channel of unsubscribed subscriber is not closed")
+	}
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+)
+
+// songBatch загружает связанные данные сразу для всех песен одного ответа.
+// Первый резолвер группы или количества версий запрашивает их для всей пачки
+// одним запросом, остальные песни берут готовый результат, поэтому страница
+// песен с группами стоит два запроса к базе, а не один на каждую песню.
+type songBatch struct {
+	service *service.SongService
+	songs   []*models.Song
+
+	groupsOnce sync.Once
+	groups     map[string]*models.GroupDetails
+	groupsErr  error
+
+	revisionsOnce sync.Once
+	revisions     map[int]int
+	revisionsErr  error
+}
+
+// newSongBatch объединяет песни ответа в одну пачку
+func newSongBatch(service *service.SongService, songs []*models.Song) []*songNode {
+	batch := &songBatch{service: service, songs: songs}
+	nodes := make([]*songNode, len(songs))
+	for i, song := range songs {
+		nodes[i] = &songNode{song: song, batch: batch}
+	}
+	return nodes
+}
+
+// newSongNode пачка из одной песни
+func newSongNode(service *service.SongService, song *models.Song) *songNode {
+	return newSongBatch(service, []*models.Song{song})[0]
+}
+
+// group возвращает сведения о группе песни пачки
+func (b *songBatch) group(ctx context.Context, groupName string) (*models.GroupDetails, error) {
+	b.groupsOnce.Do(func() {
+		seen := make(map[string]bool, len(b.songs))
+		var names []string
+		for _, song := range b.songs {
+			if !seen[song.GroupName] {
+				seen[song.GroupName] = true
+				names = append(names, song.GroupName)
+			}
+		}
+
+		groups, err := b.service.GetGroups(ctx, names)
+		if err != nil {
+			b.groupsErr = err
+			return
+		}
+		b.groups = make(map[string]*models.GroupDetails, len(names))
+		for i, name := range names {
+			b.groups[name] = groups[i]
+		}
+	})
+	if b.groupsErr != nil {
+		return nil, b.groupsErr
+	}
+
+	// Песню могли удалить между чтением страницы и загрузкой групп
+	group := b.groups[groupName]
+	if group == nil {
+		return nil, errors.NewNotFound("group not found", nil)
+	}
+	return group, nil
+}
+
+// revisionCount возвращает количество сохраненных версий песни пачки
+func (b *songBatch) revisionCount(ctx context.Context, id int) (int, error) {
+	b.revisionsOnce.Do(func() {
+		ids := make([]int, len(b.songs))
+		for i, song := range b.songs {
+			ids[i] = song.ID
+		}
+		b.revisions, b.revisionsErr = b.service.GetRevisionCounts(ctx, ids)
+	})
+	if b.revisionsErr != nil {
+		return 0, b.revisionsErr
+	}
+	return b.revisions[id], nil
+}
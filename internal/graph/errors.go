@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/testTask/internal/errors"
+)
+
+// graphError ошибка GraphQL с кодом ошибки приложения в extensions
+type graphError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+// newGraphError переводит ошибку приложения в ошибку GraphQL. Детали внутренних
+// ошибок наружу не отдаем, как и в REST API.
+func newGraphError(err error) *graphError {
+	appErr, ok := errors.As(err)
+	if !ok || appErr.Type == errors.Internal {
+		return &graphError{
+			message:    "internal server error",
+			extensions: map[string]interface{}{"code": errors.Internal},
+		}
+	}
+
+	gqlErr := &graphError{
+		message:    appErr.Message,
+		extensions: map[string]interface{}{"code": appErr.Type},
+	}
+	if len(appErr.Fields) > 0 {
+		gqlErr.extensions["fields"] = appErr.Fields
+	}
+	if appErr.ExistingID != 0 {
+		gqlErr.extensions["existingId"] = appErr.ExistingID
+	}
+	return gqlErr
+}
+
+func (e *graphError) Error() string {
+	return e.message
+}
+
+// Extensions попадает в поле extensions ошибки ответа
+func (e *graphError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+// internal ошибка скрывает внутреннюю
+func (e *graphError) internal() bool {
+	return e.extensions["code"] == errors.Internal
+}
+
+// formatError ошибка GraphQL без позиции в запросе
+func formatError(err error) gqlerrors.FormattedError {
+	gqlErr := newGraphError(err)
+	return gqlerrors.FormatError(gqlerrors.NewError(gqlErr.message, nil, "", nil, nil, gqlErr))
+}
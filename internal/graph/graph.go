@@ -0,0 +1,75 @@
+// Package graph реализует GraphQL API поверх SongService
+package graph
+
+import (
+	"context"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// Request тело запроса GraphQL
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Executor разбирает, проверяет и выполняет запросы GraphQL
+type Executor struct {
+	schema graphql.Schema
+	limits Limits
+	logger *zap.Logger
+}
+
+func NewExecutor(songService *service.SongService, limits Limits, logger *zap.Logger) (*Executor, error) {
+	schema, err := newSchema(&resolver{service: songService, logger: logger})
+	if err != nil {
+		return nil, err
+	}
+	return &Executor{
+		schema: schema,
+		limits: limits,
+		logger: logger,
+	}, nil
+}
+
+// Execute выполняет запрос. Ошибки разбора, проверки и резолверов возвращаются
+// в поле errors результата.
+func (e *Executor) Execute(ctx context.Context, req *Request) *graphql.Result {
+	ctx, span := tracing.Start(ctx, "GraphQL.Execute", attribute.String("graphql.operation.name", req.OperationName))
+	defer span.End()
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		tracing.RecordError(span, err)
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+
+	if validation := graphql.ValidateDocument(&e.schema, doc, graphql.SpecifiedRules); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	if err := checkLimits(&e.schema, doc, req.OperationName, req.Variables, e.limits); err != nil {
+		logging.FromContext(ctx, e.logger).Warn("GraphQL query rejected", zap.Error(err))
+		tracing.RecordError(span, err)
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(err)}}
+	}
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+}
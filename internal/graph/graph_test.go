@@ -0,0 +1,155 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/testTask/internal/graph"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/service"
+	"go.uber.org/zap"
+)
+
+var testLimits = graph.Limits{MaxDepth: 5, MaxComplexity: 200}
+
+// countingRepository считает запросы связанных данных к хранилищу
+type countingRepository struct {
+	repository.SongRepository
+	groups, revisions int
+}
+
+func (r *countingRepository) GetGroup(ctx context.Context, groupName string) (*models.GroupDetails, error) {
+	r.groups++
+	return r.SongRepository.GetGroup(ctx, groupName)
+}
+
+func (r *countingRepository) CountSongsByGroup(ctx context.Context, groupNames []string) (map[string]int, error) {
+	r.groups++
+	return r.SongRepository.CountSongsByGroup(ctx, groupNames)
+}
+
+func (r *countingRepository) CountSongRevisions(ctx context.Context, songID int) (int, error) {
+	r.revisions++
+	return r.SongRepository.CountSongRevisions(ctx, songID)
+}
+
+func (r *countingRepository) CountRevisionsBySong(ctx context.Context, songIDs []int) (map[int]int, error) {
+	r.revisions++
+	return r.SongRepository.CountRevisionsBySong(ctx, songIDs)
+}
+
+func newExecutor(t *testing.T, repo repository.SongRepository) *graph.Executor {
+	t.Helper()
+	executor, err := graph.NewExecutor(service.NewSongService(repo, zap.NewNop()), testLimits, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+	return executor
+}
+
+// execute выполняет запрос и возвращает результат в виде JSON
+func execute(t *testing.T, executor *graph.Executor, query string, variables map[string]interface{}) string {
+	t.Helper()
+	result := executor.Execute(context.Background(), &graph.Request{Query: query, Variables: variables})
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to encode result: %v", err)
+	}
+	return string(body)
+}
+
+func TestSongsLoadRelatedDataInBatches(t *testing.T) {
+	repo := &countingRepository{SongRepository: repository.NewMemorySongRepository()}
+	executor := newExecutor(t, repo)
+
+	for _, song := range [][2]string{{"Muse", "Hysteria"}, {"Muse", "Uprising"}, {"Queen", "Innuendo"}} {
+		got := execute(t, executor, `mutation($group: String!, $song: String!) {
+			createSong(input: {groupName: $group, songName: $song}) { id }
+		}`, map[string]interface{}{"group": song[0], "song": song[1]})
+		if strings.Contains(got, `"errors"`) {
+			t.Fatalf("createSong: %s", got)
+		}
+	}
+
+	got := execute(t, executor, `{
+		songs(pageSize: 10) { totalItems songs { songName group { name songCount } revisionCount } }
+	}`, nil)
+	want := `{"data":{"songs":{"songs":[` +
+		`{"group":{"name":"Queen","songCount":1},"revisionCount":1,"songName":"Innuendo"},` +
+		`{"group":{"name":"Muse","songCount":2},"revisionCount":1,"songName":"Uprising"},` +
+		`{"group":{"name":"Muse","songCount":2},"revisionCount":1,"songName":"Hysteria"}],"totalItems":3}}}`
+	if got != want {
+		t.Errorf("songs:\n got %s\nwant %s", got, want)
+	}
+	if repo.groups != 1 || repo.revisions != 1 {
+		t.Errorf("storage reads: %d groups, %d revisions, want 1 and 1", repo.groups, repo.revisions)
+	}
+}
+
+func TestErrorsCarryApplicationCode(t *testing.T) {
+	executor := newExecutor(t, repository.NewMemorySongRepository())
+
+	got := execute(t, executor, `mutation { createSong(input: {groupName: " ", songName: "Hysteria"}) { id } }`, nil)
+	if !strings.Contains(got, `"code":"VALIDATION"`) || !strings.Contains(got, `"field":"group"`) {
+		t.Errorf("validation error: %s", got)
+	}
+
+	got = execute(t, executor, `{ song(id: 42) { id } }`, nil)
+	if got != `{"data":{"song":null}}` {
+		t.Errorf("missing song: %s", got)
+	}
+
+	got = execute(t, executor, `{ lyrics(songId: 42) { text } }`, nil)
+	if !strings.Contains(got, `"code":"NOT_FOUND"`) {
+		t.Errorf("lyrics of missing song: %s", got)
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	executor := newExecutor(t, repository.NewMemorySongRepository())
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		rejected  string
+	}{
+		{
+			name:  "within limits",
+			query: `{ songs { songs { id group { name } } } }`,
+		},
+		{
+			name:     "too deep",
+			query:    `{ __schema { types { fields { type { ofType { name } } } } } }`,
+			rejected: "query depth 6 exceeds the limit of 5",
+		},
+		{
+			name:     "page size multiplies complexity",
+			query:    `{ songs(pageSize: 50) { songs { id songName text } } }`,
+			rejected: "query complexity 201 exceeds the limit of 200",
+		},
+		{
+			name:      "page size from variable",
+			query:     `query($size: Int) { songs(pageSize: $size) { songs { ...fields } } } fragment fields on Song { id songName text }`,
+			variables: map[string]interface{}{"size": float64(50)},
+			rejected:  "query complexity 201 exceeds the limit of 200",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := execute(t, executor, tt.query, tt.variables)
+			if tt.rejected == "" {
+				if strings.Contains(got, "exceeds the limit") {
+					t.Errorf("query rejected: %s", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.rejected) || !strings.Contains(got, `"code":"BAD_REQUEST"`) {
+				t.Errorf("got %s, want rejection %q", got, tt.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/validation"
+)
+
+// Limits ограничения запросов, проверяются до выполнения
+type Limits struct {
+	// MaxDepth наибольшая вложенность полей
+	MaxDepth int
+	// MaxComplexity наибольшая стоимость запроса: каждое поле стоит 1,
+	// поля внутри страницы песен умножаются на ее размер
+	MaxComplexity int
+}
+
+// pagedFields поля со страницей песен: стоимость вложенных полей умножается на pageSize
+var pagedFields = map[string]bool{
+	"Query.songs": true,
+}
+
+// fieldsOwner тип с полями: объект или интерфейс
+type fieldsOwner interface {
+	graphql.Type
+	Fields() graphql.FieldDefinitionMap
+}
+
+// cost вложенность и стоимость выборки
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// limitChecker считает стоимость операции по схеме
+type limitChecker struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkLimits проверяет вложенность и стоимость выполняемой операции документа.
+// Документ уже прошел проверку схемы, поэтому циклов во фрагментах в нем нет.
+func checkLimits(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}, limits Limits) error {
+	c := &limitChecker{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	// Неизвестную операцию отклонит выполнение
+	if operation == nil {
+		return nil
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	}
+	if root == nil {
+		return nil
+	}
+
+	total := c.selectionSet(root, operation.SelectionSet)
+	if total.depth > limits.MaxDepth {
+		return errors.NewBadRequest(fmt.Sprintf("query depth %d exceeds the limit of %d", total.depth, limits.MaxDepth), nil)
+	}
+	if total.complexity > limits.MaxComplexity {
+		return errors.NewBadRequest(fmt.Sprintf("query complexity %d exceeds the limit of %d", total.complexity, limits.MaxComplexity), nil)
+	}
+	return nil
+}
+
+// selectionSet считает стоимость выборки полей типа parent
+func (c *limitChecker) selectionSet(parent graphql.Named, set *ast.SelectionSet) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+
+	for _, selection := range set.Selections {
+		var next cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			next = c.field(parent, selection)
+		case *ast.InlineFragment:
+			fragmentType := parent
+			if selection.TypeCondition != nil {
+				fragmentType = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+			next = c.selectionSet(fragmentType, selection.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				next = c.selectionSet(c.schema.Type(fragment.TypeCondition.Name.Value), fragment.SelectionSet)
+			}
+		}
+		total.depth = max(total.depth, next.depth)
+		total.complexity += next.complexity
+	}
+	return total
+}
+
+// field считает стоимость поля вместе с его выборкой
+func (c *limitChecker) field(parent graphql.Named, field *ast.Field) cost {
+	var definition *graphql.FieldDefinition
+	switch name := field.Name.Value; name {
+	case graphql.SchemaMetaFieldDef.Name:
+		definition = graphql.SchemaMetaFieldDef
+	case graphql.TypeMetaFieldDef.Name:
+		definition = graphql.TypeMetaFieldDef
+	case graphql.TypeNameMetaFieldDef.Name:
+		definition = graphql.TypeNameMetaFieldDef
+	default:
+		if owner, ok := parent.(fieldsOwner); ok {
+			definition = owner.Fields()[name]
+		}
+	}
+	if definition == nil {
+		return cost{depth: 1, complexity: 1}
+	}
+
+	nested := c.selectionSet(graphql.GetNamed(definition.Type), field.SelectionSet)
+	if owner, ok := parent.(fieldsOwner); ok && pagedFields[owner.Name()+"."+definition.Name] {
+		nested.complexity *= c.pageSize(field)
+	}
+	return cost{depth: nested.depth + 1, complexity: nested.complexity + 1}
+}
+
+// pageSize размер страницы из аргумента pageSize или переменной, как его увидит
+// резолвер. Размер больше допустимого отклонит проверка сервиса.
+func (c *limitChecker) pageSize(field *ast.Field) int {
+	size := defaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "pageSize" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if parsed, err := strconv.Atoi(value.Value); err == nil && parsed > 0 {
+				size = parsed
+			}
+		case *ast.Variable:
+			switch variable := c.variables[value.Name.Value].(type) {
+			case float64:
+				if variable > 0 {
+					size = int(min(variable, validation.MaxPageSize))
+				}
+			case int:
+				if variable > 0 {
+					size = variable
+				}
+			}
+		}
+	}
+	return min(size, validation.MaxPageSize)
+}
@@ -0,0 +1,148 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+	"go.uber.org/zap"
+)
+
+// resolver резолверы запросов и мутаций поверх SongService
+type resolver struct {
+	service *service.SongService
+	logger  *zap.Logger
+}
+
+// resolve переводит ошибки резолвера в ошибки GraphQL с кодом ошибки приложения.
+// Внутренние ошибки логируются, клиенту уходит только общее сообщение.
+func (r *resolver) resolve(fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := fn(p)
+		if err == nil {
+			return result, nil
+		}
+		gqlErr := newGraphError(err)
+		if gqlErr.internal() {
+			logging.FromContext(p.Context, r.logger).Error("GraphQL resolver failed",
+				zap.String("field", p.Info.FieldName),
+				zap.Error(err))
+		}
+		return nil, gqlErr
+	}
+}
+
+// songs возвращает страницу песен по фильтру
+func (r *resolver) songs(p graphql.ResolveParams) (interface{}, error) {
+	filter := &models.SongFilter{
+		GroupName: stringArg(p.Args, "groupName"),
+		SongName:  stringArg(p.Args, "songName"),
+		FromDate:  dateArg(p.Args, "fromDate"),
+		ToDate:    dateArg(p.Args, "toDate"),
+		Text:      stringArg(p.Args, "text"),
+		Link:      stringArg(p.Args, "link"),
+		Page:      intArg(p.Args, "page"),
+		PageSize:  intArg(p.Args, "pageSize"),
+	}
+
+	response, err := r.service.GetSongs(p.Context, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	songs := make([]*models.Song, len(response.Songs))
+	for i := range response.Songs {
+		songs[i] = &response.Songs[i]
+	}
+	return &songPage{response: response, nodes: newSongBatch(r.service, songs)}, nil
+}
+
+// song возвращает песню по ID или null
+func (r *resolver) song(p graphql.ResolveParams) (interface{}, error) {
+	details, err := r.service.GetSong(p.Context, intArg(p.Args, "id"), nil)
+	if appErr, ok := errors.As(err); ok && appErr.Type == errors.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSongNode(r.service, &details.Song), nil
+}
+
+// lyrics возвращает страницу куплетов песни
+func (r *resolver) lyrics(p graphql.ResolveParams) (interface{}, error) {
+	return r.service.GetLyrics(p.Context, intArg(p.Args, "songId"), intArg(p.Args, "page"), intArg(p.Args, "pageSize"))
+}
+
+// group возвращает группу по названию или null
+func (r *resolver) group(p graphql.ResolveParams) (interface{}, error) {
+	groups, err := r.service.GetGroups(p.Context, []string{stringArg(p.Args, "name")})
+	if err != nil {
+		return nil, err
+	}
+	if groups[0] == nil {
+		return nil, nil
+	}
+	return groups[0], nil
+}
+
+// createSong создает песню
+func (r *resolver) createSong(p graphql.ResolveParams) (interface{}, error) {
+	song, err := r.service.CreateSong(p.Context, songRequest(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	return newSongNode(r.service, song), nil
+}
+
+// updateSong частично обновляет песню
+func (r *resolver) updateSong(p graphql.ResolveParams) (interface{}, error) {
+	song, err := r.service.UpdateSong(p.Context, intArg(p.Args, "id"), songRequest(p.Args))
+	if err != nil {
+		return nil, err
+	}
+	return newSongNode(r.service, song), nil
+}
+
+// deleteSong удаляет песню
+func (r *resolver) deleteSong(p graphql.ResolveParams) (interface{}, error) {
+	if err := r.service.DeleteSong(p.Context, intArg(p.Args, "id")); err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+// songRequest собирает запрос сервиса из аргумента input
+func songRequest(args map[string]interface{}) *models.SongRequest {
+	input, _ := args["input"].(map[string]interface{})
+	return &models.SongRequest{
+		GroupName: stringArg(input, "groupName"),
+		SongName:  stringArg(input, "songName"),
+		Text:      stringArg(input, "text"),
+		Link:      stringArg(input, "link"),
+	}
+}
+
+// stringArg возвращает строковый аргумент, пустую строку без него
+func stringArg(args map[string]interface{}, name string) string {
+	value, _ := args[name].(string)
+	return value
+}
+
+// intArg возвращает целый аргумент, 0 без него
+func intArg(args map[string]interface{}, name string) int {
+	value, _ := args[name].(int)
+	return value
+}
+
+// dateArg возвращает аргумент с датой, nil без него
+func dateArg(args map[string]interface{}, name string) *time.Time {
+	value, ok := args[name].(time.Time)
+	if !ok {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,277 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+)
+
+// dateLayout формат дат выпуска в запросах и ответах
+const dateLayout = "2006-01-02"
+
+// defaultPageSize размер страницы без аргумента pageSize, как в REST API
+const defaultPageSize = 10
+
+// songNode песня в ответе вместе с пачкой, через которую загружаются ее связанные данные
+type songNode struct {
+	song  *models.Song
+	batch *songBatch
+}
+
+// songPage страница песен в ответе
+type songPage struct {
+	response *models.SongsResponse
+	nodes    []*songNode
+}
+
+// dateType скаляр даты в формате YYYY-MM-DD
+var dateType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Date",
+	Description: "Date in format YYYY-MM-DD",
+	Serialize: func(value interface{}) interface{} {
+		if date, ok := value.(time.Time); ok {
+			return date.Format(dateLayout)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if raw, ok := value.(string); ok {
+			return parseDate(raw)
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) interface{} {
+		if raw, ok := value.(*ast.StringValue); ok {
+			return parseDate(raw.Value)
+		}
+		return nil
+	},
+})
+
+// parseDate разбирает дату, nil означает неверный формат и ошибку проверки запроса
+func parseDate(raw string) interface{} {
+	date, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		return nil
+	}
+	return date
+}
+
+// newSchema собирает схему GraphQL поверх резолверов r
+func newSchema(r *resolver) (graphql.Schema, error) {
+	groupType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Group",
+		Description: "Group and the number of its songs",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.GroupDetails).Name, nil
+				},
+			},
+			"songCount": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*models.GroupDetails).SongCount, nil
+				},
+			},
+		},
+	})
+
+	songType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Song",
+		Fields: graphql.Fields{
+			"id":          songField(graphql.Int, func(s *models.Song) interface{} { return s.ID }),
+			"groupName":   songField(graphql.String, func(s *models.Song) interface{} { return s.GroupName }),
+			"songName":    songField(graphql.String, func(s *models.Song) interface{} { return s.SongName }),
+			"releaseDate": songField(dateType, func(s *models.Song) interface{} { return s.ReleaseDate }),
+			"text":        songField(graphql.String, func(s *models.Song) interface{} { return s.Text }),
+			"link":        songField(graphql.String, func(s *models.Song) interface{} { return s.Link }),
+			"createdAt":   songField(graphql.DateTime, func(s *models.Song) interface{} { return s.CreatedAt }),
+			"updatedAt":   songField(graphql.DateTime, func(s *models.Song) interface{} { return s.UpdatedAt }),
+			"lyrics": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Description: "Verses of the song",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					verses := service.SplitVerses(p.Source.(*songNode).song.Text)
+					if verses == nil {
+						verses = []string{}
+					}
+					return verses, nil
+				},
+			},
+			"group": &graphql.Field{
+				Type:        graphql.NewNonNull(groupType),
+				Description: "Group of the song, loaded at once for all songs of the response",
+				Resolve: r.resolve(func(p graphql.ResolveParams) (interface{}, error) {
+					node := p.Source.(*songNode)
+					return node.batch.group(p.Context, node.song.GroupName)
+				}),
+			},
+			"revisionCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Number of saved revisions of the song, loaded at once for all songs of the response",
+				Resolve: r.resolve(func(p graphql.ResolveParams) (interface{}, error) {
+					node := p.Source.(*songNode)
+					return node.batch.revisionCount(p.Context, node.song.ID)
+				}),
+			},
+		},
+	})
+
+	songPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SongPage",
+		Fields: graphql.Fields{
+			"songs": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(songType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*songPage).nodes, nil
+				},
+			},
+			"currentPage": pageField(func(r *models.SongsResponse) int { return r.CurrentPage }),
+			"totalPages":  pageField(func(r *models.SongsResponse) int { return r.TotalPages }),
+			"totalItems":  pageField(func(r *models.SongsResponse) int { return r.TotalItems }),
+			"pageSize":    pageField(func(r *models.SongsResponse) int { return r.PageSize }),
+		},
+	})
+
+	lyricsPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LyricsPage",
+		Fields: graphql.Fields{
+			"text":        lyricsField(graphql.String, func(l *models.LyricsResponse) interface{} { return l.Text }),
+			"currentPage": lyricsField(graphql.Int, func(l *models.LyricsResponse) interface{} { return l.CurrentPage }),
+			"totalPages":  lyricsField(graphql.Int, func(l *models.LyricsResponse) interface{} { return l.TotalPages }),
+			"pageSize":    lyricsField(graphql.Int, func(l *models.LyricsResponse) interface{} { return l.PageSize }),
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"songs": &graphql.Field{
+				Type:        graphql.NewNonNull(songPageType),
+				Description: "Songs with filtering and pagination, the same as GET /api/v1/songs",
+				Args: graphql.FieldConfigArgument{
+					"groupName": {Type: graphql.String},
+					"songName":  {Type: graphql.String},
+					"fromDate":  {Type: dateType},
+					"toDate":    {Type: dateType},
+					"text":      {Type: graphql.String},
+					"link":      {Type: graphql.String},
+					"page":      {Type: graphql.Int},
+					"pageSize":  {Type: graphql.Int, Description: "Page size, 10 by default"},
+				},
+				Resolve: r.resolve(r.songs),
+			},
+			"song": &graphql.Field{
+				Type:        songType,
+				Description: "Song by ID, null when it does not exist",
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: r.resolve(r.song),
+			},
+			"lyrics": &graphql.Field{
+				Type:        graphql.NewNonNull(lyricsPageType),
+				Description: "Page of verses of the song",
+				Args: graphql.FieldConfigArgument{
+					"songId":   {Type: graphql.NewNonNull(graphql.Int)},
+					"page":     {Type: graphql.Int},
+					"pageSize": {Type: graphql.Int},
+				},
+				Resolve: r.resolve(r.lyrics),
+			},
+			"group": &graphql.Field{
+				Type:        groupType,
+				Description: "Group by name, case insensitive, null when it has no songs",
+				Args: graphql.FieldConfigArgument{
+					"name": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: r.resolve(r.group),
+			},
+		},
+	})
+
+	songInput := func(name string, required bool) *graphql.InputObject {
+		nameType := graphql.Input(graphql.String)
+		if required {
+			nameType = graphql.NewNonNull(graphql.String)
+		}
+		return graphql.NewInputObject(graphql.InputObjectConfig{
+			Name: name,
+			Fields: graphql.InputObjectConfigFieldMap{
+				"groupName": {Type: nameType},
+				"songName":  {Type: nameType},
+				"text":      {Type: graphql.String},
+				"link":      {Type: graphql.String},
+			},
+		})
+	}
+
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createSong": &graphql.Field{
+				Type: graphql.NewNonNull(songType),
+				Args: graphql.FieldConfigArgument{
+					"input": {Type: graphql.NewNonNull(songInput("CreateSongInput", true))},
+				},
+				Resolve: r.resolve(r.createSong),
+			},
+			"updateSong": &graphql.Field{
+				Type:        graphql.NewNonNull(songType),
+				Description: "Partial update: omitted fields keep their values",
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.NewNonNull(graphql.Int)},
+					"input": {Type: graphql.NewNonNull(songInput("UpdateSongInput", false))},
+				},
+				Resolve: r.resolve(r.updateSong),
+			},
+			"deleteSong": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": {Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: r.resolve(r.deleteSong),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    queryType,
+		Mutation: mutationType,
+	})
+}
+
+// songField обязательное поле песни
+func songField(fieldType graphql.Output, get func(*models.Song) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(fieldType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*songNode).song), nil
+		},
+	}
+}
+
+// pageField поле пагинации страницы песен
+func pageField(get func(*models.SongsResponse) int) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Int),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*songPage).response), nil
+		},
+	}
+}
+
+// lyricsField обязательное поле страницы текста
+func lyricsField(fieldType graphql.Output, get func(*models.LyricsResponse) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(fieldType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(*models.LyricsResponse)), nil
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+)
+
+// EventStreamOptions параметры потока событий
+type EventStreamOptions struct {
+	// PollInterval как часто проверять новые события без уведомления от шины
+	PollInterval time.Duration
+	// HeartbeatInterval как часто отправлять комментарий в тихий поток
+	HeartbeatInterval time.Duration
+}
+
+type EventHandler struct {
+	service *service.EventService
+	bus     *events.Bus
+	options EventStreamOptions
+	logger  *zap.Logger
+
+	// closed закрывается при остановке сервера и завершает открытые потоки,
+	// иначе graceful shutdown ждал бы их до своего таймаута
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewEventHandler создает обработчик потока событий. Шина bus будит потоки
+// сразу после изменений, без нее события приходят раз в PollInterval.
+func NewEventHandler(service *service.EventService, bus *events.Bus, options EventStreamOptions, logger *zap.Logger) *EventHandler {
+	return &EventHandler{
+		service: service,
+		bus:     bus,
+		options: options,
+		logger:  logger,
+		closed:  make(chan struct{}),
+	}
+}
+
+// Close завершает все открытые потоки, повторный вызов безопасен
+func (h *EventHandler) Close() {
+	h.closeOnce.Do(func() { close(h.closed) })
+}
+
+// lastEventID разбирает позицию возобновления из заголовка Last-Event-ID, который
+// EventSource передает при переподключении, или из query параметра last_event_id
+func lastEventID(v *validation.Errors, r *http.Request) *int64 {
+	field, raw := "Last-Event-ID", r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		field, raw = "last_event_id", r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		v.Add(field, "must be an integer")
+		return nil
+	}
+	return &id
+}
+
+// @Summary Stream song events
+// @Description Server-Sent Events stream of song changes. Every event has id (position in the persisted event sequence), event (song.created, song.updated, song.deleted) and data with the event JSON. Reconnecting with Last-Event-ID resumes after that event, without it the stream starts with new events. Heartbeat comments are sent while the stream is idle.
+// @Tags events
+// @Produce text/event-stream
+// @Param group query string false "Only events of songs of this group, case insensitive"
+// @Param event_types query string false "Comma separated event types: song.created, song.updated, song.deleted"
+// @Param last_event_id query int false "Resume after this event when Last-Event-ID header is not set"
+// @Param Last-Event-ID header int false "Resume after this event"
+// @Success 200 {object} models.OutboxEvent "Stream of events"
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /events [get]
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := logging.FromContext(ctx, h.logger)
+	log.Debug("Handling StreamEvents request")
+
+	v := validation.New()
+	filter := &models.EventFilter{
+		GroupName: r.URL.Query().Get("group"),
+		Types:     queryList(r, "event_types"),
+	}
+	resumeAfter := lastEventID(v, r)
+	if err := v.Err(); err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	// Подписка раньше первого чтения, чтобы не пропустить изменение между ними
+	wake, unsubscribe := h.bus.SubscribeWakeups()
+	defer unsubscribe()
+
+	cursor, err := h.service.OpenStream(ctx, filter, resumeAfter)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Поток живет дольше server.write_timeout, поэтому дедлайн записи снимается
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("Failed to clear write deadline, stream is limited by server write timeout", zap.Error(err))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Просим nginx не буферизовать поток
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		log.Error("Streaming is not supported by the response writer", zap.Error(err))
+		return
+	}
+
+	poll := time.NewTicker(h.options.PollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.options.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		if cursor, err = h.sendEvents(ctx, w, rc, cursor, filter); err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Event stream stopped", zap.Int64("lastEventId", cursor), zap.Error(err))
+			}
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-h.closed:
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendEvents отправляет все события после cursor и возвращает ID последнего прочитанного
+func (h *EventHandler) sendEvents(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController, cursor int64, filter *models.EventFilter) (int64, error) {
+	for {
+		batch, next, err := h.service.GetEvents(ctx, cursor, filter)
+		if err != nil {
+			return cursor, err
+		}
+		if next == cursor {
+			return cursor, nil
+		}
+
+		for _, event := range batch {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return cursor, err
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return cursor, err
+			}
+		}
+		// Отфильтрованные события в конце пачки отправляются одним id без данных:
+		// клиент запомнит позицию и после переподключения не будет их перечитывать
+		if len(batch) == 0 || batch[len(batch)-1].ID != next {
+			if _, err := fmt.Fprintf(w, "id: %d\n\n", next); err != nil {
+				return cursor, err
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return cursor, err
+		}
+		cursor = next
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/validation"
+)
+
+// queryList разбирает query параметр со списком значений через запятую
+func queryList(r *http.Request, name string) []string {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil
+	}
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// queryFields разбирает параметр fields и проверяет имена полей песни
+func queryFields(v *validation.Errors, r *http.Request) []string {
+	fields := queryList(r, "fields")
+	validation.Values(v, "fields", fields, models.SongFields)
+	return fields
+}
+
+// hasField сообщает, попадет ли поле в ответ: пустой fields означает все поля
+func hasField(fields []string, name string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// selectFields оставляет в JSON объекте песни только поля из fields.
+// Поля вне models.SongFields (встроенные через include) сохраняются всегда.
+func selectFields(song map[string]any, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	for _, name := range models.SongFields {
+		if !hasField(fields, name) {
+			delete(song, name)
+		}
+	}
+}
+
+// sparseSong переводит песню в JSON объект с выбранными полями
+func sparseSong(v any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+	var song map[string]any
+	if err := remarshal(v, &song); err != nil {
+		return nil, err
+	}
+	selectFields(song, fields)
+	return song, nil
+}
+
+// sparseSongs применяет fields к каждой песне в ответе со списком
+func sparseSongs(response *models.SongsResponse, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return response, nil
+	}
+	var body struct {
+		Songs []map[string]any `json:"songs"`
+		models.SongsResponse
+	}
+	body.SongsResponse = *response
+	if err := remarshal(response.Songs, &body.Songs); err != nil {
+		return nil, err
+	}
+	for _, song := range body.Songs {
+		selectFields(song, fields)
+	}
+	return body, nil
+}
+
+// remarshal перекладывает значение в другой тип через JSON
+func remarshal(from, to any) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
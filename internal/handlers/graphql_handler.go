@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/graph"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+)
+
+type GraphQLHandler struct {
+	executor *graph.Executor
+	logger   *zap.Logger
+}
+
+func NewGraphQLHandler(executor *graph.Executor, logger *zap.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+		logger:   logger,
+	}
+}
+
+// Execute выполняет запрос GraphQL. Ошибки запроса и резолверов возвращаются
+// в поле errors ответа со статусом 200, Problem только для нечитаемого тела.
+func (h *GraphQLHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GraphQL request")
+
+	var req graph.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, errors.NewBadRequest("Invalid request body", err), h.logger)
+		return
+	}
+	v := validation.New()
+	v.Check(req.Query != "", "query", "is required")
+	if err := v.Err(); err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, h.executor.Execute(r.Context(), &req), h.logger)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Статусы проверок готовности
+const (
+	healthStatusOK           = "ok"
+	healthStatusFail         = "fail"
+	healthStatusShuttingDown = "shutting_down"
+)
+
+// readinessTimeout ограничивает время всех проверок одного запроса /readyz
+const readinessTimeout = 3 * time.Second
+
+// HealthCheck проверка одной зависимости сервиса
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) (details string, err error)
+}
+
+// DependencyStatus результат проверки зависимости
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Details string `json:"details,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// HealthResponse структура ответа проверок живости и готовности
+type HealthResponse struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	checks       []HealthCheck
+	shuttingDown func() bool
+	logger       *zap.Logger
+}
+
+func NewHealthHandler(checks []HealthCheck, shuttingDown func() bool, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		checks:       checks,
+		shuttingDown: shuttingDown,
+		logger:       logger,
+	}
+}
+
+// Liveness сообщает, что процесс жив и обслуживает запросы
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	h.writeResponse(w, http.StatusOK, &HealthResponse{Status: healthStatusOK})
+}
+
+// Readiness проверяет зависимости и сообщает, готов ли сервис принимать трафик
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	// Во время остановки сразу отвечаем отказом, чтобы балансировщик снял трафик
+	if h.shuttingDown() {
+		h.writeResponse(w, http.StatusServiceUnavailable, &HealthResponse{Status: healthStatusShuttingDown})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	response := &HealthResponse{
+		Status: healthStatusOK,
+		Checks: make(map[string]DependencyStatus, len(h.checks)),
+	}
+
+	// Зависимости проверяем параллельно, чтобы медленная не задерживала остальные
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			details, err := check.Check(ctx)
+			status := DependencyStatus{Status: healthStatusOK, Details: details}
+			if err != nil {
+				status.Status = healthStatusFail
+				status.Error = err.Error()
+				h.logger.Warn("Readiness check failed", zap.String("check", check.Name), zap.Error(err))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.Name] = status
+			if err != nil {
+				response.Status = healthStatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if response.Status != healthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	h.writeResponse(w, status, response)
+}
+
+// writeResponse отправляет ответ проверки в JSON
+func (h *HealthHandler) writeResponse(w http.ResponseWriter, status int, response *HealthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode health response", zap.Error(err))
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"go.uber.org/zap"
+)
+
+// problemContentType тип содержимого ответов с ошибкой по RFC 7807
+const problemContentType = "application/problem+json"
+
+// Problem тело ответа с ошибкой в формате RFC 7807
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      errors.ErrorType    `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []errors.FieldError `json:"errors,omitempty"`
+	// ExistingID идентификатор песни, с которой возник конфликт
+	ExistingID int `json:"existing_id,omitempty"`
+}
+
+// statusByErrorType соответствие типов ошибок приложения HTTP статусам
+var statusByErrorType = map[errors.ErrorType]int{
+	errors.NotFound:       http.StatusNotFound,
+	errors.BadRequest:     http.StatusBadRequest,
+	errors.Validation:     http.StatusUnprocessableEntity,
+	errors.AlreadyExists:  http.StatusConflict,
+	errors.ResyncRequired: http.StatusGone,
+	errors.Internal:       http.StatusInternalServerError,
+}
+
+// newProblem собирает Problem из ошибки приложения
+func newProblem(r *http.Request, err error) *Problem {
+	problem := &Problem{
+		Status:    http.StatusInternalServerError,
+		Code:      errors.Internal,
+		Detail:    "Internal server error",
+		Instance:  r.URL.Path,
+		RequestID: logging.RequestID(r.Context()),
+	}
+
+	// Детали внутренних ошибок наружу не отдаем
+	if appErr, ok := errors.As(err); ok {
+		if status, known := statusByErrorType[appErr.Type]; known && status != http.StatusInternalServerError {
+			problem.Status = status
+			problem.Code = appErr.Type
+			problem.Detail = appErr.Message
+			problem.Errors = appErr.Fields
+			problem.ExistingID = appErr.ExistingID
+		}
+	}
+
+	problem.Type = problemType(problem.Code)
+	problem.Title = http.StatusText(problem.Status)
+	return problem
+}
+
+// problemType возвращает стабильный URI типа проблемы для кода ошибки
+func problemType(code errors.ErrorType) string {
+	return "/problems/" + strings.ReplaceAll(strings.ToLower(string(code)), "_", "-")
+}
+
+// writeProblem отправляет Problem клиенту
+func writeProblem(w http.ResponseWriter, problem *Problem, logger *zap.Logger) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		logger.Error("Failed to encode problem response", zap.Error(err))
+	}
+}
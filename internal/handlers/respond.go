@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/testTask/internal/logging"
+	"go.uber.org/zap"
+)
+
+// respondError логирует ошибку запроса и отвечает application/problem+json
+func respondError(w http.ResponseWriter, r *http.Request, err error, logger *zap.Logger) {
+	problem := newProblem(r, err)
+
+	logging.FromContext(r.Context(), logger).Error("Request error",
+		zap.Error(err),
+		zap.Int("status", problem.Status),
+		zap.String("code", string(problem.Code)),
+		zap.String("message", problem.Detail),
+	)
+
+	writeProblem(w, problem, logger)
+}
+
+// respondJSON отправляет успешный ответ в JSON
+func respondJSON(w http.ResponseWriter, status int, v any, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// Заголовки уже отправлены, поэтому ошибку кодирования можно только залогировать
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
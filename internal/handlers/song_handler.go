@@ -8,11 +8,16 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
 	"github.com/testTask/internal/models"
 	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/validation"
 	"go.uber.org/zap"
 )
 
+// dateLayout формат дат в query параметрах
+const dateLayout = "2006-01-02"
+
 type SongHandler struct {
 	service *service.SongService
 	logger  *zap.Logger
@@ -25,41 +30,42 @@ func NewSongHandler(service *service.SongService, logger *zap.Logger) *SongHandl
 	}
 }
 
-// handleError обрабатывает ошибки и возвращает соответствующий HTTP статус
-func (h *SongHandler) handleError(w http.ResponseWriter, err error) {
-	var status int
-	var message string
-
-	if appErr, ok := err.(*errors.Error); ok {
-		switch appErr.Type {
-		case errors.NotFound:
-			status = http.StatusNotFound
-			message = appErr.Message
-		case errors.BadRequest:
-			status = http.StatusBadRequest
-			message = appErr.Message
-		case errors.Validation:
-			status = http.StatusUnprocessableEntity
-			message = appErr.Message
-		case errors.AlreadyExists:
-			status = http.StatusConflict
-			message = appErr.Message
-		default:
-			status = http.StatusInternalServerError
-			message = "Internal server error"
-		}
-	} else {
-		status = http.StatusInternalServerError
-		message = "Internal server error"
-	}
-
-	h.logger.Error("Request error",
-		zap.Error(err),
-		zap.Int("status", status),
-		zap.String("message", message),
-	)
-
-	http.Error(w, message, status)
+// handleError обрабатывает ошибки и возвращает ответ application/problem+json
+func (h *SongHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	respondError(w, r, err, h.logger)
+}
+
+// writeJSON отправляет успешный ответ в JSON
+func (h *SongHandler) writeJSON(w http.ResponseWriter, status int, v any) {
+	respondJSON(w, status, v, h.logger)
+}
+
+// queryInt разбирает целочисленный query параметр, отсутствующий параметр дает 0
+func queryInt(v *validation.Errors, r *http.Request, name string) int {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		v.Add(name, "must be an integer")
+		return 0
+	}
+	return value
+}
+
+// queryDate разбирает query параметр с датой в формате 2006-01-02
+func queryDate(v *validation.Errors, r *http.Request, name string) *time.Time {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil
+	}
+	value, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		v.Add(name, "must be a date in format YYYY-MM-DD")
+		return nil
+	}
+	return &value
 }
 
 // @Summary Get songs with filtering and pagination
@@ -75,47 +81,92 @@ func (h *SongHandler) handleError(w http.ResponseWriter, err error) {
 // @Param link query string false "Link"
 // @Param page query int false "Page number"
 // @Param page_size query int false "Page size"
+// @Param fields query string false "Comma separated song fields to return, e.g. id,group_name,song_name"
 // @Success 200 {object} models.SongsResponse
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
 // @Router /songs [get]
 func (h *SongHandler) GetSongs(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("Handling GetSongs request")
-
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetSongs request")
 
+	// Собираем ошибки разбора всех параметров, чтобы вернуть их разом
+	v := validation.New()
 	filter := &models.SongFilter{
 		GroupName: r.URL.Query().Get("group_name"),
 		SongName:  r.URL.Query().Get("song_name"),
+		FromDate:  queryDate(v, r, "from_date"),
+		ToDate:    queryDate(v, r, "to_date"),
 		Text:      r.URL.Query().Get("text"),
 		Link:      r.URL.Query().Get("link"),
-		Page:      page,
-		PageSize:  pageSize,
+		Page:      queryInt(v, r, "page"),
+		PageSize:  queryInt(v, r, "page_size"),
 	}
+	fields := queryFields(v, r)
+	if err := v.Err(); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+	// Текст самая тяжелая колонка, без него в fields ее не читаем из базы
+	filter.WithoutText = !hasField(fields, "text")
 
-	// Парсим даты, если они предоставлены
-	if fromDateStr := r.URL.Query().Get("from_date"); fromDateStr != "" {
-		if fromDate, err := time.Parse("2006-01-02", fromDateStr); err == nil {
-			filter.FromDate = &fromDate
-		}
+	response, err := h.service.GetSongs(r.Context(), filter)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
 	}
-	if toDateStr := r.URL.Query().Get("to_date"); toDateStr != "" {
-		if toDate, err := time.Parse("2006-01-02", toDateStr); err == nil {
-			filter.ToDate = &toDate
-		}
+
+	body, err := sparseSongs(response, fields)
+	if err != nil {
+		h.handleError(w, r, errors.NewInternal("failed to select fields", err))
+		return
 	}
+	h.writeJSON(w, http.StatusOK, body)
+}
 
-	response, err := h.service.GetSongs(r.Context(), filter)
+// @Summary Get song
+// @Description Get a song by ID with optional related data and sparse fieldset
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param id path int true "Song ID"
+// @Param include query string false "Comma separated related data: lyrics, group, revisions"
+// @Param fields query string false "Comma separated song fields to return, included data is always returned"
+// @Success 200 {object} models.SongDetails
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /songs/{id} [get]
+func (h *SongHandler) GetSong(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetSong request")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.handleError(w, r, errors.NewBadRequest("Invalid song ID", err))
+		return
+	}
+
+	v := validation.New()
+	fields := queryFields(v, r)
+	if err := v.Err(); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	song, err := h.service.GetSong(r.Context(), id, queryList(r, "include"))
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(response)
+	body, err := sparseSong(song, fields)
 	if err != nil {
-		h.handleError(w, errors.NewValidation("json encode error", err))
+		h.handleError(w, r, errors.NewInternal("failed to select fields", err))
 		return
 	}
+	h.writeJSON(w, http.StatusOK, body)
 }
 
 // @Summary Get song lyrics
@@ -127,32 +178,36 @@ func (h *SongHandler) GetSongs(w http.ResponseWriter, r *http.Request) {
 // @Param page query int false "Page number"
 // @Param page_size query int false "Page size"
 // @Success 200 {object} models.LyricsResponse
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
 // @Router /songs/{id}/lyrics [get]
 func (h *SongHandler) GetLyrics(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("Handling GetLyrics request")
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetLyrics request")
 
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.handleError(w, errors.NewBadRequest("Invalid song ID", err))
+		h.handleError(w, r, errors.NewBadRequest("Invalid song ID", err))
 		return
 	}
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
-
-	response, err := h.service.GetLyrics(r.Context(), id, page, pageSize)
-	if err != nil {
-		h.handleError(w, err)
+	v := validation.New()
+	page := queryInt(v, r, "page")
+	pageSize := queryInt(v, r, "page_size")
+	if err := v.Err(); err != nil {
+		h.handleError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(response)
+	response, err := h.service.GetLyrics(r.Context(), id, page, pageSize)
 	if err != nil {
-		h.handleError(w, errors.NewValidation("json encode error", err))
+		h.handleError(w, r, err)
 		return
 	}
+
+	h.writeJSON(w, http.StatusOK, response)
 }
 
 // @Summary Create new song
@@ -162,29 +217,86 @@ func (h *SongHandler) GetLyrics(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param song body models.SongRequest true "Song information"
 // @Success 201 {object} models.Song
+// @Failure 400 {object} handlers.Problem
+// @Failure 409 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
 // @Router /songs [post]
 func (h *SongHandler) CreateSong(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("Handling CreateSong request")
+	logging.FromContext(r.Context(), h.logger).Debug("Handling CreateSong request")
 
 	var req models.SongRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.handleError(w, errors.NewBadRequest("Invalid request body", err))
+		h.handleError(w, r, errors.NewBadRequest("Invalid request body", err))
 		return
 	}
 
 	song, err := h.service.CreateSong(r.Context(), &req)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(song)
+	h.writeJSON(w, http.StatusCreated, song)
+}
+
+// @Summary Get song by natural key
+// @Description Get a song by group and song name, case and extra whitespace are ignored
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param group query string true "Group name"
+// @Param song query string true "Song name"
+// @Success 200 {object} models.Song
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /songs/by-key [get]
+func (h *SongHandler) GetSongByKey(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetSongByKey request")
+
+	song, err := h.service.GetSongByKey(r.Context(), r.URL.Query().Get("group"), r.URL.Query().Get("song"))
 	if err != nil {
-		h.handleError(w, errors.NewValidation("json encode error", err))
+		h.handleError(w, r, err)
 		return
 	}
+
+	h.writeJSON(w, http.StatusOK, song)
+}
+
+// @Summary Create or replace song by natural key
+// @Description Atomically create a song or replace the song with the same group and song name
+// @Description (case and extra whitespace are ignored). Release date is kept on replace.
+// @Tags songs
+// @Accept json
+// @Produce json
+// @Param song body models.SongRequest true "Song information"
+// @Success 200 {object} models.Song "Song replaced"
+// @Success 201 {object} models.Song "Song created"
+// @Failure 400 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /songs/by-key [put]
+func (h *SongHandler) UpsertSongByKey(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling UpsertSongByKey request")
+
+	var req models.SongRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.handleError(w, r, errors.NewBadRequest("Invalid request body", err))
+		return
+	}
+
+	song, created, err := h.service.UpsertSongByKey(r.Context(), &req)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	h.writeJSON(w, status, song)
 }
 
 // @Summary Update song
@@ -195,35 +307,35 @@ func (h *SongHandler) CreateSong(w http.ResponseWriter, r *http.Request) {
 // @Param id path int true "Song ID"
 // @Param song body models.SongRequest true "Song information"
 // @Success 200 {object} models.Song
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 409 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
 // @Router /songs/{id} [put]
 func (h *SongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("Handling UpdateSong request")
+	logging.FromContext(r.Context(), h.logger).Debug("Handling UpdateSong request")
 
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.handleError(w, errors.NewBadRequest("Invalid song ID", err))
+		h.handleError(w, r, errors.NewBadRequest("Invalid song ID", err))
 		return
 	}
 
 	var req models.SongRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.handleError(w, errors.NewBadRequest("Invalid request body", err))
+		h.handleError(w, r, errors.NewBadRequest("Invalid request body", err))
 		return
 	}
 
 	song, err := h.service.UpdateSong(r.Context(), id, &req)
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(song)
-	if err != nil {
-		h.handleError(w, errors.NewValidation("json encode error", err))
-		return
-	}
+	h.writeJSON(w, http.StatusOK, song)
 }
 
 // @Summary Delete song
@@ -233,19 +345,22 @@ func (h *SongHandler) UpdateSong(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path int true "Song ID"
 // @Success 204 "No Content"
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
 // @Router /songs/{id} [delete]
 func (h *SongHandler) DeleteSong(w http.ResponseWriter, r *http.Request) {
-	h.logger.Debug("Handling DeleteSong request")
+	logging.FromContext(r.Context(), h.logger).Debug("Handling DeleteSong request")
 
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.handleError(w, errors.NewBadRequest("Invalid song ID", err))
+		h.handleError(w, r, errors.NewBadRequest("Invalid song ID", err))
 		return
 	}
 
 	if err := h.service.DeleteSong(r.Context(), id); err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+)
+
+type SyncHandler struct {
+	service *service.SyncService
+	logger  *zap.Logger
+}
+
+func NewSyncHandler(service *service.SyncService, logger *zap.Logger) *SyncHandler {
+	return &SyncHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// @Summary Sync songs
+// @Description Get songs created or changed and IDs of songs deleted after the since token, in the order of changes. Without since the sync starts from scratch. Pass next_token of the response as since of the next request, has_more means the next request returns more changes right away. 410 with code RESYNC_REQUIRED means the token is too old and deletions may be missed: drop the local copy and sync again without since.
+// @Tags sync
+// @Produce json
+// @Param since query string false "next_token of the previous response"
+// @Param limit query int false "Maximum number of changes in the response, 100 by default, at most 1000"
+// @Success 200 {object} models.SyncResponse
+// @Failure 410 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /sync [get]
+func (h *SyncHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetChanges request")
+
+	v := validation.New()
+	limit := queryInt(v, r, "limit")
+	if err := v.Err(); err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	changes, err := h.service.GetChanges(r.Context(), r.URL.Query().Get("since"), limit)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, changes, h.logger)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+	logger  *zap.Logger
+}
+
+func NewWebhookHandler(service *service.WebhookService, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// webhookID разбирает ID вебхука из пути
+func webhookID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, errors.NewBadRequest("Invalid webhook ID", err)
+	}
+	return id, nil
+}
+
+// @Summary Register webhook
+// @Description Register a URL to receive song change events signed with HMAC-SHA256. Empty event_types subscribes to all events.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookRequest true "Webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling CreateWebhook request")
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, errors.NewBadRequest("Invalid request body", err), h.logger)
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(r.Context(), &req)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, webhook, h.logger)
+}
+
+// @Summary List webhooks
+// @Description Get all registered webhooks, secrets are not returned
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 500 {object} handlers.Problem
+// @Router /webhooks [get]
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetWebhooks request")
+
+	webhooks, err := h.service.GetWebhooks(r.Context())
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, webhooks, h.logger)
+}
+
+// @Summary Delete webhook
+// @Description Delete a webhook together with its delivery log
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling DeleteWebhook request")
+
+	id, err := webhookID(r)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	if err := h.service.DeleteWebhook(r.Context(), id); err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Get webhook deliveries
+// @Description Get the delivery log of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Delivery status: pending, delivered, failed"
+// @Param page query int false "Page number"
+// @Param page_size query int false "Page size"
+// @Success 200 {object} models.DeliveriesResponse
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling GetDeliveries request")
+
+	id, err := webhookID(r)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	v := validation.New()
+	filter := &models.DeliveryFilter{
+		WebhookID: id,
+		Status:    r.URL.Query().Get("status"),
+		Page:      queryInt(v, r, "page"),
+		PageSize:  queryInt(v, r, "page_size"),
+	}
+	if err := v.Err(); err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	response, err := h.service.GetDeliveries(r.Context(), filter)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, response, h.logger)
+}
+
+// @Summary Replay webhook events
+// @Description Enqueue delivery of all already dispatched events starting from from_event_id again
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param replay body models.ReplayRequest true "First event to replay"
+// @Success 202 {object} models.ReplayResponse
+// @Failure 400 {object} handlers.Problem
+// @Failure 404 {object} handlers.Problem
+// @Failure 422 {object} handlers.Problem
+// @Failure 500 {object} handlers.Problem
+// @Router /webhooks/{id}/replay [post]
+func (h *WebhookHandler) ReplayDeliveries(w http.ResponseWriter, r *http.Request) {
+	logging.FromContext(r.Context(), h.logger).Debug("Handling ReplayDeliveries request")
+
+	id, err := webhookID(r)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	var req models.ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, r, errors.NewBadRequest("Invalid request body", err), h.logger)
+		return
+	}
+
+	response, err := h.service.ReplayDeliveries(r.Context(), id, req.FromEventID)
+	if err != nil {
+		respondError(w, r, err, h.logger)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, response, h.logger)
+}
@@ -0,0 +1,25 @@
+package logging
+
+import (
+	"fmt"
+
+	"github.com/testTask/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New создает логгер по настройкам из конфигурации
+func New(cfg config.LogConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level: %w", err)
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	if cfg.Format == "console" {
+		zapConfig = zap.NewDevelopmentConfig()
+	}
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+
+	return zapConfig.Build()
+}
@@ -0,0 +1,55 @@
+// Package metrics настраивает метрики OpenTelemetry с выдачей в формате Prometheus
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Имя инструментации, под которым создаются метрики приложения
+const instrumentationName = "github.com/testTask"
+
+// Init настраивает глобальный MeterProvider и возвращает обработчик /metrics.
+// Кроме метрик приложения отдаются метрики Go рантайма и процесса.
+func Init(serviceName string) (http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	exporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metric resource: %w", err)
+	}
+
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(exporter),
+		sdkmetric.WithResource(res),
+	))
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
+
+// Meter возвращает метр приложения. До Init метрики никуда не выдаются.
+func Meter() metric.Meter {
+	return otel.Meter(instrumentationName)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/testTask/internal/logging"
 	"go.uber.org/zap"
 )
 
@@ -12,6 +13,7 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			requestLogger := logging.FromContext(r.Context(), logger)
 
 			// Создаем ResponseWriter, который может отслеживать статус ответа
 			wrappedWriter := &responseWriter{
@@ -20,7 +22,7 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 			}
 
 			// Логируем входящий запрос
-			logger.Info("Incoming request",
+			requestLogger.Info("Incoming request",
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
 				zap.String("remote_addr", r.RemoteAddr),
@@ -32,7 +34,7 @@ func LoggingMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
 
 			// Логируем результат запроса
 			duration := time.Since(start)
-			logger.Info("Request completed",
+			requestLogger.Info("Request completed",
 				zap.String("method", r.Method),
 				zap.String("path", r.URL.Path),
 				zap.Int("status", wrappedWriter.status),
@@ -53,3 +55,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap возвращает исходный ResponseWriter, чтобы http.ResponseController
+// мог сбросить буфер и продлить дедлайн записи потокового ответа
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/testTask/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength ограничивает длину принятого от клиента идентификатора
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware принимает или генерирует X-Request-ID, возвращает его в ответе
+// и кладет в контекст логгер, который добавляет идентификатор ко всем записям
+func RequestIDMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if !IsValidRequestID(requestID) {
+				requestID = NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			fields := []zap.Field{zap.String("request_id", requestID)}
+			if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.IsValid() {
+				fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+			}
+
+			ctx := logging.WithRequestID(r.Context(), requestID)
+			ctx = logging.WithLogger(ctx, logger.With(fields...))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IsValidRequestID проверяет, что идентификатор от клиента безопасно писать в логи и заголовки
+func IsValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if c := requestID[i]; c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRequestID генерирует случайный идентификатор запроса
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,9 @@
+package models
+
+// EventFilter фильтр потока событий об изменении песен
+type EventFilter struct {
+	// GroupName только события о песнях группы, без учета регистра и лишних пробелов
+	GroupName string
+	// Types виды событий, пустой список - все
+	Types []string
+}
@@ -16,10 +16,10 @@ type Song struct {
 
 // SongRequest структура запроса для создания/обновления песни
 type SongRequest struct {
-	GroupName string `json:"group" binding:"required"`
-	SongName  string `json:"song" binding:"required"`
+	GroupName string `json:"group" binding:"required" maxLength:"255"`
+	SongName  string `json:"song" binding:"required" maxLength:"255"`
 	Text      string `json:"text"`
-	Link      string `json:"link"`
+	Link      string `json:"link" maxLength:"255" example:"https://example.com/song"`
 }
 
 // SongFilter структура фильтрации песен
@@ -32,6 +32,38 @@ type SongFilter struct {
 	Link      string     `json:"link"`
 	Page      int        `json:"page"`
 	PageSize  int        `json:"page_size"`
+	// WithoutText не читать из базы текст песен, когда он не нужен в ответе
+	WithoutText bool `json:"-"`
+}
+
+// SongFields поля песни, которые можно выбрать параметром fields
+var SongFields = []string{"id", "group_name", "song_name", "release_date", "text", "link", "created_at", "updated_at"}
+
+// Связанные данные, которые можно встроить в песню параметром include
+const (
+	IncludeLyrics    = "lyrics"
+	IncludeGroup     = "group"
+	IncludeRevisions = "revisions"
+)
+
+// SongIncludes все допустимые значения include
+var SongIncludes = []string{IncludeLyrics, IncludeGroup, IncludeRevisions}
+
+// SongDetails песня со встроенными по include связанными данными
+type SongDetails struct {
+	Song
+	// Lyrics куплеты песни, include=lyrics
+	Lyrics []string `json:"lyrics,omitempty"`
+	// Group сведения о группе, include=group
+	Group *GroupDetails `json:"group,omitempty"`
+	// RevisionCount количество сохраненных версий песни, include=revisions
+	RevisionCount *int `json:"revision_count,omitempty"`
+}
+
+// GroupDetails сведения о группе
+type GroupDetails struct {
+	Name      string `json:"name"`
+	SongCount int    `json:"song_count"`
 }
 
 // SongsResponse структура ответа со списком песен и информацией о пагинации
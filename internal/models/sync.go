@@ -0,0 +1,48 @@
+package models
+
+// SyncPosition место в последовательности изменений. Изменения упорядочены по
+// транзакции записи, внутри нее по номеру. Хранилища, где транзакции записи
+// идут по одной, оставляют Tx нулевым.
+type SyncPosition struct {
+	// Tx транзакция, записавшая изменение
+	Tx int64
+	// Seq номер изменения, растет с каждой записью песни
+	Seq int64
+}
+
+// Before сообщает, идет ли p раньше other
+func (p SyncPosition) Before(other SyncPosition) bool {
+	if p.Tx != other.Tx {
+		return p.Tx < other.Tx
+	}
+	return p.Seq < other.Seq
+}
+
+// SongChange изменение песни в последовательности изменений
+type SongChange struct {
+	Position SyncPosition
+	SongID   int
+	// Song песня после изменения, nil для удаления
+	Song *Song
+}
+
+// SyncBounds границы последовательности изменений
+type SyncBounds struct {
+	// Horizon самое старое место, с которого еще можно продолжить синхронизацию:
+	// удаления до него уже забыты
+	Horizon SyncPosition
+	// Latest последний выданный номер изменения
+	Latest int64
+}
+
+// SyncResponse изменения библиотеки после токена синхронизации
+type SyncResponse struct {
+	// Songs текущие версии созданных и измененных песен по порядку изменений
+	Songs []Song `json:"songs"`
+	// Deleted ID удаленных песен
+	Deleted []int `json:"deleted"`
+	// NextToken токен для следующего запроса
+	NextToken string `json:"next_token" example:"djI6MDo0Mg"`
+	// HasMore изменений больше, чем вошло в ответ, следующий запрос вернет продолжение
+	HasMore bool `json:"has_more"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent событие об изменении песни, записанное в одной транзакции с изменением
+type OutboxEvent struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	SongID int    `json:"song_id"`
+	// Payload песня после изменения, для удаления - перед ним
+	Payload   json.RawMessage `json:"data" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Webhook адрес, на который доставляются события
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret ключ подписи HMAC-SHA256, наружу не отдается
+	Secret string `json:"-"`
+	// EventTypes виды событий для доставки, пустой список - все
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookRequest запрос на регистрацию вебхука
+type WebhookRequest struct {
+	URL        string   `json:"url" example:"https://example.com/hooks/songs"`
+	Secret     string   `json:"secret" minLength:"16" maxLength:"255"`
+	EventTypes []string `json:"event_types" example:"song.created,song.deleted"`
+}
+
+// Статусы доставки события
+const (
+	// DeliveryPending доставка ждет первой или повторной попытки
+	DeliveryPending = "pending"
+	// DeliveryDelivered получатель ответил 2xx
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed попытки исчерпаны
+	DeliveryFailed = "failed"
+)
+
+// DeliveryStatuses все статусы доставки
+var DeliveryStatuses = []string{DeliveryPending, DeliveryDelivered, DeliveryFailed}
+
+// WebhookDelivery доставка одного события одному вебхуку и итог последней попытки
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int    `json:"webhook_id"`
+	EventID   int64  `json:"event_id"`
+	EventType string `json:"event_type"`
+	Status    string `json:"status"`
+	Attempts  int    `json:"attempts"`
+	// NextAttemptAt время следующей попытки доставки в статусе pending
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DeliveryFilter фильтр журнала доставок вебхука
+type DeliveryFilter struct {
+	WebhookID int
+	// Status пустой - все статусы
+	Status   string
+	Page     int
+	PageSize int
+}
+
+// DeliveriesResponse страница журнала доставок
+type DeliveriesResponse struct {
+	Deliveries  []WebhookDelivery `json:"deliveries"`
+	CurrentPage int               `json:"current_page"`
+	TotalPages  int               `json:"total_pages"`
+	TotalItems  int               `json:"total_items"`
+	PageSize    int               `json:"page_size"`
+}
+
+// PendingDelivery доставка, взятая диспетчером в работу, со всем нужным для отправки
+type PendingDelivery struct {
+	ID int64
+	// Attempt номер текущей попытки, начиная с 1
+	Attempt int
+	Webhook Webhook
+	Event   OutboxEvent
+}
+
+// DeliveryResult итог попытки доставки
+type DeliveryResult struct {
+	Status     string
+	StatusCode int
+	Error      string
+	// NextAttemptAt время повтора, если Status равен DeliveryPending
+	NextAttemptAt time.Time
+}
+
+// ReplayRequest запрос на повторную доставку событий вебхуку
+type ReplayRequest struct {
+	// FromEventID доставить заново все события, начиная с этого
+	FromEventID int64 `json:"from_event_id" example:"1"`
+}
+
+// ReplayResponse результат повторной доставки
+type ReplayResponse struct {
+	// Enqueued сколько доставок поставлено в очередь
+	Enqueued int `json:"enqueued"`
+}
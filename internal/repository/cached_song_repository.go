@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/testTask/internal/metrics"
+	"github.com/testTask/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Виды записей кэша в атрибуте метрик
+const (
+	cacheKindSong = "song"
+	cacheKindList = "list"
+)
+
+// CacheOptions параметры кэша песен
+type CacheOptions struct {
+	// Size максимальное количество записей каждого вида: песен и страниц списка
+	Size int
+	// TTL время жизни записи
+	TTL time.Duration
+}
+
+// CachedSongRepository кэширует в памяти процесса GetSongByID и страницы GetSongs
+// поверх другого репозитория. Остальные методы вызываются напрямую.
+// Любая запись сбрасывает песню и все страницы списка, так как песня
+// может появиться в выдаче или пропасть из нее при любом фильтре.
+type CachedSongRepository struct {
+	SongRepository
+	cache *songCache
+	// tx собирает ID измененных песен, пока репозиторий привязан к транзакции.
+	// Кэш сбрасывается только после фиксации, чтения в транзакции идут мимо кэша.
+	tx *txInvalidations
+}
+
+// songCache общие для репозитория и его транзакций данные кэша
+type songCache struct {
+	songs *expirable.LRU[int, models.Song]
+	lists *expirable.LRU[string, models.SongsResponse]
+	// mu защищает generation. Сброс кэша и запись прочитанного из хранилища идут
+	// под ней, чтобы сброс не проскочил между проверкой generation и записью.
+	mu sync.Mutex
+	// generation растет при каждой записи. Чтение, начатое до записи,
+	// не кладет свой результат в кэш, иначе он мог бы вернуть старые данные.
+	generation uint64
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+// txInvalidations песни, измененные в транзакции
+type txInvalidations struct {
+	mu  sync.Mutex
+	ids []int
+}
+
+// NewCachedSongRepository оборачивает repo кэшем и регистрирует метрики попаданий и промахов
+func NewCachedSongRepository(repo SongRepository, opts CacheOptions) (*CachedSongRepository, error) {
+	cache := &songCache{
+		songs: expirable.NewLRU[int, models.Song](opts.Size, nil, opts.TTL),
+		lists: expirable.NewLRU[string, models.SongsResponse](opts.Size, nil, opts.TTL),
+	}
+
+	meter := metrics.Meter()
+	var err error
+	if cache.hits, err = meter.Int64Counter("song_cache.hits",
+		metric.WithDescription("Song cache lookups served from the cache")); err != nil {
+		return nil, fmt.Errorf("failed to create cache metric: %w", err)
+	}
+	if cache.misses, err = meter.Int64Counter("song_cache.misses",
+		metric.WithDescription("Song cache lookups passed to the storage")); err != nil {
+		return nil, fmt.Errorf("failed to create cache metric: %w", err)
+	}
+	_, err = meter.Int64ObservableGauge("song_cache.entries",
+		metric.WithDescription("Entries currently held by the song cache"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(int64(cache.songs.Len()), metric.WithAttributes(cacheKind(cacheKindSong)))
+			o.Observe(int64(cache.lists.Len()), metric.WithAttributes(cacheKind(cacheKindList)))
+			return nil
+		}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache metric: %w", err)
+	}
+
+	return &CachedSongRepository{SongRepository: repo, cache: cache}, nil
+}
+
+// GetSongs возвращает страницу списка из кэша или из хранилища
+func (r *CachedSongRepository) GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error) {
+	if r.tx != nil {
+		return r.SongRepository.GetSongs(ctx, filter)
+	}
+
+	// Значения по умолчанию те же, что в хранилище, чтобы одинаковые запросы давали один ключ
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	key := filterKey(filter)
+	if cached, ok := r.cache.lists.Get(key); ok {
+		r.cache.record(ctx, r.cache.hits, cacheKindList)
+		cached.Songs = append([]models.Song(nil), cached.Songs...)
+		return &cached, nil
+	}
+	r.cache.record(ctx, r.cache.misses, cacheKindList)
+
+	generation := r.cache.currentGeneration()
+	response, err := r.SongRepository.GetSongs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.addIfCurrent(generation, func() {
+		stored := *response
+		stored.Songs = append([]models.Song(nil), response.Songs...)
+		r.cache.lists.Add(key, stored)
+	})
+	return response, nil
+}
+
+// GetSongByID возвращает песню из кэша или из хранилища. Ненайденные песни не кэшируются.
+func (r *CachedSongRepository) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	if r.tx != nil {
+		return r.SongRepository.GetSongByID(ctx, id)
+	}
+
+	if cached, ok := r.cache.songs.Get(id); ok {
+		r.cache.record(ctx, r.cache.hits, cacheKindSong)
+		return &cached, nil
+	}
+	r.cache.record(ctx, r.cache.misses, cacheKindSong)
+
+	generation := r.cache.currentGeneration()
+	song, err := r.SongRepository.GetSongByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.addIfCurrent(generation, func() { r.cache.songs.Add(id, *song) })
+	return song, nil
+}
+
+// CreateSong создает песню и сбрасывает страницы списка
+func (r *CachedSongRepository) CreateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	created, err := r.SongRepository.CreateSong(ctx, song)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(created.ID)
+	return created, nil
+}
+
+// UpsertSongByKey создает или заменяет песню и сбрасывает ее в кэше
+func (r *CachedSongRepository) UpsertSongByKey(ctx context.Context, song *models.Song) (*models.Song, bool, error) {
+	saved, created, err := r.SongRepository.UpsertSongByKey(ctx, song)
+	if err != nil {
+		return nil, false, err
+	}
+	r.invalidate(saved.ID)
+	return saved, created, nil
+}
+
+// CreateSongs массово добавляет песни и сбрасывает страницы списка
+func (r *CachedSongRepository) CreateSongs(ctx context.Context, songs []models.Song) (int, error) {
+	created, err := r.SongRepository.CreateSongs(ctx, songs)
+	if err != nil {
+		return 0, err
+	}
+	if created > 0 {
+		r.invalidate()
+	}
+	return created, nil
+}
+
+// UpdateSong обновляет песню и сбрасывает ее в кэше
+func (r *CachedSongRepository) UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	updated, err := r.SongRepository.UpdateSong(ctx, song)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(updated.ID)
+	return updated, nil
+}
+
+// DeleteSong удаляет песню и сбрасывает ее в кэше
+func (r *CachedSongRepository) DeleteSong(ctx context.Context, id int) error {
+	if err := r.SongRepository.DeleteSong(ctx, id); err != nil {
+		return err
+	}
+	r.invalidate(id)
+	return nil
+}
+
+// WithinTx выполняет fn в транзакции хранилища. Записи внутри транзакции
+// сбрасывают кэш только после ее успешной фиксации.
+func (r *CachedSongRepository) WithinTx(ctx context.Context, fn func(repo SongRepository) error, opts ...TxOption) error {
+	if r.tx != nil {
+		// Вложенный вызов выполняется в уже открытой транзакции
+		return fn(r)
+	}
+
+	tx := &txInvalidations{}
+	err := r.SongRepository.WithinTx(ctx, func(repo SongRepository) error {
+		return fn(&CachedSongRepository{SongRepository: repo, cache: r.cache, tx: tx})
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(tx.ids) > 0 {
+		r.cache.invalidate(tx.ids...)
+	}
+	return nil
+}
+
+// Invalidate сбрасывает песни ids и все страницы списка. Нужен, когда песни
+// меняются в обход этого репозитория, например другим экземпляром сервиса.
+func (r *CachedSongRepository) Invalidate(ids ...int) {
+	r.cache.invalidate(ids...)
+}
+
+// Purge сбрасывает весь кэш, когда неизвестно, какие песни изменились
+func (r *CachedSongRepository) Purge() {
+	r.cache.purge()
+}
+
+// invalidate сбрасывает песни ids и все страницы списка, в транзакции откладывает сброс до фиксации
+func (r *CachedSongRepository) invalidate(ids ...int) {
+	if r.tx == nil {
+		r.cache.invalidate(ids...)
+		return
+	}
+	r.tx.mu.Lock()
+	defer r.tx.mu.Unlock()
+	// Нулевой ID означает, что нужно сбросить только страницы списка
+	r.tx.ids = append(r.tx.ids, append(ids, 0)...)
+}
+
+// currentGeneration возвращает generation перед чтением из хранилища
+func (c *songCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// addIfCurrent выполняет add, если после чтения generation кэш не сбрасывался
+func (c *songCache) addIfCurrent(generation uint64, add func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		add()
+	}
+}
+
+func (c *songCache) invalidate(ids ...int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, id := range ids {
+		if id != 0 {
+			c.songs.Remove(id)
+		}
+	}
+	c.lists.Purge()
+}
+
+func (c *songCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.songs.Purge()
+	c.lists.Purge()
+}
+
+func (c *songCache) record(ctx context.Context, counter metric.Int64Counter, kind string) {
+	counter.Add(ctx, 1, metric.WithAttributes(cacheKind(kind)))
+}
+
+func cacheKind(kind string) attribute.KeyValue {
+	return attribute.String("cache", kind)
+}
+
+// filterKey ключ страницы списка. Строковые фильтры сравниваются без учета регистра,
+// поэтому приводятся к нижнему регистру, даты - к UTC.
+func filterKey(filter *models.SongFilter) string {
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return strings.Join([]string{
+		strings.ToLower(filter.GroupName),
+		strings.ToLower(filter.SongName),
+		date(filter.FromDate),
+		date(filter.ToDate),
+		strings.ToLower(filter.Text),
+		strings.ToLower(filter.Link),
+		fmt.Sprint(filter.Page),
+		fmt.Sprint(filter.PageSize),
+		fmt.Sprint(filter.WithoutText),
+	}, "\x00")
+}
@@ -0,0 +1,169 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/repository/repotest"
+)
+
+var testCacheOptions = repository.CacheOptions{Size: 100, TTL: time.Minute}
+
+func TestCachedSongRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.SongRepository {
+		return newCachedRepository(t, repository.NewMemorySongRepository())
+	})
+}
+
+func TestCachedSongRepositoryServesFromCache(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{SongRepository: repository.NewMemorySongRepository()}
+	repo := newCachedRepository(t, inner)
+
+	song, err := repo.CreateSong(ctx, &models.Song{GroupName: "Muse", SongName: "Hysteria"})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetSongByID(ctx, song.ID); err != nil {
+			t.Fatalf("GetSongByID: %v", err)
+		}
+		if _, err := repo.GetSongs(ctx, &models.SongFilter{GroupName: "MUSE"}); err != nil {
+			t.Fatalf("GetSongs: %v", err)
+		}
+	}
+	// Фильтры без учета регистра и явные значения по умолчанию дают тот же ключ
+	if _, err := repo.GetSongs(ctx, &models.SongFilter{GroupName: "muse", Page: 1, PageSize: 10}); err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+
+	if inner.gets != 1 || inner.lists != 1 {
+		t.Errorf("storage reads: %d songs, %d lists, want 1 and 1", inner.gets, inner.lists)
+	}
+}
+
+func TestCachedSongRepositoryInvalidation(t *testing.T) {
+	ctx := context.Background()
+	repo := newCachedRepository(t, repository.NewMemorySongRepository())
+
+	song, err := repo.CreateSong(ctx, &models.Song{GroupName: "Muse", SongName: "Hysteria"})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	if _, err := repo.GetSongByID(ctx, song.ID); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if _, err := repo.GetSongs(ctx, &models.SongFilter{}); err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+
+	// Изменение в транзакции видно сразу после ее фиксации
+	err = repo.WithinTx(ctx, func(tx repository.SongRepository) error {
+		update := *song
+		update.Text = "Updated"
+		_, err := tx.UpdateSong(ctx, &update)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx: %v", err)
+	}
+	got, err := repo.GetSongByID(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.Text != "Updated" {
+		t.Errorf("cached song not invalidated, text %q", got.Text)
+	}
+
+	if _, err := repo.CreateSong(ctx, &models.Song{GroupName: "Muse", SongName: "Uprising"}); err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	list, err := repo.GetSongs(ctx, &models.SongFilter{})
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	if list.TotalItems != 2 {
+		t.Errorf("cached list not invalidated, total items %d", list.TotalItems)
+	}
+
+	// Возвращенные значения - копии, их изменение не портит кэш
+	got.Text = "Changed by caller"
+	again, err := repo.GetSongByID(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if again.Text != "Updated" {
+		t.Errorf("cache returned shared value, text %q", again.Text)
+	}
+}
+
+func TestCachedSongRepositorySkipsReadsOverlappingInvalidation(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingRepository{SongRepository: repository.NewMemorySongRepository()}
+	repo, err := repository.NewCachedSongRepository(inner, testCacheOptions)
+	if err != nil {
+		t.Fatalf("NewCachedSongRepository: %v", err)
+	}
+	song, err := repo.CreateSong(ctx, &models.Song{GroupName: "Muse", SongName: "Hysteria"})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+
+	// Сброс между чтением из хранилища и записью в кэш оставляет кэш пустым
+	inner.afterRead = func() { repo.Invalidate(song.ID) }
+	if _, err := repo.GetSongByID(ctx, song.ID); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if _, err := repo.GetSongs(ctx, &models.SongFilter{}); err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	inner.afterRead = nil
+	if _, err := repo.GetSongByID(ctx, song.ID); err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if _, err := repo.GetSongs(ctx, &models.SongFilter{}); err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+
+	if inner.gets != 2 || inner.lists != 2 {
+		t.Errorf("storage reads: %d songs, %d lists, want 2 and 2", inner.gets, inner.lists)
+	}
+}
+
+func newCachedRepository(t *testing.T, inner repository.SongRepository) repository.SongRepository {
+	t.Helper()
+	repo, err := repository.NewCachedSongRepository(inner, testCacheOptions)
+	if err != nil {
+		t.Fatalf("NewCachedSongRepository: %v", err)
+	}
+	return repo
+}
+
+// countingRepository считает чтения, дошедшие до хранилища
+type countingRepository struct {
+	repository.SongRepository
+	gets, lists int
+	// afterRead вызывается после каждого чтения, если задан
+	afterRead func()
+}
+
+func (r *countingRepository) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	r.gets++
+	defer r.read()
+	return r.SongRepository.GetSongByID(ctx, id)
+}
+
+func (r *countingRepository) GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error) {
+	r.lists++
+	defer r.read()
+	return r.SongRepository.GetSongs(ctx, filter)
+}
+
+func (r *countingRepository) read() {
+	if r.afterRead != nil {
+		r.afterRead()
+	}
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/testTask/internal/models"
+)
+
+// firstSongChanges упорядочивает изменения песен и удалений по месту и оставляет первые limit.
+// Песни и удаления читаются по limit каждые, поэтому среди первых limit слитых нет пропусков.
+func firstSongChanges(changes []models.SongChange, limit int) []models.SongChange {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Position.Before(changes[j].Position) })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	if changes == nil {
+		return []models.SongChange{}
+	}
+	return changes
+}
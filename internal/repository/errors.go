@@ -0,0 +1,115 @@
+package repository
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/testTask/internal/errors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// songExistsMessage сообщение о занятой паре группа/песня
+const songExistsMessage = "song with this group name and song name already exists"
+
+// constraintMessages понятные сообщения о нарушении ограничений схемы
+var constraintMessages = map[string]string{
+	"songs_natural_key": songExistsMessage,
+}
+
+// mapError переводит ошибку PostgreSQL в ошибку приложения по коду SQLSTATE.
+// Неизвестные ошибки становятся внутренними с сообщением message.
+func mapError(err error, message string) error {
+	var pgErr *pgconn.PgError
+	if !stderrors.As(err, &pgErr) {
+		return errors.NewInternal(message, err)
+	}
+
+	switch pgErr.Code {
+	case pgerrcode.UniqueViolation:
+		msg, ok := constraintMessages[pgErr.ConstraintName]
+		if !ok {
+			msg = "record already exists"
+		}
+		return errors.NewAlreadyExists(msg, err)
+	case pgerrcode.ForeignKeyViolation:
+		return errors.NewNotFound("referenced record not found", err)
+	case pgerrcode.NotNullViolation,
+		pgerrcode.CheckViolation,
+		pgerrcode.StringDataRightTruncationDataException,
+		pgerrcode.InvalidTextRepresentation,
+		pgerrcode.InvalidDatetimeFormat,
+		pgerrcode.DatetimeFieldOverflow,
+		pgerrcode.NumericValueOutOfRange:
+		if pgErr.ColumnName != "" {
+			return errors.NewValidationFields("invalid song", []errors.FieldError{
+				{Field: pgErr.ColumnName, Message: pgErr.Message},
+			})
+		}
+		return errors.NewValidation(pgErr.Message, err)
+	case pgerrcode.QueryCanceled:
+		// Сюда попадает и превышение db.statement_timeout
+		return errors.NewInternal(message+": query canceled", err)
+	}
+	return errors.NewInternal(message, err)
+}
+
+// sqliteUniqueMessages сообщения о нарушении уникальности по списку колонок,
+// SQLite не сообщает имя ограничения
+var sqliteUniqueMessages = map[string]string{
+	"songs.group_key, songs.song_key": songExistsMessage,
+}
+
+// mapSQLiteError переводит ошибку SQLite в ошибку приложения по расширенному коду
+func mapSQLiteError(err error, message string) error {
+	var sqliteErr *sqlite.Error
+	if !stderrors.As(err, &sqliteErr) {
+		return errors.NewInternal(message, err)
+	}
+
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		msg, ok := sqliteUniqueMessages[sqliteConstraintColumns(sqliteErr)]
+		if !ok {
+			msg = "record already exists"
+		}
+		return errors.NewAlreadyExists(msg, err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return errors.NewNotFound("referenced record not found", err)
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return errors.NewValidation(sqliteErr.Error(), err)
+	}
+	if isSQLiteBusy(err) {
+		return errors.NewInternal(message+": database is locked", err)
+	}
+	return errors.NewInternal(message, err)
+}
+
+// isSQLiteBusy сообщает, что база занята другой транзакцией и запрос можно повторить
+func isSQLiteBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !stderrors.As(err, &sqliteErr) {
+		return false
+	}
+	// Младший байт расширенного кода - основной код ошибки
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
+
+// sqliteConstraintColumns колонки из сообщения вида
+// "constraint failed: UNIQUE constraint failed: songs.group_key, songs.song_key (2067)"
+func sqliteConstraintColumns(err *sqlite.Error) string {
+	columns := err.Error()
+	if i := strings.LastIndex(columns, ": "); i >= 0 {
+		columns = columns[i+2:]
+	}
+	if i := strings.LastIndex(columns, " ("); i >= 0 {
+		columns = columns[:i]
+	}
+	return columns
+}
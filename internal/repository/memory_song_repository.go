@@ -0,0 +1,557 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/models"
+)
+
+// MemorySongRepository хранит песни в памяти процесса. Повторяет поведение
+// PostgresSongRepository: фильтры, пагинацию, уникальность естественного ключа
+// и ошибки NotFound/AlreadyExists. Подходит для тестов и демонстрации без базы.
+type MemorySongRepository struct {
+	// mu общий для репозитория и его транзакций
+	mu    *sync.RWMutex
+	state *memoryState
+	// inTx репозиторий привязан к транзакции WithinTx и уже держит mu
+	inTx bool
+}
+
+// memoryState данные репозитория, которые транзакция копирует целиком
+type memoryState struct {
+	songs     map[int]models.Song
+	keys      map[NaturalKey]int
+	revisions map[int]int
+	nextID    int
+	// outbox события по возрастанию ID, ID события на единицу больше его индекса
+	outbox []models.OutboxEvent
+	// changes номер последнего изменения каждой песни
+	changes map[int]int64
+	// tombstones удаленные песни
+	tombstones map[int]memoryTombstone
+	// lastSeq последний выданный номер изменения
+	lastSeq int64
+	// horizon горизонт синхронизации, см. models.SyncBounds
+	horizon int64
+}
+
+// memoryTombstone запись об удалении песни
+type memoryTombstone struct {
+	seq       int64
+	deletedAt time.Time
+}
+
+// NewMemorySongRepository создает пустой репозиторий в памяти
+func NewMemorySongRepository() *MemorySongRepository {
+	return &MemorySongRepository{
+		mu: &sync.RWMutex{},
+		state: &memoryState{
+			songs:      make(map[int]models.Song),
+			keys:       make(map[NaturalKey]int),
+			revisions:  make(map[int]int),
+			nextID:     1,
+			changes:    make(map[int]int64),
+			tombstones: make(map[int]memoryTombstone),
+		},
+	}
+}
+
+func (r *MemorySongRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *MemorySongRepository) rlock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
+
+// GetSongs получает список песен
+func (r *MemorySongRepository) GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error) {
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	match, err := newSongMatcher(filter)
+	if err != nil {
+		return nil, errors.NewInternal("failed to query songs", err)
+	}
+
+	defer r.rlock()()
+
+	songs := make([]models.Song, 0)
+	for _, song := range r.state.songs {
+		if match(song) {
+			songs = append(songs, song)
+		}
+	}
+	sort.Slice(songs, func(i, j int) bool {
+		if !songs[i].CreatedAt.Equal(songs[j].CreatedAt) {
+			return songs[i].CreatedAt.After(songs[j].CreatedAt)
+		}
+		return songs[i].ID > songs[j].ID
+	})
+
+	totalItems := len(songs)
+	totalPages := (totalItems + filter.PageSize - 1) / filter.PageSize
+	if filter.Page > totalPages {
+		return nil, errors.NewNotFound(fmt.Sprintf("page %d does not exist, total pages: %d", filter.Page, totalPages), nil)
+	}
+
+	start := (filter.Page - 1) * filter.PageSize
+	end := min(start+filter.PageSize, totalItems)
+	page := songs[start:end]
+	if filter.WithoutText {
+		for i := range page {
+			page[i].Text = ""
+		}
+	}
+
+	return &models.SongsResponse{
+		Songs:       page,
+		CurrentPage: filter.Page,
+		TotalPages:  totalPages,
+		TotalItems:  totalItems,
+		PageSize:    filter.PageSize,
+	}, nil
+}
+
+// GetSongByID получает песню по ID
+func (r *MemorySongRepository) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	defer r.rlock()()
+
+	song, ok := r.state.songs[id]
+	if !ok {
+		return nil, errors.NewNotFound("song not found", nil)
+	}
+	return &song, nil
+}
+
+// CreateSong создает новую песню
+func (r *MemorySongRepository) CreateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	defer r.lock()()
+
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	if id, ok := r.state.keys[key]; ok {
+		return nil, errors.NewAlreadyExistsWithID(songExistsMessage, id, nil)
+	}
+
+	r.state.insert(song, key)
+	return song, nil
+}
+
+// GetSongByKey получает песню по естественному ключу
+func (r *MemorySongRepository) GetSongByKey(ctx context.Context, groupName, songName string) (*models.Song, error) {
+	defer r.rlock()()
+
+	id, ok := r.state.keys[NewNaturalKey(groupName, songName)]
+	if !ok {
+		return nil, errors.NewNotFound("song not found", nil)
+	}
+	song := r.state.songs[id]
+	return &song, nil
+}
+
+// UpsertSongByKey создает песню или заменяет песню с тем же естественным ключом
+func (r *MemorySongRepository) UpsertSongByKey(ctx context.Context, song *models.Song) (*models.Song, bool, error) {
+	defer r.lock()()
+
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	id, ok := r.state.keys[key]
+	if !ok {
+		r.state.insert(song, key)
+		return song, true, nil
+	}
+
+	// Как и в PostgreSQL, дата выпуска и время создания при замене сохраняются
+	existing := r.state.songs[id]
+	existing.GroupName = song.GroupName
+	existing.SongName = song.SongName
+	existing.Text = song.Text
+	existing.Link = song.Link
+	existing.UpdatedAt = now()
+	r.state.songs[id] = existing
+	r.state.touch(id)
+
+	*song = existing
+	return song, false, nil
+}
+
+// CreateSongs массово добавляет песни, уже существующие пропускает, и пишет события о них в outbox
+func (r *MemorySongRepository) CreateSongs(ctx context.Context, songs []models.Song) (int, error) {
+	defer r.lock()()
+
+	created := 0
+	for i := range songs {
+		song := songs[i]
+		key := NewNaturalKey(song.GroupName, song.SongName)
+		if _, ok := r.state.keys[key]; ok {
+			continue
+		}
+		r.state.insert(&song, key)
+		r.state.revisions[song.ID] = 1
+		event, err := NewOutboxEvent(events.SongCreated, &song)
+		if err != nil {
+			return created, errors.NewInternal("failed to encode song event", err)
+		}
+		r.state.appendEvent(event)
+		created++
+	}
+	return created, nil
+}
+
+// UpdateSong обновляет песню
+func (r *MemorySongRepository) UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	defer r.lock()()
+
+	existing, ok := r.state.songs[song.ID]
+	if !ok {
+		return nil, errors.NewNotFound("song not found", nil)
+	}
+
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	if id, ok := r.state.keys[key]; ok && id != song.ID {
+		return nil, errors.NewAlreadyExistsWithID(songExistsMessage, id, nil)
+	}
+
+	delete(r.state.keys, NewNaturalKey(existing.GroupName, existing.SongName))
+	r.state.keys[key] = song.ID
+
+	existing.GroupName = song.GroupName
+	existing.SongName = song.SongName
+	existing.ReleaseDate = dateOnly(song.ReleaseDate)
+	existing.Text = song.Text
+	existing.Link = song.Link
+	existing.UpdatedAt = now()
+	r.state.songs[song.ID] = existing
+	r.state.touch(song.ID)
+
+	*song = existing
+	return song, nil
+}
+
+// DeleteSong удаляет песню вместе с ее версиями
+func (r *MemorySongRepository) DeleteSong(ctx context.Context, id int) error {
+	defer r.lock()()
+
+	song, ok := r.state.songs[id]
+	if !ok {
+		return errors.NewNotFound("song not found", nil)
+	}
+	delete(r.state.songs, id)
+	delete(r.state.keys, NewNaturalKey(song.GroupName, song.SongName))
+	delete(r.state.revisions, id)
+	r.state.bury(id)
+	return nil
+}
+
+// GetGroup получает сведения о группе
+func (r *MemorySongRepository) GetGroup(ctx context.Context, groupName string) (*models.GroupDetails, error) {
+	defer r.rlock()()
+
+	group := &models.GroupDetails{Name: groupName}
+	groupKey := NewNaturalKey(groupName, "").Group
+	for key := range r.state.keys {
+		if key.Group == groupKey {
+			group.SongCount++
+		}
+	}
+	if group.SongCount == 0 {
+		return nil, errors.NewNotFound("group not found", nil)
+	}
+	return group, nil
+}
+
+// CreateSongRevision сохраняет версию песни
+func (r *MemorySongRepository) CreateSongRevision(ctx context.Context, song *models.Song) error {
+	defer r.lock()()
+
+	if _, ok := r.state.songs[song.ID]; !ok {
+		return errors.NewNotFound("referenced record not found", nil)
+	}
+	r.state.revisions[song.ID]++
+	return nil
+}
+
+// CountSongRevisions считает версии песни
+func (r *MemorySongRepository) CountSongRevisions(ctx context.Context, songID int) (int, error) {
+	defer r.rlock()()
+
+	return r.state.revisions[songID], nil
+}
+
+// CountSongsByGroup считает песни групп
+func (r *MemorySongRepository) CountSongsByGroup(ctx context.Context, groupNames []string) (map[string]int, error) {
+	defer r.rlock()()
+
+	wanted := make(map[string]bool, len(groupNames))
+	for _, key := range groupKeys(groupNames) {
+		wanted[key] = true
+	}
+	counts := make(map[string]int)
+	for key := range r.state.keys {
+		if wanted[key.Group] {
+			counts[key.Group]++
+		}
+	}
+	return counts, nil
+}
+
+// CountRevisionsBySong считает версии песен
+func (r *MemorySongRepository) CountRevisionsBySong(ctx context.Context, songIDs []int) (map[int]int, error) {
+	defer r.rlock()()
+
+	counts := make(map[int]int)
+	for _, id := range songIDs {
+		if count := r.state.revisions[id]; count > 0 {
+			counts[id] = count
+		}
+	}
+	return counts, nil
+}
+
+// CreateOutboxEvent записывает событие об изменении песни в outbox
+func (r *MemorySongRepository) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	defer r.lock()()
+
+	r.state.appendEvent(event)
+	return nil
+}
+
+// GetOutboxEvents возвращает события после afterID
+func (r *MemorySongRepository) GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	defer r.rlock()()
+
+	outbox := r.state.outbox
+	start := int(min(max(afterID, 0), int64(len(outbox))))
+	end := min(start+limit, len(outbox))
+	return append([]models.OutboxEvent{}, outbox[start:end]...), nil
+}
+
+// GetLastOutboxEventID возвращает ID последнего события
+func (r *MemorySongRepository) GetLastOutboxEventID(ctx context.Context) (int64, error) {
+	defer r.rlock()()
+
+	return int64(len(r.state.outbox)), nil
+}
+
+// GetSongChanges возвращает изменения песен после since. Записи идут под общей
+// блокировкой по одной, поэтому изменения упорядочены одним номером и Tx всегда 0.
+func (r *MemorySongRepository) GetSongChanges(ctx context.Context, since models.SyncPosition, limit int) ([]models.SongChange, error) {
+	defer r.rlock()()
+
+	var changes []models.SongChange
+	for id, seq := range r.state.changes {
+		if position := (models.SyncPosition{Seq: seq}); since.Before(position) {
+			song := r.state.songs[id]
+			changes = append(changes, models.SongChange{Position: position, SongID: id, Song: &song})
+		}
+	}
+	for id, tombstone := range r.state.tombstones {
+		if position := (models.SyncPosition{Seq: tombstone.seq}); since.Before(position) {
+			changes = append(changes, models.SongChange{Position: position, SongID: id})
+		}
+	}
+	return firstSongChanges(changes, limit), nil
+}
+
+// GetSyncBounds возвращает границы последовательности изменений
+func (r *MemorySongRepository) GetSyncBounds(ctx context.Context) (models.SyncBounds, error) {
+	defer r.rlock()()
+
+	return models.SyncBounds{Horizon: models.SyncPosition{Seq: r.state.horizon}, Latest: r.state.lastSeq}, nil
+}
+
+// PruneTombstones забывает старые удаления песен
+func (r *MemorySongRepository) PruneTombstones(ctx context.Context, before time.Time) (int, error) {
+	defer r.lock()()
+
+	pruned := 0
+	for id, tombstone := range r.state.tombstones {
+		if tombstone.deletedAt.Before(before) {
+			delete(r.state.tombstones, id)
+			r.state.horizon = max(r.state.horizon, tombstone.seq)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// WithinTx выполняет fn над копией данных и при успехе подменяет ими данные
+// репозитория. Транзакции выполняются строго по очереди, поэтому параметры
+// изоляции не нужны и игнорируются.
+func (r *MemorySongRepository) WithinTx(ctx context.Context, fn func(repo SongRepository) error, opts ...TxOption) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	txRepo := &MemorySongRepository{mu: r.mu, state: r.state.clone(), inTx: true}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+	*r.state = *txRepo.state
+	return nil
+}
+
+// insert добавляет новую песню и заполняет ее служебные поля
+func (s *memoryState) insert(song *models.Song, key NaturalKey) {
+	song.ID = s.nextID
+	s.nextID++
+	song.ReleaseDate = dateOnly(song.ReleaseDate)
+	song.CreatedAt = now()
+	song.UpdatedAt = song.CreatedAt
+
+	s.songs[song.ID] = *song
+	s.keys[key] = song.ID
+	s.touch(song.ID)
+}
+
+// touch выдает песне следующий номер изменения
+func (s *memoryState) touch(id int) {
+	s.lastSeq++
+	s.changes[id] = s.lastSeq
+}
+
+// bury выдает удалению песни следующий номер изменения
+func (s *memoryState) bury(id int) {
+	s.lastSeq++
+	delete(s.changes, id)
+	s.tombstones[id] = memoryTombstone{seq: s.lastSeq, deletedAt: now()}
+}
+
+// appendEvent добавляет событие в outbox и заполняет его ID и время
+func (s *memoryState) appendEvent(event *models.OutboxEvent) {
+	event.ID = int64(len(s.outbox)) + 1
+	event.CreatedAt = now()
+	s.outbox = append(s.outbox, *event)
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := &memoryState{
+		songs:      make(map[int]models.Song, len(s.songs)),
+		keys:       make(map[NaturalKey]int, len(s.keys)),
+		revisions:  make(map[int]int, len(s.revisions)),
+		nextID:     s.nextID,
+		changes:    make(map[int]int64, len(s.changes)),
+		tombstones: make(map[int]memoryTombstone, len(s.tombstones)),
+		lastSeq:    s.lastSeq,
+		horizon:    s.horizon,
+		// События только дописываются, поэтому копия делит с данными начало outbox:
+		// запись транзакции за концом среза не видна, пока транзакция не зафиксирована
+		outbox: s.outbox,
+	}
+	for k, v := range s.songs {
+		c.songs[k] = v
+	}
+	for k, v := range s.keys {
+		c.keys[k] = v
+	}
+	for k, v := range s.revisions {
+		c.revisions[k] = v
+	}
+	for k, v := range s.changes {
+		c.changes[k] = v
+	}
+	for k, v := range s.tombstones {
+		c.tombstones[k] = v
+	}
+	return c
+}
+
+// newSongMatcher собирает проверку песни по фильтру с семантикой ILIKE '%value%'
+func newSongMatcher(filter *models.SongFilter) (func(models.Song) bool, error) {
+	type textFilter struct {
+		pattern *regexp.Regexp
+		value   func(models.Song) string
+	}
+
+	var texts []textFilter
+	for _, f := range []struct {
+		value string
+		field func(models.Song) string
+	}{
+		{filter.GroupName, func(s models.Song) string { return s.GroupName }},
+		{filter.SongName, func(s models.Song) string { return s.SongName }},
+		{filter.Text, func(s models.Song) string { return s.Text }},
+		{filter.Link, func(s models.Song) string { return s.Link }},
+	} {
+		if f.value == "" {
+			continue
+		}
+		pattern, err := likePattern("%" + f.value + "%")
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, textFilter{pattern: pattern, value: f.field})
+	}
+
+	return func(song models.Song) bool {
+		for _, t := range texts {
+			if !t.pattern.MatchString(t.value(song)) {
+				return false
+			}
+		}
+		if filter.FromDate != nil && song.ReleaseDate.Before(*filter.FromDate) {
+			return false
+		}
+		if filter.ToDate != nil && song.ReleaseDate.After(*filter.ToDate) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// likePattern переводит шаблон ILIKE в регулярное выражение:
+// % любая строка, _ один символ, \ экранирует следующий символ
+func likePattern(like string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`(?is)^`)
+	escaped := false
+	for _, c := range like {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '%':
+			b.WriteString(`.*`)
+		case c == '_':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
+
+// dateOnly отбрасывает время, как колонка DATE
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// now время записи с точностью до микросекунд, как TIMESTAMP в PostgreSQL
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
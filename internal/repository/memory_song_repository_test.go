@@ -0,0 +1,21 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/repository/repotest"
+)
+
+func TestMemorySongRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.SongRepository {
+		return repository.NewMemorySongRepository()
+	})
+}
+
+func TestMemoryWebhookRepository(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.SongRepository, repository.WebhookRepository) {
+		songs := repository.NewMemorySongRepository()
+		return songs, repository.NewMemoryWebhookRepository(songs)
+	})
+}
@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+)
+
+// MemoryWebhookRepository хранит вебхуки в памяти процесса и читает события
+// из outbox репозитория песен в памяти, с которым делит блокировку
+type MemoryWebhookRepository struct {
+	songs *MemorySongRepository
+
+	webhooks      map[int]models.Webhook
+	nextWebhookID int
+	// deliveries по возрастанию ID, ID доставки на единицу больше ее индекса.
+	// Доставки удаленного вебхука остаются в срезе с deleted.
+	deliveries []memoryDelivery
+	// dispatched сколько первых событий outbox уже разослано
+	dispatched int
+}
+
+// memoryDelivery доставка и признак удаления вместе с вебхуком
+type memoryDelivery struct {
+	models.WebhookDelivery
+	deleted bool
+}
+
+// NewMemoryWebhookRepository создает репозиторий вебхуков для событий репозитория songs
+func NewMemoryWebhookRepository(songs *MemorySongRepository) WebhookRepository {
+	return &MemoryWebhookRepository{
+		songs:         songs,
+		webhooks:      make(map[int]models.Webhook),
+		nextWebhookID: 1,
+	}
+}
+
+func (r *MemoryWebhookRepository) lock() func() {
+	r.songs.mu.Lock()
+	return r.songs.mu.Unlock
+}
+
+func (r *MemoryWebhookRepository) rlock() func() {
+	r.songs.mu.RLock()
+	return r.songs.mu.RUnlock
+}
+
+// CreateWebhook регистрирует вебхук
+func (r *MemoryWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	defer r.lock()()
+
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	webhook.ID = r.nextWebhookID
+	r.nextWebhookID++
+	webhook.CreatedAt = now()
+	r.webhooks[webhook.ID] = *webhook
+	return webhook, nil
+}
+
+// GetWebhooks возвращает все вебхуки по порядку регистрации
+func (r *MemoryWebhookRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	defer r.rlock()()
+
+	return r.sortedWebhooks(), nil
+}
+
+// GetWebhookByID получает вебхук по ID
+func (r *MemoryWebhookRepository) GetWebhookByID(ctx context.Context, id int) (*models.Webhook, error) {
+	defer r.rlock()()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return nil, errors.NewNotFound("webhook not found", nil)
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет вебхук вместе с его доставками
+func (r *MemoryWebhookRepository) DeleteWebhook(ctx context.Context, id int) error {
+	defer r.lock()()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return errors.NewNotFound("webhook not found", nil)
+	}
+	delete(r.webhooks, id)
+	for i := range r.deliveries {
+		if r.deliveries[i].WebhookID == id {
+			r.deliveries[i].deleted = true
+		}
+	}
+	return nil
+}
+
+// EnqueueDeliveries раскладывает события outbox по вебхукам
+func (r *MemoryWebhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (int, error) {
+	defer r.lock()()
+
+	outbox := r.songs.state.outbox
+	end := min(r.dispatched+limit, len(outbox))
+	webhooks := r.sortedWebhooks()
+	for _, event := range outbox[r.dispatched:end] {
+		for _, webhook := range webhooks {
+			if eventTypeMatches(webhook.EventTypes, event.Type) {
+				r.addDelivery(webhook.ID, event)
+			}
+		}
+	}
+
+	enqueued := end - r.dispatched
+	r.dispatched = end
+	return enqueued, nil
+}
+
+// ClaimDeliveries забирает доставки, время которых пришло
+func (r *MemoryWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	defer r.lock()()
+
+	current := now()
+	var due []*memoryDelivery
+	for i := range r.deliveries {
+		d := &r.deliveries[i]
+		if !d.deleted && d.Status == models.DeliveryPending && !d.NextAttemptAt.After(current) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	deliveries := make([]models.PendingDelivery, 0, len(due))
+	for _, d := range due {
+		d.Attempts++
+		d.NextAttemptAt = current.Add(lease)
+		d.UpdatedAt = current
+		deliveries = append(deliveries, models.PendingDelivery{
+			ID:      d.ID,
+			Attempt: d.Attempts,
+			Webhook: r.webhooks[d.WebhookID],
+			Event:   r.songs.state.outbox[d.EventID-1],
+		})
+	}
+	return deliveries, nil
+}
+
+// CompleteDelivery сохраняет итог попытки доставки
+func (r *MemoryWebhookRepository) CompleteDelivery(ctx context.Context, id int64, result *models.DeliveryResult) error {
+	defer r.lock()()
+
+	if id <= 0 || id > int64(len(r.deliveries)) {
+		return errors.NewNotFound("delivery not found", nil)
+	}
+	d := &r.deliveries[id-1]
+	current := now()
+	d.Status = result.Status
+	d.LastStatusCode = result.StatusCode
+	d.LastError = result.Error
+	d.UpdatedAt = current
+	switch result.Status {
+	case models.DeliveryPending:
+		d.NextAttemptAt = result.NextAttemptAt.UTC().Truncate(time.Microsecond)
+	case models.DeliveryDelivered:
+		d.DeliveredAt = &current
+	}
+	return nil
+}
+
+// GetDeliveries возвращает страницу журнала доставок
+func (r *MemoryWebhookRepository) GetDeliveries(ctx context.Context, filter *models.DeliveryFilter) (*models.DeliveriesResponse, error) {
+	setDeliveryFilterDefaults(filter)
+
+	defer r.rlock()()
+
+	var matched []models.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0; i-- {
+		d := r.deliveries[i]
+		if d.deleted || d.WebhookID != filter.WebhookID {
+			continue
+		}
+		if filter.Status != "" && d.Status != filter.Status {
+			continue
+		}
+		matched = append(matched, d.WebhookDelivery)
+	}
+
+	start := min((filter.Page-1)*filter.PageSize, len(matched))
+	end := min(start+filter.PageSize, len(matched))
+	page := append(make([]models.WebhookDelivery, 0, end-start), matched[start:end]...)
+	return newDeliveriesResponse(page, filter, len(matched)), nil
+}
+
+// ReplayDeliveries заново ставит в очередь доставку разосланных событий
+func (r *MemoryWebhookRepository) ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (int, error) {
+	defer r.lock()()
+
+	webhook, ok := r.webhooks[webhookID]
+	if !ok {
+		return 0, nil
+	}
+
+	replayed := 0
+	for _, event := range r.songs.state.outbox[:r.dispatched] {
+		if event.ID >= fromEventID && eventTypeMatches(webhook.EventTypes, event.Type) {
+			r.addDelivery(webhookID, event)
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+// addDelivery ставит доставку события вебхуку в очередь
+func (r *MemoryWebhookRepository) addDelivery(webhookID int, event models.OutboxEvent) {
+	current := now()
+	r.deliveries = append(r.deliveries, memoryDelivery{WebhookDelivery: models.WebhookDelivery{
+		ID:            int64(len(r.deliveries)) + 1,
+		WebhookID:     webhookID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: current,
+		CreatedAt:     current,
+		UpdatedAt:     current,
+	}})
+}
+
+// sortedWebhooks вебхуки по порядку регистрации, чтобы доставки создавались в том же порядке, что в базе
+func (r *MemoryWebhookRepository) sortedWebhooks() []models.Webhook {
+	webhooks := make([]models.Webhook, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks
+}
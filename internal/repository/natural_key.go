@@ -0,0 +1,38 @@
+package repository
+
+import "strings"
+
+// NaturalKey естественный ключ песни: группа и название после нормализации.
+// Это единственное место, где определены правила сравнения песен по имени:
+// регистр не учитывается, пробелы по краям отбрасываются, внутренние
+// последовательности пробелов считаются одним пробелом.
+type NaturalKey struct {
+	Group string
+	Song  string
+}
+
+// NewNaturalKey строит естественный ключ по группе и названию песни
+func NewNaturalKey(groupName, songName string) NaturalKey {
+	return NaturalKey{
+		Group: normalizeKeyPart(groupName),
+		Song:  normalizeKeyPart(songName),
+	}
+}
+
+func normalizeKeyPart(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// groupKeys нормализованные названия групп без повторов
+func groupKeys(groupNames []string) []string {
+	seen := make(map[string]bool, len(groupNames))
+	keys := make([]string, 0, len(groupNames))
+	for _, name := range groupNames {
+		key := normalizeKeyPart(name)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/models"
+)
+
+// NewOutboxEvent собирает событие outbox о песне. В данные события песня
+// попадает в том же виде, что и в ответах API.
+func NewOutboxEvent(eventType events.Type, song *models.Song) (*models.OutboxEvent, error) {
+	payload, err := json.Marshal(song)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxEvent{
+		Type:    string(eventType),
+		SongID:  song.ID,
+		Payload: payload,
+	}, nil
+}
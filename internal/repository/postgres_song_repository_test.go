@@ -0,0 +1,208 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pkg/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/repository/repotest"
+	"github.com/testTask/migrations"
+	"go.uber.org/zap"
+)
+
+// testDatabaseURLEnv строка подключения к отдельной базе для тестов, ее данные удаляются
+const testDatabaseURLEnv = "TEST_DATABASE_URL"
+
+func TestPostgresSongRepository(t *testing.T) {
+	pool := openPostgres(t)
+	repotest.Run(t, func(t *testing.T) repository.SongRepository {
+		truncate(t, pool)
+		return repository.NewPostgresSongRepository(pool, zap.NewNop())
+	})
+}
+
+func TestPostgresWebhookRepository(t *testing.T) {
+	pool := openPostgres(t)
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.SongRepository, repository.WebhookRepository) {
+		truncate(t, pool)
+		return repository.NewPostgresSongRepository(pool, zap.NewNop()), repository.NewPostgresWebhookRepository(pool, zap.NewNop())
+	})
+}
+
+func TestPostgresOutboxEventsWaitForOlderTransactions(t *testing.T) {
+	pool := openPostgres(t)
+	truncate(t, pool)
+	repo := repository.NewPostgresSongRepository(pool, zap.NewNop())
+	ctx := context.Background()
+
+	ids := func(afterID int64) []int64 {
+		t.Helper()
+		events, err := repo.GetOutboxEvents(ctx, afterID, 10)
+		if err != nil {
+			t.Fatalf("GetOutboxEvents: %v", err)
+		}
+		ids := []int64{}
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+	insert := "INSERT INTO outbox_events (type, song_id, payload) VALUES ('song.created', 1, '{}') RETURNING id"
+
+	// Старая транзакция получает txid раньше, а ID события позже новой
+	older, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer older.Rollback(ctx)
+	if _, err := older.Exec(ctx, "SELECT pg_current_xact_id()"); err != nil {
+		t.Fatalf("failed to assign txid: %v", err)
+	}
+	var newerID, olderID int64
+	if err := pool.QueryRow(ctx, insert).Scan(&newerID); err != nil {
+		t.Fatalf("failed to insert newer event: %v", err)
+	}
+	if got := ids(0); len(got) != 0 {
+		t.Fatalf("events while an older transaction is open = %v, want none", got)
+	}
+
+	if err := older.QueryRow(ctx, insert).Scan(&olderID); err != nil {
+		t.Fatalf("failed to insert older event: %v", err)
+	}
+	if err := older.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got, want := ids(0), []int64{olderID, newerID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if got, want := ids(olderID), []int64{newerID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events after %d = %v, want %v", olderID, got, want)
+	}
+	if last, err := repo.GetLastOutboxEventID(ctx); err != nil || last != newerID {
+		t.Errorf("GetLastOutboxEventID = %d, %v, want %d", last, err, newerID)
+	}
+}
+
+func TestPostgresSongChangesWaitForOlderTransactions(t *testing.T) {
+	pool := openPostgres(t)
+	truncate(t, pool)
+	repo := repository.NewPostgresSongRepository(pool, zap.NewNop())
+	ctx := context.Background()
+
+	songIDs := func(since models.SyncPosition) ([]int, []models.SyncPosition) {
+		t.Helper()
+		changes, err := repo.GetSongChanges(ctx, since, 10)
+		if err != nil {
+			t.Fatalf("GetSongChanges: %v", err)
+		}
+		ids, positions := []int{}, []models.SyncPosition{}
+		for _, change := range changes {
+			ids, positions = append(ids, change.SongID), append(positions, change.Position)
+		}
+		return ids, positions
+	}
+	insert := `INSERT INTO songs (group_name, song_name, group_key, song_key) VALUES ($1, $1, $1, $1) RETURNING id`
+
+	// Старая транзакция получает txid раньше, а номер изменения позже новой
+	older, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	defer older.Rollback(ctx)
+	if _, err := older.Exec(ctx, "SELECT pg_current_xact_id()"); err != nil {
+		t.Fatalf("failed to assign txid: %v", err)
+	}
+	var newerID, olderID int
+	if err := pool.QueryRow(ctx, insert, "newer").Scan(&newerID); err != nil {
+		t.Fatalf("failed to insert newer song: %v", err)
+	}
+	if ids, _ := songIDs(models.SyncPosition{}); len(ids) != 0 {
+		t.Fatalf("changes while an older transaction is open = %v, want none", ids)
+	}
+
+	if err := older.QueryRow(ctx, insert, "older").Scan(&olderID); err != nil {
+		t.Fatalf("failed to insert older song: %v", err)
+	}
+	if err := older.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	ids, positions := songIDs(models.SyncPosition{})
+	if want := []int{olderID, newerID}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("changed songs = %v, want %v", ids, want)
+	}
+	if positions[0].Seq < positions[1].Seq {
+		t.Errorf("older song has seq %d before %d, want it numbered after the newer one", positions[0].Seq, positions[1].Seq)
+	}
+	if ids, _ := songIDs(positions[0]); !reflect.DeepEqual(ids, []int{newerID}) {
+		t.Errorf("changed songs after %+v = %v, want [%d]", positions[0], ids, newerID)
+	}
+}
+
+// openPostgres подключается к тестовой базе и применяет миграции,
+// без TEST_DATABASE_URL тест пропускается
+func openPostgres(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv(testDatabaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseURLEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to open pool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	migrateUp(t, pool)
+	return pool
+}
+
+// truncate очищает все таблицы перед тестом
+func truncate(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), "TRUNCATE songs, song_tombstones, outbox_events, webhooks RESTART IDENTITY CASCADE")
+	if err == nil {
+		_, err = pool.Exec(context.Background(), "UPDATE sync_horizon SET seq = 0")
+	}
+	if err != nil {
+		t.Fatalf("failed to truncate tables: %v", err)
+	}
+}
+
+// migrateUp применяет встроенные миграции к тестовой базе
+func migrateUp(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	ctx := context.Background()
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		t.Fatalf("failed to open migrations: %v", err)
+	}
+	db := stdlib.OpenDBFromPool(pool)
+	t.Cleanup(func() { _ = db.Close() })
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to get connection: %v", err)
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("failed to create migration driver: %v", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "postgres", driver)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
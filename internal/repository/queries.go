@@ -1,9 +1,10 @@
 package repository
 
 const (
-	// queries получить список песен с фильтрами
+	// queries получить список песен с фильтрами, при $9 = false текст не читается.
+	// id в сортировке делает страницы стабильными при одинаковом created_at
 	getSongsQuery = `
-		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
+		SELECT id, group_name, song_name, release_date, CASE WHEN $9 THEN text ELSE '' END, link, created_at, updated_at
 		FROM songs
 		WHERE ($1 = '' OR group_name ILIKE '%' || $1 || '%')
 		AND ($2 = '' OR song_name ILIKE '%' || $2 || '%')
@@ -11,7 +12,7 @@ const (
 		AND ($4::timestamp IS NULL OR release_date <= $4)
 		AND ($5 = '' OR text ILIKE '%' || $5 || '%')
 		AND ($6 = '' OR link ILIKE '%' || $6 || '%')
-		ORDER BY created_at DESC
+		ORDER BY created_at DESC, id DESC
 		LIMIT $7 OFFSET $8`
 
 	// queries счетчик количества песен с фильтрами
@@ -33,10 +34,31 @@ const (
 
 	// queries создать песню
 	createSongQuery = `
-		INSERT INTO songs (group_name, song_name, release_date, text, link)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO songs (group_name, song_name, release_date, text, link, group_key, song_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (group_key, song_key) DO NOTHING
 		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at`
 
+	// getSongByKeyQuery получить песню по естественному ключу
+	getSongByKeyQuery = `
+		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM songs
+		WHERE group_key = $1
+		AND song_key = $2`
+
+	// upsertSongByKeyQuery создать песню или заменить существующую с тем же естественным ключом.
+	// xmax = 0 только у только что вставленной строки. Дата выпуска и created_at при замене сохраняются.
+	upsertSongByKeyQuery = `
+		INSERT INTO songs (group_name, song_name, release_date, text, link, group_key, song_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (group_key, song_key) DO UPDATE
+		SET group_name = EXCLUDED.group_name,
+			song_name = EXCLUDED.song_name,
+			text = EXCLUDED.text,
+			link = EXCLUDED.link,
+			updated_at = NOW()
+		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at, xmax = 0`
+
 	// update обновить песню
 	updateSongQuery = `
 		UPDATE songs
@@ -45,6 +67,8 @@ const (
 			release_date = $3,
 			text = $4,
 			link = $5,
+			group_key = $7,
+			song_key = $8,
 			updated_at = NOW()
 		WHERE id = $6
 		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at`
@@ -52,20 +76,260 @@ const (
 	// delete удалить песню
 	deleteSongQuery = `DELETE FROM songs WHERE id = $1`
 
-	// queries проверить существование песни
-	checkSongExistsQuery = `
-		SELECT EXISTS(
-			SELECT 1 FROM songs 
-			WHERE group_name = $1 
-			AND song_name = $2 
-			AND id != $3
-		)`
-
-	// queries проверить существование песни
-	checkSongExistsForCreateQuery = `
-		SELECT EXISTS(
-			SELECT 1 FROM songs 
-			WHERE group_name = $1 
-			AND song_name = $2
-		)`
+	// getSongIDByKeyQuery найти ID песни по естественному ключу
+	getSongIDByKeyQuery = `
+		SELECT id FROM songs
+		WHERE group_key = $1
+		AND song_key = $2`
+
+	// getGroupSongCountQuery количество песен группы по нормализованному названию
+	getGroupSongCountQuery = `SELECT COUNT(*) FROM songs WHERE group_key = $1`
+
+	// createSongRevisionQuery сохранить текущую версию песни следующим номером
+	createSongRevisionQuery = `
+		INSERT INTO song_revisions (song_id, revision, group_name, song_name, release_date, text, link)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6
+		FROM song_revisions
+		WHERE song_id = $1`
+
+	// countSongRevisionsQuery количество версий песни
+	countSongRevisionsQuery = `SELECT COUNT(*) FROM song_revisions WHERE song_id = $1`
+
+	// countSongsByGroupQuery количество песен групп по нормализованным названиям
+	countSongsByGroupQuery = `
+		SELECT group_key, COUNT(*)
+		FROM songs
+		WHERE group_key = ANY ($1)
+		GROUP BY group_key`
+
+	// countRevisionsBySongQuery количество версий песен
+	countRevisionsBySongQuery = `
+		SELECT song_id, COUNT(*)
+		FROM song_revisions
+		WHERE song_id = ANY ($1)
+		GROUP BY song_id`
+
+	// createSongsImportTableQuery временная таблица для массовой загрузки через COPY
+	createSongsImportTableQuery = `
+		CREATE TEMP TABLE ` + songsImportTable + ` (
+			group_name TEXT,
+			song_name TEXT,
+			release_date DATE,
+			text TEXT,
+			link TEXT,
+			group_key TEXT,
+			song_key TEXT
+		) ON COMMIT DROP`
+
+	// insertImportedSongsQuery переносит загруженные песни, пропуская существующие,
+	// сохраняет первую версию каждой добавленной песни и возвращает добавленные
+	insertImportedSongsQuery = `
+		WITH inserted AS (
+			INSERT INTO songs (group_name, song_name, release_date, text, link, group_key, song_key)
+			SELECT group_name, song_name, release_date, text, link, group_key, song_key
+			FROM ` + songsImportTable + `
+			ON CONFLICT (group_key, song_key) DO NOTHING
+			RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at
+		), revisions AS (
+			INSERT INTO song_revisions (song_id, revision, group_name, song_name, release_date, text, link)
+			SELECT id, 1, group_name, song_name, release_date, text, link
+			FROM inserted
+		)
+		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM inserted
+		ORDER BY id`
+
+	// createOutboxEventQuery записать событие об изменении песни, txid заполняет значение по умолчанию
+	createOutboxEventQuery = `
+		INSERT INTO outbox_events (type, song_id, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	// snapshotXmin самая старая транзакция, еще не завершенная к снимку запроса.
+	// Читатели outbox и изменений песен берут только записи транзакций с txid ниже нее:
+	// они все завершены, а запись, которая станет видна позже, получит txid не меньше,
+	// поэтому в порядке (txid, номер) окажется после уже прочитанных
+	snapshotXmin = `pg_snapshot_xmin(pg_current_snapshot())`
+
+	// getOutboxEventsQuery события после события $1 в порядке (txid, id).
+	// Для неизвестного ID, в том числе 0, события читаются с ID больше $1
+	getOutboxEventsQuery = `
+		SELECT e.id, e.type, e.song_id, e.payload, e.created_at
+		FROM outbox_events e
+		LEFT JOIN outbox_events a ON a.id = $1
+		WHERE e.txid < ` + snapshotXmin + `
+		AND CASE WHEN a.id IS NULL THEN e.id > $1 ELSE (e.txid, e.id) > (a.txid, a.id) END
+		ORDER BY e.txid, e.id
+		LIMIT $2`
+
+	// getLastOutboxEventIDQuery ID последнего видимого события в порядке (txid, id), 0 если событий нет
+	getLastOutboxEventIDQuery = `
+		SELECT COALESCE((
+			SELECT id
+			FROM outbox_events
+			WHERE txid < ` + snapshotXmin + `
+			ORDER BY txid DESC, id DESC
+			LIMIT 1
+		), 0)`
+
+	// getChangedSongsQuery песни, измененные после места ($1, $2), по порядку изменений.
+	// xid8 передается через bigint: pgx не знает этого типа
+	getChangedSongsQuery = `
+		SELECT change_txid::text::bigint, change_seq, id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM songs
+		WHERE change_txid < ` + snapshotXmin + `
+		AND (change_txid, change_seq) > ($1::bigint::text::xid8, $2)
+		ORDER BY change_txid, change_seq
+		LIMIT $3`
+
+	// getSongTombstonesQuery удаления песен после места ($1, $2) по порядку изменений
+	getSongTombstonesQuery = `
+		SELECT change_txid::text::bigint, change_seq, song_id
+		FROM song_tombstones
+		WHERE change_txid < ` + snapshotXmin + `
+		AND (change_txid, change_seq) > ($1::bigint::text::xid8, $2)
+		ORDER BY change_txid, change_seq
+		LIMIT $3`
+
+	// getSyncBoundsQuery горизонт синхронизации и последний выданный номер изменения.
+	// Пока последовательность не выдала ни одного номера, is_called = false
+	getSyncBoundsQuery = `
+		SELECT h.txid::text::bigint, h.seq, CASE WHEN s.is_called THEN s.last_value ELSE 0 END
+		FROM sync_horizon h, song_change_seq s`
+
+	// pruneSongTombstonesQuery удаляет записи об удалениях песен до $1, сдвигает
+	// горизонт на последнее удаленное изменение и возвращает количество удаленных
+	pruneSongTombstonesQuery = `
+		WITH pruned AS (
+			DELETE FROM song_tombstones
+			WHERE deleted_at < $1
+			RETURNING change_txid, change_seq
+		), horizon AS (
+			SELECT change_txid, change_seq FROM pruned
+			UNION ALL
+			SELECT txid, seq FROM sync_horizon
+			ORDER BY change_txid DESC, change_seq DESC
+			LIMIT 1
+		)
+		UPDATE sync_horizon
+		SET txid = horizon.change_txid, seq = horizon.change_seq
+		FROM horizon
+		RETURNING (SELECT COUNT(*) FROM pruned)`
+
+	// createWebhookQuery зарегистрировать вебхук
+	createWebhookQuery = `
+		INSERT INTO webhooks (url, secret, event_types)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at`
+
+	// getWebhooksQuery все вебхуки по порядку регистрации
+	getWebhooksQuery = `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhooks
+		ORDER BY id`
+
+	// getWebhookByIDQuery получить вебхук по id
+	getWebhookByIDQuery = `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhooks
+		WHERE id = $1`
+
+	// deleteWebhookQuery удалить вебхук, доставки удаляются каскадом
+	deleteWebhookQuery = `DELETE FROM webhooks WHERE id = $1`
+
+	// enqueueDeliveriesQuery создает доставки до $1 еще не разосланных событий всем
+	// подписанным вебхукам и отмечает события разосланными. События, которые
+	// раскладывает параллельный диспетчер, пропускаются.
+	enqueueDeliveriesQuery = `
+		WITH pending AS (
+			SELECT id, type
+			FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), deliveries AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id)
+			SELECT w.id, p.id
+			FROM pending p
+			JOIN webhooks w ON cardinality(w.event_types) = 0 OR p.type = ANY (w.event_types)
+		)
+		UPDATE outbox_events
+		SET dispatched_at = now()
+		WHERE id IN (SELECT id FROM pending)`
+
+	// claimDeliveriesQuery берет до $1 доставок, время которых пришло, считает попытку
+	// и откладывает следующую на $2 секунд на случай, если диспетчер не сообщит итог
+	claimDeliveriesQuery = `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending'
+			AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1,
+			next_attempt_at = now() + make_interval(secs => $2),
+			updated_at = now()
+		FROM due, webhooks w, outbox_events e
+		WHERE d.id = due.id
+		AND w.id = d.webhook_id
+		AND e.id = d.event_id
+		RETURNING d.id, d.attempts, w.id, w.url, w.secret, w.event_types, w.created_at,
+			e.id, e.type, e.song_id, e.payload, e.created_at`
+
+	// completeDeliveryQuery сохранить итог попытки доставки,
+	// время следующей попытки меняется только для повтора
+	completeDeliveryQuery = `
+		UPDATE webhook_deliveries
+		SET status = $2,
+			last_status_code = NULLIF($3, 0),
+			last_error = NULLIF($4, ''),
+			next_attempt_at = COALESCE($5, next_attempt_at),
+			delivered_at = CASE WHEN $2 = 'delivered' THEN now() ELSE delivered_at END,
+			updated_at = now()
+		WHERE id = $1`
+
+	// deliveriesFilter условия журнала доставок вебхука, пустой $2 - все статусы
+	deliveriesFilter = `
+		WHERE d.webhook_id = $1
+		AND ($2 = '' OR d.status = $2)`
+
+	// getDeliveriesQuery страница журнала доставок, новые первыми
+	getDeliveriesQuery = `
+		SELECT d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+			COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.delivered_at, d.created_at, d.updated_at
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id` + deliveriesFilter + `
+		ORDER BY d.id DESC
+		LIMIT $3 OFFSET $4`
+
+	// countDeliveriesQuery количество доставок в журнале
+	countDeliveriesQuery = `SELECT COUNT(*) FROM webhook_deliveries d` + deliveriesFilter
+
+	// replayDeliveriesQuery заново ставит в очередь доставку вебхуку $1 разосланных
+	// событий начиная с $2 в порядке потока событий, см. getOutboxEventsQuery.
+	// Еще не разосланные события доставит обычная рассылка.
+	replayDeliveriesQuery = `
+		INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT w.id, e.id
+		FROM webhooks w
+		LEFT JOIN outbox_events a ON a.id = $2
+		JOIN outbox_events e ON CASE WHEN a.id IS NULL THEN e.id >= $2 ELSE (e.txid, e.id) >= (a.txid, a.id) END
+			AND e.dispatched_at IS NOT NULL
+			AND (cardinality(w.event_types) = 0 OR e.type = ANY (w.event_types))
+		WHERE w.id = $1
+		ORDER BY e.txid, e.id`
 )
+
+// songsImportTable временная таблица массовой загрузки
+const songsImportTable = "songs_import"
+
+// songsImportColumns колонки, которые передаются через COPY
+var songsImportColumns = []string{"group_name", "song_name", "release_date", "text", "link", "group_key", "song_key"}
+
+// outboxEventColumns колонки событий outbox, которые передаются через COPY
+var outboxEventColumns = []string{"type", "song_id", "payload"}
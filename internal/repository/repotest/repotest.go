@@ -0,0 +1,492 @@
+// Package repotest содержит общий набор тестов, который должна проходить
+// каждая реализация repository.SongRepository.
+package repotest
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+)
+
+// Factory создает пустой репозиторий для одного теста
+type Factory func(t *testing.T) repository.SongRepository
+
+// Run запускает все тесты набора на репозиториях из newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo repository.SongRepository)
+	}{
+		{"CreateAndGet", testCreateAndGet},
+		{"GetMissing", testGetMissing},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"Update", testUpdate},
+		{"UpdateConflict", testUpdateConflict},
+		{"Delete", testDelete},
+		{"ListFilters", testListFilters},
+		{"ListPagination", testListPagination},
+		{"ListWithoutText", testListWithoutText},
+		{"GetByKey", testGetByKey},
+		{"UpsertByKey", testUpsertByKey},
+		{"CreateSongs", testCreateSongs},
+		{"Revisions", testRevisions},
+		{"Group", testGroup},
+		{"BatchCounts", testBatchCounts},
+		{"WithinTx", testWithinTx},
+		{"SongChanges", testSongChanges},
+		{"PruneTombstones", testPruneTombstones},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+var releaseDate = time.Date(2006, time.July, 16, 0, 0, 0, 0, time.UTC)
+
+func newSong(group, name string) *models.Song {
+	return &models.Song{
+		GroupName:   group,
+		SongName:    name,
+		ReleaseDate: releaseDate,
+		Text:        "Verse one\n\nVerse two",
+		Link:        "https://example.com/" + name,
+	}
+}
+
+func mustCreate(t *testing.T, repo repository.SongRepository, group, name string) *models.Song {
+	t.Helper()
+	song, err := repo.CreateSong(context.Background(), newSong(group, name))
+	if err != nil {
+		t.Fatalf("CreateSong(%q, %q): %v", group, name, err)
+	}
+	return song
+}
+
+// requireType проверяет тип ошибки приложения
+func requireType(t *testing.T, err error, want errors.ErrorType) *errors.Error {
+	t.Helper()
+	appErr, ok := errors.As(err)
+	if !ok {
+		t.Fatalf("expected %s error, got %v", want, err)
+	}
+	if appErr.Type != want {
+		t.Fatalf("expected %s error, got %s: %v", want, appErr.Type, err)
+	}
+	return appErr
+}
+
+func testCreateAndGet(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	created := mustCreate(t, repo, "Muse", "Knights of Cydonia")
+	if created.ID == 0 {
+		t.Fatal("created song has no ID")
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Error("created song has no timestamps")
+	}
+
+	got, err := repo.GetSongByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.GroupName != "Muse" || got.SongName != "Knights of Cydonia" || got.Text != created.Text || got.Link != created.Link {
+		t.Errorf("GetSongByID returned %+v, want %+v", got, created)
+	}
+	if !got.ReleaseDate.Equal(releaseDate) {
+		t.Errorf("release date %v, want %v", got.ReleaseDate, releaseDate)
+	}
+}
+
+func testGetMissing(t *testing.T, repo repository.SongRepository) {
+	_, err := repo.GetSongByID(context.Background(), 424242)
+	requireType(t, err, errors.NotFound)
+}
+
+func testCreateDuplicate(t *testing.T, repo repository.SongRepository) {
+	existing := mustCreate(t, repo, "Muse", "Hysteria")
+
+	// Регистр и лишние пробелы не делают песню другой
+	_, err := repo.CreateSong(context.Background(), newSong("  muse ", "HYSTERIA"))
+	appErr := requireType(t, err, errors.AlreadyExists)
+	if appErr.ExistingID != existing.ID {
+		t.Errorf("ExistingID = %d, want %d", appErr.ExistingID, existing.ID)
+	}
+}
+
+func testUpdate(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	song := mustCreate(t, repo, "Muse", "Uprising")
+
+	song.SongName = "Uprising (Live)"
+	song.Text = "New text"
+	updated, err := repo.UpdateSong(ctx, song)
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if updated.SongName != "Uprising (Live)" || updated.Text != "New text" {
+		t.Errorf("UpdateSong returned %+v", updated)
+	}
+
+	got, err := repo.GetSongByID(ctx, song.ID)
+	if err != nil {
+		t.Fatalf("GetSongByID: %v", err)
+	}
+	if got.SongName != "Uprising (Live)" {
+		t.Errorf("song name after update %q", got.SongName)
+	}
+
+	// Старое название освободилось
+	mustCreate(t, repo, "Muse", "Uprising")
+
+	missing := newSong("Muse", "Missing")
+	missing.ID = 424242
+	_, err = repo.UpdateSong(ctx, missing)
+	requireType(t, err, errors.NotFound)
+}
+
+func testUpdateConflict(t *testing.T, repo repository.SongRepository) {
+	first := mustCreate(t, repo, "Muse", "Madness")
+	second := mustCreate(t, repo, "Muse", "Resistance")
+
+	second.SongName = "madness"
+	_, err := repo.UpdateSong(context.Background(), second)
+	appErr := requireType(t, err, errors.AlreadyExists)
+	if appErr.ExistingID != first.ID {
+		t.Errorf("ExistingID = %d, want %d", appErr.ExistingID, first.ID)
+	}
+}
+
+func testDelete(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	song := mustCreate(t, repo, "Muse", "Starlight")
+
+	if err := repo.DeleteSong(ctx, song.ID); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	_, err := repo.GetSongByID(ctx, song.ID)
+	requireType(t, err, errors.NotFound)
+	requireType(t, repo.DeleteSong(ctx, song.ID), errors.NotFound)
+}
+
+func testListFilters(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	for i, name := range []string{"Alpha", "Beta", "Gamma"} {
+		song := newSong("Group "+name, name+" Song")
+		song.ReleaseDate = releaseDate.AddDate(i, 0, 0)
+		song.Text = "lyrics about " + name
+		if _, err := repo.CreateSong(ctx, song); err != nil {
+			t.Fatalf("CreateSong: %v", err)
+		}
+	}
+	unicode := newSong("Кино", "Группа крови")
+	unicode.Text = "Теплое место, но улицы ждут"
+	if _, err := repo.CreateSong(ctx, unicode); err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+
+	from := releaseDate.AddDate(1, 0, 0)
+	to := releaseDate.AddDate(2, 0, 0)
+	tests := []struct {
+		name   string
+		filter models.SongFilter
+		want   int
+	}{
+		{"no filter", models.SongFilter{}, 4},
+		{"group case insensitive", models.SongFilter{GroupName: "group al"}, 1},
+		{"song substring", models.SongFilter{SongName: "ta So"}, 1},
+		{"text", models.SongFilter{Text: "ABOUT"}, 3},
+		{"text unicode case insensitive", models.SongFilter{Text: "ТЕПЛОЕ МЕСТО"}, 1},
+		{"group unicode case insensitive", models.SongFilter{GroupName: "кИНО"}, 1},
+		{"short text", models.SongFilter{Text: "ут"}, 1},
+		{"link", models.SongFilter{Link: "Gamma"}, 1},
+		{"from date inclusive", models.SongFilter{FromDate: &from}, 2},
+		{"to date inclusive", models.SongFilter{ToDate: &from}, 3},
+		{"date range inclusive", models.SongFilter{FromDate: &from, ToDate: &to}, 2},
+		{"to date", models.SongFilter{ToDate: &releaseDate}, 2},
+		{"like wildcard", models.SongFilter{GroupName: "Group _eta"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			response, err := repo.GetSongs(ctx, &filter)
+			if err != nil {
+				t.Fatalf("GetSongs: %v", err)
+			}
+			if response.TotalItems != tt.want || len(response.Songs) != tt.want {
+				t.Errorf("got %d items (%d on page), want %d", response.TotalItems, len(response.Songs), tt.want)
+			}
+		})
+	}
+
+	_, err := repo.GetSongs(ctx, &models.SongFilter{GroupName: "nothing matches"})
+	requireType(t, err, errors.NotFound)
+}
+
+func testListPagination(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+
+	// Пустой список не имеет даже первой страницы
+	_, err := repo.GetSongs(ctx, &models.SongFilter{})
+	requireType(t, err, errors.NotFound)
+
+	for i := 0; i < 5; i++ {
+		mustCreate(t, repo, "Group", fmt.Sprintf("Song %d", i))
+	}
+
+	response, err := repo.GetSongs(ctx, &models.SongFilter{})
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	if response.CurrentPage != 1 || response.PageSize != 10 {
+		t.Errorf("defaults page %d, page size %d, want 1 and 10", response.CurrentPage, response.PageSize)
+	}
+
+	seen := make(map[int]bool)
+	for page := 1; page <= 3; page++ {
+		response, err := repo.GetSongs(ctx, &models.SongFilter{Page: page, PageSize: 2})
+		if err != nil {
+			t.Fatalf("GetSongs page %d: %v", page, err)
+		}
+		if response.TotalItems != 5 || response.TotalPages != 3 {
+			t.Errorf("page %d: total items %d, total pages %d, want 5 and 3", page, response.TotalItems, response.TotalPages)
+		}
+		for _, song := range response.Songs {
+			if seen[song.ID] {
+				t.Errorf("song %d returned twice", song.ID)
+			}
+			seen[song.ID] = true
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("pages returned %d songs, want 5", len(seen))
+	}
+
+	_, err = repo.GetSongs(ctx, &models.SongFilter{Page: 4, PageSize: 2})
+	requireType(t, err, errors.NotFound)
+}
+
+func testListWithoutText(t *testing.T, repo repository.SongRepository) {
+	mustCreate(t, repo, "Muse", "Plug In Baby")
+
+	response, err := repo.GetSongs(context.Background(), &models.SongFilter{WithoutText: true})
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	if response.Songs[0].Text != "" {
+		t.Errorf("text returned with WithoutText: %q", response.Songs[0].Text)
+	}
+	if response.Songs[0].SongName != "Plug In Baby" {
+		t.Errorf("song name %q", response.Songs[0].SongName)
+	}
+}
+
+func testGetByKey(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	song := mustCreate(t, repo, "The Beatles", "Let It Be")
+
+	got, err := repo.GetSongByKey(ctx, "  the   BEATLES ", "let it be")
+	if err != nil {
+		t.Fatalf("GetSongByKey: %v", err)
+	}
+	if got.ID != song.ID {
+		t.Errorf("GetSongByKey returned ID %d, want %d", got.ID, song.ID)
+	}
+
+	_, err = repo.GetSongByKey(ctx, "The Beatles", "Yesterday")
+	requireType(t, err, errors.NotFound)
+}
+
+func testUpsertByKey(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+
+	created, isNew, err := repo.UpsertSongByKey(ctx, newSong("Queen", "Bohemian Rhapsody"))
+	if err != nil {
+		t.Fatalf("UpsertSongByKey: %v", err)
+	}
+	if !isNew {
+		t.Error("first upsert did not create the song")
+	}
+
+	replacement := newSong("QUEEN", "bohemian  rhapsody")
+	replacement.ReleaseDate = releaseDate.AddDate(5, 0, 0)
+	replacement.Text = "Replaced"
+	replaced, isNew, err := repo.UpsertSongByKey(ctx, replacement)
+	if err != nil {
+		t.Fatalf("UpsertSongByKey: %v", err)
+	}
+	if isNew {
+		t.Error("second upsert created a new song")
+	}
+	if replaced.ID != created.ID {
+		t.Errorf("replaced ID %d, want %d", replaced.ID, created.ID)
+	}
+	if replaced.Text != "Replaced" || replaced.GroupName != "QUEEN" {
+		t.Errorf("replaced song %+v", replaced)
+	}
+	if !replaced.ReleaseDate.Equal(releaseDate) {
+		t.Errorf("release date changed on replace: %v", replaced.ReleaseDate)
+	}
+}
+
+func testCreateSongs(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	existing := mustCreate(t, repo, "Muse", "Hysteria")
+
+	created, err := repo.CreateSongs(ctx, []models.Song{
+		*newSong("Muse", "hysteria"),
+		*newSong("Muse", "Supermassive Black Hole"),
+		*newSong("Muse", "Time Is Running Out"),
+		*newSong("MUSE", "time is running out"),
+	})
+	if err != nil {
+		t.Fatalf("CreateSongs: %v", err)
+	}
+	if created != 2 {
+		t.Errorf("CreateSongs created %d, want 2", created)
+	}
+
+	response, err := repo.GetSongs(ctx, &models.SongFilter{})
+	if err != nil {
+		t.Fatalf("GetSongs: %v", err)
+	}
+	if response.TotalItems != 3 {
+		t.Errorf("total items %d, want 3", response.TotalItems)
+	}
+
+	// Импортированные песни сразу получают первую версию
+	song, err := repo.GetSongByKey(ctx, "Muse", "Supermassive Black Hole")
+	if err != nil {
+		t.Fatalf("GetSongByKey: %v", err)
+	}
+	if count, err := repo.CountSongRevisions(ctx, song.ID); err != nil || count != 1 {
+		t.Errorf("revisions of imported song %d, %v, want 1", count, err)
+	}
+	if count, err := repo.CountSongRevisions(ctx, existing.ID); err != nil || count != 0 {
+		t.Errorf("revisions of skipped song %d, %v, want 0", count, err)
+	}
+}
+
+func testRevisions(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	song := mustCreate(t, repo, "Muse", "Map of the Problematique")
+
+	for want := 1; want <= 2; want++ {
+		if err := repo.CreateSongRevision(ctx, song); err != nil {
+			t.Fatalf("CreateSongRevision: %v", err)
+		}
+		count, err := repo.CountSongRevisions(ctx, song.ID)
+		if err != nil {
+			t.Fatalf("CountSongRevisions: %v", err)
+		}
+		if count != want {
+			t.Errorf("revisions %d, want %d", count, want)
+		}
+	}
+
+	missing := newSong("Muse", "Missing")
+	missing.ID = 424242
+	requireType(t, repo.CreateSongRevision(ctx, missing), errors.NotFound)
+
+	// Версии удаляются вместе с песней
+	if err := repo.DeleteSong(ctx, song.ID); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if count, err := repo.CountSongRevisions(ctx, song.ID); err != nil || count != 0 {
+		t.Errorf("revisions after delete %d, %v, want 0", count, err)
+	}
+}
+
+func testGroup(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	mustCreate(t, repo, "Muse", "Hysteria")
+	mustCreate(t, repo, "MUSE", "Uprising")
+	mustCreate(t, repo, "Queen", "Innuendo")
+
+	group, err := repo.GetGroup(ctx, "muse")
+	if err != nil {
+		t.Fatalf("GetGroup: %v", err)
+	}
+	if group.SongCount != 2 {
+		t.Errorf("song count %d, want 2", group.SongCount)
+	}
+
+	_, err = repo.GetGroup(ctx, "Nobody")
+	requireType(t, err, errors.NotFound)
+}
+
+func testBatchCounts(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	hysteria := mustCreate(t, repo, "Muse", "Hysteria")
+	uprising := mustCreate(t, repo, "MUSE", "Uprising")
+	mustCreate(t, repo, "Queen", "Innuendo")
+	for _, song := range []*models.Song{hysteria, hysteria, uprising} {
+		if err := repo.CreateSongRevision(ctx, song); err != nil {
+			t.Fatalf("CreateSongRevision: %v", err)
+		}
+	}
+
+	groups, err := repo.CountSongsByGroup(ctx, []string{" muse ", "Muse", "Nobody"})
+	if err != nil {
+		t.Fatalf("CountSongsByGroup: %v", err)
+	}
+	if len(groups) != 1 || groups["muse"] != 2 {
+		t.Errorf("group song counts %v, want map[muse:2]", groups)
+	}
+
+	revisions, err := repo.CountRevisionsBySong(ctx, []int{hysteria.ID, uprising.ID, 424242})
+	if err != nil {
+		t.Fatalf("CountRevisionsBySong: %v", err)
+	}
+	want := map[int]int{hysteria.ID: 2, uprising.ID: 1}
+	if len(revisions) != len(want) || revisions[hysteria.ID] != 2 || revisions[uprising.ID] != 1 {
+		t.Errorf("revision counts %v, want %v", revisions, want)
+	}
+
+	if empty, err := repo.CountRevisionsBySong(ctx, nil); err != nil || len(empty) != 0 {
+		t.Errorf("revision counts of no songs %v, %v", empty, err)
+	}
+}
+
+func testWithinTx(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	errRollback := stderrors.New("rollback")
+
+	err := repo.WithinTx(ctx, func(tx repository.SongRepository) error {
+		if _, err := tx.CreateSong(ctx, newSong("Muse", "Rolled Back")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !stderrors.Is(err, errRollback) {
+		t.Fatalf("WithinTx returned %v, want %v", err, errRollback)
+	}
+	_, err = repo.GetSongByKey(ctx, "Muse", "Rolled Back")
+	requireType(t, err, errors.NotFound)
+
+	var committed *models.Song
+	err = repo.WithinTx(ctx, func(tx repository.SongRepository) error {
+		song, err := tx.CreateSong(ctx, newSong("Muse", "Committed"))
+		if err != nil {
+			return err
+		}
+		committed = song
+		// Вложенный вызов работает в той же транзакции
+		return tx.WithinTx(ctx, func(nested repository.SongRepository) error {
+			return nested.CreateSongRevision(ctx, song)
+		})
+	}, repository.WithIsolation(repository.Serializable))
+	if err != nil {
+		t.Fatalf("WithinTx: %v", err)
+	}
+	if count, err := repo.CountSongRevisions(ctx, committed.ID); err != nil || count != 1 {
+		t.Errorf("revisions after commit %d, %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,151 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+)
+
+// mustSyncBounds читает границы последовательности изменений
+func mustSyncBounds(t *testing.T, repo repository.SongRepository) models.SyncBounds {
+	t.Helper()
+	bounds, err := repo.GetSyncBounds(context.Background())
+	if err != nil {
+		t.Fatalf("GetSyncBounds: %v", err)
+	}
+	return bounds
+}
+
+// mustSongChanges читает изменения песен после since
+func mustSongChanges(t *testing.T, repo repository.SongRepository, since models.SyncPosition, limit int) []models.SongChange {
+	t.Helper()
+	changes, err := repo.GetSongChanges(context.Background(), since, limit)
+	if err != nil {
+		t.Fatalf("GetSongChanges(%+v, %d): %v", since, limit, err)
+	}
+	return changes
+}
+
+// requireChanges проверяет порядок изменений: ID песен и удаления (Song == nil)
+func requireChanges(t *testing.T, changes []models.SongChange, since models.SyncPosition, ids []int, deleted []bool) {
+	t.Helper()
+	if len(changes) != len(ids) {
+		t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(ids))
+	}
+	last := since
+	for i, change := range changes {
+		if !last.Before(change.Position) {
+			t.Errorf("change %d is at %+v, want after %+v", i, change.Position, last)
+		}
+		last = change.Position
+		if change.SongID != ids[i] || (change.Song == nil) != deleted[i] {
+			t.Errorf("change %d is song %d deleted=%t, want song %d deleted=%t",
+				i, change.SongID, change.Song == nil, ids[i], deleted[i])
+		}
+		if change.Song != nil && change.Song.ID != change.SongID {
+			t.Errorf("change %d of song %d carries song %d", i, change.SongID, change.Song.ID)
+		}
+	}
+}
+
+func testSongChanges(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	// Хранилище новое, поэтому все изменения теста идут после последнего номера
+	base := models.SyncPosition{Seq: mustSyncBounds(t, repo).Latest}
+
+	first := mustCreate(t, repo, "Muse", "Uprising")
+	second := mustCreate(t, repo, "Muse", "Resistance")
+	third := mustCreate(t, repo, "Muse", "Madness")
+
+	first.SongName = "Uprising (Live)"
+	if _, err := repo.UpdateSong(ctx, first); err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if err := repo.DeleteSong(ctx, second.ID); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+
+	// Каждая песня встречается один раз, на месте своего последнего изменения
+	changes := mustSongChanges(t, repo, base, 10)
+	requireChanges(t, changes, base, []int{third.ID, first.ID, second.ID}, []bool{false, false, true})
+	if changes[1].Song.SongName != "Uprising (Live)" {
+		t.Errorf("changed song name %q, want the updated one", changes[1].Song.SongName)
+	}
+	if latest := mustSyncBounds(t, repo).Latest; latest != changes[2].Position.Seq {
+		t.Errorf("latest seq %d, want %d", latest, changes[2].Position.Seq)
+	}
+
+	// Пачка ограничена limit, следующая продолжается с номера последнего изменения
+	page := mustSongChanges(t, repo, base, 2)
+	requireChanges(t, page, base, []int{third.ID, first.ID}, []bool{false, false})
+	rest := mustSongChanges(t, repo, page[1].Position, 2)
+	requireChanges(t, rest, page[1].Position, []int{second.ID}, []bool{true})
+	if empty := mustSongChanges(t, repo, rest[0].Position, 2); len(empty) != 0 {
+		t.Errorf("changes after the last one: %+v", empty)
+	}
+
+	// Замена по ключу и массовое добавление тоже выдают номера
+	since := rest[0].Position
+	if _, _, err := repo.UpsertSongByKey(ctx, newSong("Muse", "Madness")); err != nil {
+		t.Fatalf("UpsertSongByKey: %v", err)
+	}
+	if _, err := repo.CreateSongs(ctx, []models.Song{*newSong("Muse", "Unintended")}); err != nil {
+		t.Fatalf("CreateSongs: %v", err)
+	}
+	imported, err := repo.GetSongByKey(ctx, "Muse", "Unintended")
+	if err != nil {
+		t.Fatalf("GetSongByKey: %v", err)
+	}
+	requireChanges(t, mustSongChanges(t, repo, since, 10), since, []int{third.ID, imported.ID}, []bool{false, false})
+}
+
+func testPruneTombstones(t *testing.T, repo repository.SongRepository) {
+	ctx := context.Background()
+	base := mustSyncBounds(t, repo)
+
+	kept := mustCreate(t, repo, "Muse", "Hysteria")
+	for _, name := range []string{"Plug In Baby", "Bliss"} {
+		song := mustCreate(t, repo, "Muse", name)
+		if err := repo.DeleteSong(ctx, song.ID); err != nil {
+			t.Fatalf("DeleteSong: %v", err)
+		}
+	}
+	latest := mustSyncBounds(t, repo).Latest
+
+	pruned, err := repo.PruneTombstones(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("PruneTombstones: %v", err)
+	}
+	if pruned != 0 {
+		t.Errorf("pruned %d fresh tombstones, want 0", pruned)
+	}
+	if horizon := mustSyncBounds(t, repo).Horizon; horizon != base.Horizon {
+		t.Errorf("horizon moved to %+v without pruning, want %+v", horizon, base.Horizon)
+	}
+
+	pruned, err = repo.PruneTombstones(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("PruneTombstones: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("pruned %d tombstones, want 2", pruned)
+	}
+	// Горизонт встает на последнее забытое удаление
+	horizon := mustSyncBounds(t, repo).Horizon
+	if horizon.Seq != latest {
+		t.Errorf("horizon %+v, want seq %d", horizon, latest)
+	}
+	since := models.SyncPosition{Seq: base.Latest}
+	requireChanges(t, mustSongChanges(t, repo, since, 10), since, []int{kept.ID}, []bool{false})
+
+	// Повторная чистка без удалений горизонт не сдвигает
+	if pruned, err := repo.PruneTombstones(ctx, time.Now().Add(time.Hour)); err != nil || pruned != 0 {
+		t.Errorf("second prune %d, %v, want 0", pruned, err)
+	}
+	if again := mustSyncBounds(t, repo).Horizon; again != horizon {
+		t.Errorf("horizon %+v after empty prune, want %+v", again, horizon)
+	}
+}
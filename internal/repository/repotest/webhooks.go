@@ -0,0 +1,381 @@
+package repotest
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+)
+
+// WebhookFactory создает пустые репозитории песен и вебхуков над общим хранилищем
+type WebhookFactory func(t *testing.T) (repository.SongRepository, repository.WebhookRepository)
+
+// RunWebhooks запускает тесты outbox и очереди доставки на репозиториях из newRepos
+func RunWebhooks(t *testing.T, newRepos WebhookFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, songs repository.SongRepository, webhooks repository.WebhookRepository)
+	}{
+		{"Webhooks", testWebhooks},
+		{"OutboxRollback", testOutboxRollback},
+		{"Deliveries", testDeliveries},
+		{"CreateSongsEvents", testCreateSongsEvents},
+		{"OutboxEvents", testOutboxEvents},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			songs, webhooks := newRepos(t)
+			tt.run(t, songs, webhooks)
+		})
+	}
+}
+
+func mustCreateWebhook(t *testing.T, repo repository.WebhookRepository, eventTypes ...string) *models.Webhook {
+	t.Helper()
+	webhook, err := repo.CreateWebhook(context.Background(), &models.Webhook{
+		URL:        "https://example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return webhook
+}
+
+// writeSongEvent создает песню и событие о ней в одной транзакции, как это делает сервис
+func writeSongEvent(t *testing.T, repo repository.SongRepository, eventType events.Type, name string) *models.OutboxEvent {
+	t.Helper()
+	ctx := context.Background()
+
+	var event *models.OutboxEvent
+	err := repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		song, err := repo.CreateSong(ctx, newSong("Muse", name))
+		if err != nil {
+			return err
+		}
+		if event, err = repository.NewOutboxEvent(eventType, song); err != nil {
+			return err
+		}
+		return repo.CreateOutboxEvent(ctx, event)
+	})
+	if err != nil {
+		t.Fatalf("failed to write song event: %v", err)
+	}
+	if event.ID == 0 || event.CreatedAt.IsZero() {
+		t.Fatalf("event has no ID or time: %+v", event)
+	}
+	return event
+}
+
+func testWebhooks(t *testing.T, _ repository.SongRepository, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	first := mustCreateWebhook(t, repo)
+	second := mustCreateWebhook(t, repo, string(events.SongDeleted))
+	if first.ID == 0 || first.CreatedAt.IsZero() {
+		t.Fatalf("created webhook has no ID or time: %+v", first)
+	}
+
+	got, err := repo.GetWebhookByID(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("GetWebhookByID: %v", err)
+	}
+	if got.URL != second.URL || got.Secret != second.Secret || len(got.EventTypes) != 1 || got.EventTypes[0] != string(events.SongDeleted) {
+		t.Errorf("GetWebhookByID returned %+v, want %+v", got, second)
+	}
+
+	all, err := repo.GetWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("GetWebhooks: %v", err)
+	}
+	if len(all) != 2 || all[0].ID != first.ID || all[1].ID != second.ID {
+		t.Fatalf("GetWebhooks returned %+v", all)
+	}
+	if all[0].EventTypes == nil || len(all[0].EventTypes) != 0 {
+		t.Errorf("webhook for all events has event types %#v, want empty", all[0].EventTypes)
+	}
+
+	if err := repo.DeleteWebhook(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	_, err = repo.GetWebhookByID(ctx, first.ID)
+	requireType(t, err, errors.NotFound)
+	requireType(t, repo.DeleteWebhook(ctx, first.ID), errors.NotFound)
+}
+
+func testOutboxRollback(t *testing.T, songs repository.SongRepository, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	mustCreateWebhook(t, repo)
+
+	errRollback := stderrors.New("rollback")
+	err := songs.WithinTx(ctx, func(songs repository.SongRepository) error {
+		song, err := songs.CreateSong(ctx, newSong("Muse", "Uprising"))
+		if err != nil {
+			return err
+		}
+		event, err := repository.NewOutboxEvent(events.SongCreated, song)
+		if err != nil {
+			return err
+		}
+		if err := songs.CreateOutboxEvent(ctx, event); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !stderrors.Is(err, errRollback) {
+		t.Fatalf("WithinTx returned %v, want rollback error", err)
+	}
+
+	enqueued, err := repo.EnqueueDeliveries(ctx, 10)
+	if err != nil {
+		t.Fatalf("EnqueueDeliveries: %v", err)
+	}
+	if enqueued != 0 {
+		t.Errorf("rolled back event was enqueued")
+	}
+}
+
+func testDeliveries(t *testing.T, songs repository.SongRepository, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	all := mustCreateWebhook(t, repo)
+	deletions := mustCreateWebhook(t, repo, string(events.SongDeleted))
+
+	created := writeSongEvent(t, songs, events.SongCreated, "Uprising")
+	updated := writeSongEvent(t, songs, events.SongUpdated, "Resistance")
+	deleted := writeSongEvent(t, songs, events.SongDeleted, "Madness")
+
+	// Рассылка идет пачками в порядке событий
+	enqueued, err := repo.EnqueueDeliveries(ctx, 2)
+	if err != nil {
+		t.Fatalf("EnqueueDeliveries: %v", err)
+	}
+	if enqueued != 2 {
+		t.Fatalf("EnqueueDeliveries enqueued %d events, want 2", enqueued)
+	}
+	if enqueued, err = repo.EnqueueDeliveries(ctx, 10); err != nil || enqueued != 1 {
+		t.Fatalf("EnqueueDeliveries = %d, %v, want 1 event", enqueued, err)
+	}
+	if enqueued, err = repo.EnqueueDeliveries(ctx, 10); err != nil || enqueued != 0 {
+		t.Fatalf("EnqueueDeliveries = %d, %v, want nothing to enqueue", enqueued, err)
+	}
+
+	claimed, err := repo.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	if len(claimed) != 4 {
+		t.Fatalf("ClaimDeliveries returned %d deliveries, want 4", len(claimed))
+	}
+	byEvent := make(map[int64]models.PendingDelivery)
+	for _, d := range claimed {
+		if d.Attempt != 1 {
+			t.Errorf("delivery %d attempt %d, want 1", d.ID, d.Attempt)
+		}
+		if d.Webhook.ID == all.ID {
+			byEvent[d.Event.ID] = d
+		} else if d.Webhook.ID != deletions.ID || d.Event.ID != deleted.ID {
+			t.Errorf("unexpected delivery of event %d to webhook %d", d.Event.ID, d.Webhook.ID)
+		}
+	}
+	for _, want := range []*models.OutboxEvent{created, updated, deleted} {
+		got, ok := byEvent[want.ID]
+		if !ok {
+			t.Fatalf("event %d is not delivered to webhook for all events", want.ID)
+		}
+		if got.Event.Type != want.Type || got.Event.SongID != want.SongID || got.Webhook.Secret != all.Secret {
+			t.Errorf("delivery %+v does not match event %+v", got, want)
+		}
+		var gotSong, wantSong models.Song
+		if err := json.Unmarshal(got.Event.Payload, &gotSong); err != nil {
+			t.Fatalf("invalid payload %s: %v", got.Event.Payload, err)
+		}
+		_ = json.Unmarshal(want.Payload, &wantSong)
+		if gotSong.ID != wantSong.ID || gotSong.SongName != wantSong.SongName {
+			t.Errorf("payload %s, want %s", got.Event.Payload, want.Payload)
+		}
+	}
+
+	// Взятые доставки не выдаются повторно, пока не истекла аренда
+	if again, err := repo.ClaimDeliveries(ctx, 10, time.Minute); err != nil || len(again) != 0 {
+		t.Fatalf("ClaimDeliveries = %d deliveries, %v, want none", len(again), err)
+	}
+
+	results := map[int64]*models.DeliveryResult{
+		byEvent[created.ID].ID: {Status: models.DeliveryDelivered, StatusCode: 204},
+		byEvent[updated.ID].ID: {Status: models.DeliveryPending, StatusCode: 503, Error: "unexpected status 503",
+			NextAttemptAt: time.Now().Add(-time.Second)},
+		byEvent[deleted.ID].ID: {Status: models.DeliveryFailed, Error: "connection refused"},
+	}
+	for id, result := range results {
+		if err := repo.CompleteDelivery(ctx, id, result); err != nil {
+			t.Fatalf("CompleteDelivery: %v", err)
+		}
+	}
+
+	retried, err := repo.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	if len(retried) != 1 || retried[0].Event.ID != updated.ID || retried[0].Attempt != 2 {
+		t.Fatalf("ClaimDeliveries returned %+v, want second attempt of event %d", retried, updated.ID)
+	}
+
+	log, err := repo.GetDeliveries(ctx, &models.DeliveryFilter{WebhookID: all.ID})
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if log.TotalItems != 3 || len(log.Deliveries) != 3 || log.TotalPages != 1 {
+		t.Fatalf("GetDeliveries returned %+v, want 3 deliveries", log)
+	}
+	// Новые доставки первыми
+	for i := 1; i < len(log.Deliveries); i++ {
+		if log.Deliveries[i-1].ID < log.Deliveries[i].ID {
+			t.Errorf("deliveries are not ordered from newest: %+v", log.Deliveries)
+		}
+	}
+	for _, d := range log.Deliveries {
+		switch d.EventID {
+		case created.ID:
+			if d.Status != models.DeliveryDelivered || d.DeliveredAt == nil || d.LastStatusCode != 204 || d.EventType != created.Type {
+				t.Errorf("delivered delivery %+v", d)
+			}
+		case deleted.ID:
+			if d.Status != models.DeliveryFailed || d.LastError != "connection refused" || d.DeliveredAt != nil {
+				t.Errorf("failed delivery %+v", d)
+			}
+		case updated.ID:
+			if d.Status != models.DeliveryPending || d.Attempts != 2 || d.LastStatusCode != 503 {
+				t.Errorf("retried delivery %+v", d)
+			}
+		}
+	}
+
+	failed, err := repo.GetDeliveries(ctx, &models.DeliveryFilter{WebhookID: all.ID, Status: models.DeliveryFailed, PageSize: 1})
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if failed.TotalItems != 1 || len(failed.Deliveries) != 1 || failed.Deliveries[0].EventID != deleted.ID {
+		t.Errorf("GetDeliveries by status returned %+v", failed)
+	}
+
+	// Повтор ставит в очередь все разосланные события начиная с заданного
+	replayed, err := repo.ReplayDeliveries(ctx, all.ID, updated.ID)
+	if err != nil {
+		t.Fatalf("ReplayDeliveries: %v", err)
+	}
+	if replayed != 2 {
+		t.Fatalf("ReplayDeliveries replayed %d events, want 2", replayed)
+	}
+	replayedClaim, err := repo.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	if len(replayedClaim) != 2 || replayedClaim[0].Attempt != 1 {
+		t.Fatalf("ClaimDeliveries after replay returned %+v", replayedClaim)
+	}
+
+	if err := repo.DeleteWebhook(ctx, all.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	log, err = repo.GetDeliveries(ctx, &models.DeliveryFilter{WebhookID: all.ID})
+	if err != nil {
+		t.Fatalf("GetDeliveries: %v", err)
+	}
+	if log.TotalItems != 0 || len(log.Deliveries) != 0 {
+		t.Errorf("deliveries of deleted webhook remain: %+v", log)
+	}
+}
+
+func testCreateSongsEvents(t *testing.T, songs repository.SongRepository, repo repository.WebhookRepository) {
+	ctx := context.Background()
+	mustCreateWebhook(t, repo)
+	mustCreate(t, songs, "Muse", "Hysteria")
+
+	created, err := songs.CreateSongs(ctx, []models.Song{
+		*newSong("Muse", "Hysteria"),
+		*newSong("Muse", "Starlight"),
+		*newSong("Muse", "Supermassive Black Hole"),
+	})
+	if err != nil {
+		t.Fatalf("CreateSongs: %v", err)
+	}
+	if created != 2 {
+		t.Fatalf("CreateSongs created %d, want 2", created)
+	}
+
+	if _, err := repo.EnqueueDeliveries(ctx, 10); err != nil {
+		t.Fatalf("EnqueueDeliveries: %v", err)
+	}
+	claimed, err := repo.ClaimDeliveries(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDeliveries: %v", err)
+	}
+	// Песня, созданная CreateSong без события, в рассылку не попадает
+	if len(claimed) != 2 {
+		t.Fatalf("ClaimDeliveries returned %d deliveries, want 2", len(claimed))
+	}
+	for _, d := range claimed {
+		if d.Event.Type != string(events.SongCreated) || d.Event.SongID == 0 {
+			t.Errorf("unexpected imported song event %+v", d.Event)
+		}
+	}
+}
+
+func testOutboxEvents(t *testing.T, songs repository.SongRepository, _ repository.WebhookRepository) {
+	ctx := context.Background()
+
+	last, err := songs.GetLastOutboxEventID(ctx)
+	if err != nil {
+		t.Fatalf("GetLastOutboxEventID: %v", err)
+	}
+	if last != 0 {
+		t.Fatalf("GetLastOutboxEventID on empty outbox = %d, want 0", last)
+	}
+	empty, err := songs.GetOutboxEvents(ctx, 0, 10)
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Fatalf("GetOutboxEvents on empty outbox = %#v, %v, want empty slice", empty, err)
+	}
+
+	written := []*models.OutboxEvent{
+		writeSongEvent(t, songs, events.SongCreated, "Uprising"),
+		writeSongEvent(t, songs, events.SongUpdated, "Resistance"),
+		writeSongEvent(t, songs, events.SongDeleted, "Madness"),
+	}
+	if last, err = songs.GetLastOutboxEventID(ctx); err != nil || last != written[2].ID {
+		t.Fatalf("GetLastOutboxEventID = %d, %v, want %d", last, err, written[2].ID)
+	}
+
+	// Чтение пачками по ID возвращает все события по порядку
+	var got []models.OutboxEvent
+	for cursor := int64(0); ; {
+		batch, err := songs.GetOutboxEvents(ctx, cursor, 2)
+		if err != nil {
+			t.Fatalf("GetOutboxEvents: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+		got = append(got, batch...)
+		cursor = batch[len(batch)-1].ID
+	}
+	if len(got) != len(written) {
+		t.Fatalf("GetOutboxEvents returned %d events, want %d", len(got), len(written))
+	}
+	for i, want := range written {
+		if got[i].ID != want.ID || got[i].Type != want.Type || got[i].SongID != want.SongID ||
+			!got[i].CreatedAt.Equal(want.CreatedAt) || len(got[i].Payload) == 0 {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+
+	tail, err := songs.GetOutboxEvents(ctx, written[2].ID, 10)
+	if err != nil || len(tail) != 0 {
+		t.Errorf("GetOutboxEvents after last event = %d events, %v, want none", len(tail), err)
+	}
+}
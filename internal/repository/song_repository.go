@@ -2,27 +2,94 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	stderrors "errors"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/events"
 	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/tracing"
+	"go.uber.org/zap"
 )
 
 type SongRepository interface {
 	GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error)
 	GetSongByID(ctx context.Context, id int) (*models.Song, error)
 	CreateSong(ctx context.Context, song *models.Song) (*models.Song, error)
+	// GetSongByKey ищет песню по естественному ключу группа/название, см. NewNaturalKey
+	GetSongByKey(ctx context.Context, groupName, songName string) (*models.Song, error)
+	// UpsertSongByKey атомарно создает песню или заменяет песню с тем же естественным ключом.
+	// Второе значение сообщает, была ли песня создана.
+	UpsertSongByKey(ctx context.Context, song *models.Song) (*models.Song, bool, error)
+	// CreateSongs массово добавляет песни, уже существующие пропускает, и записывает
+	// в outbox событие song.created каждой добавленной. Возвращает количество добавленных песен.
+	CreateSongs(ctx context.Context, songs []models.Song) (int, error)
 	UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error)
 	DeleteSong(ctx context.Context, id int) error
+	// GetGroup возвращает сведения о группе по ее названию
+	GetGroup(ctx context.Context, groupName string) (*models.GroupDetails, error)
+	// CreateSongRevision сохраняет текущее состояние песни следующей версией
+	CreateSongRevision(ctx context.Context, song *models.Song) error
+	// CountSongRevisions возвращает количество сохраненных версий песни
+	CountSongRevisions(ctx context.Context, songID int) (int, error)
+	// CountSongsByGroup считает песни нескольких групп одним запросом. Ключ результата -
+	// нормализованное название NewNaturalKey(name, "").Group, группы без песен в него не попадают.
+	CountSongsByGroup(ctx context.Context, groupNames []string) (map[string]int, error)
+	// CountRevisionsBySong считает версии нескольких песен одним запросом,
+	// песни без версий в результат не попадают
+	CountRevisionsBySong(ctx context.Context, songIDs []int) (map[int]int, error)
+	// CreateOutboxEvent записывает событие об изменении песни в outbox.
+	// Вызывается в WithinTx вместе с самим изменением, заполняет ID и CreatedAt.
+	CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error
+	// GetOutboxEvents возвращает до limit событий после события afterID в порядке потока.
+	// Событие, которое станет видно позже, в этом порядке идет после уже прочитанных, поэтому
+	// чтение можно продолжать с ID последнего события без пропусков. ID в потоке могут идти
+	// не по возрастанию.
+	GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]models.OutboxEvent, error)
+	// GetLastOutboxEventID возвращает ID последнего события в порядке GetOutboxEvents, 0 если событий нет
+	GetLastOutboxEventID(ctx context.Context) (int64, error)
+	// GetSongChanges возвращает до limit изменений песен после места since по порядку
+	// изменений: текущие версии созданных и измененных песен и удаления. Изменение, которое
+	// станет видно позже, идет после уже прочитанных. Горизонт из GetSyncBounds согласован
+	// с изменениями только внутри WithinTx с уровнем не ниже RepeatableRead.
+	GetSongChanges(ctx context.Context, since models.SyncPosition, limit int) ([]models.SongChange, error)
+	// GetSyncBounds возвращает горизонт синхронизации и последний выданный номер изменения
+	GetSyncBounds(ctx context.Context) (models.SyncBounds, error)
+	// PruneTombstones забывает удаления песен раньше before и сдвигает горизонт
+	// синхронизации на последнее забытое. Возвращает количество забытых удалений.
+	PruneTombstones(ctx context.Context, before time.Time) (int, error)
+	// WithinTx атомарно выполняет несколько вызовов репозитория, см. TxOptions
+	WithinTx(ctx context.Context, fn func(repo SongRepository) error, opts ...TxOption) error
 }
 
+// PostgresSongRepository хранит песни в PostgreSQL через пул pgx.
+// Пул кэширует подготовленные выражения, поэтому каждый запрос из queries.go
+// готовится на соединении один раз.
 type PostgresSongRepository struct {
-	db *sql.DB
+	pool *pgxpool.Pool
+	// db пул или открытая транзакция, через которые выполняются запросы
+	db        querier
+	logger    *zap.Logger
+	txOptions TxOptions
+	// inTx репозиторий привязан к транзакции WithinTx
+	inTx bool
 }
 
-func NewPostgresSongRepository(db *sql.DB) SongRepository {
-	return &PostgresSongRepository{db: db}
+// NewPostgresSongRepository создает репозиторий, opts задают параметры транзакций по умолчанию
+func NewPostgresSongRepository(pool *pgxpool.Pool, logger *zap.Logger, opts ...TxOption) SongRepository {
+	txOptions := defaultTxOptions()
+	for _, opt := range opts {
+		opt(&txOptions)
+	}
+	return &PostgresSongRepository{
+		pool:      pool,
+		db:        pool,
+		logger:    logger,
+		txOptions: txOptions,
+	}
 }
 
 // GetSongs получает список песен
@@ -34,43 +101,37 @@ func (r *PostgresSongRepository) GetSongs(ctx context.Context, filter *models.So
 		filter.Page = 1
 	}
 
-	// Получаем общее количество записей
-	var totalItems int
-	err := r.db.QueryRowContext(ctx, countSongsQuery,
+	offset := (filter.Page - 1) * filter.PageSize
+	args := []any{
 		filter.GroupName,
 		filter.SongName,
 		filter.FromDate,
 		filter.ToDate,
 		filter.Text,
 		filter.Link,
-	).Scan(&totalItems)
-	if err != nil {
-		return nil, errors.NewInternal("failed to count songs", err)
 	}
 
-	// Вычисляем общее количество страниц
-	totalPages := (totalItems + filter.PageSize - 1) / filter.PageSize
+	// Счетчик и страница уходят на сервер одним пакетом за один сетевой проход
+	batch := &pgx.Batch{}
+	batch.Queue(countSongsQuery, args...)
+	batch.Queue(getSongsQuery, append(args, filter.PageSize, offset, !filter.WithoutText)...)
 
-	// Если запрошенная страница больше общего количества страниц, возвращаем ошибку
-	if filter.Page > totalPages {
-		return nil, errors.NewNotFound(fmt.Sprintf("page %d does not exist, total pages: %d", filter.Page, totalPages), nil)
-	}
+	ctx, span := r.startQuery(ctx, "getSongsBatch", getSongsQuery)
+	defer span.End()
 
-	offset := (filter.Page - 1) * filter.PageSize
+	results := r.db.SendBatch(ctx, batch)
+	defer results.Close()
 
-	// Получаем записи для текущей страницы
-	rows, err := r.db.QueryContext(ctx, getSongsQuery,
-		filter.GroupName,
-		filter.SongName,
-		filter.FromDate,
-		filter.ToDate,
-		filter.Text,
-		filter.Link,
-		filter.PageSize,
-		offset,
-	)
+	var totalItems int
+	if err := results.QueryRow().Scan(&totalItems); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count songs")
+	}
+
+	rows, err := results.Query()
 	if err != nil {
-		return nil, errors.NewInternal("failed to query songs", err)
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to query songs")
 	}
 	defer rows.Close()
 
@@ -89,10 +150,23 @@ func (r *PostgresSongRepository) GetSongs(ctx context.Context, filter *models.So
 			&song.UpdatedAt,
 		)
 		if err != nil {
-			return nil, errors.NewInternal("failed to scan song", err)
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan song")
 		}
 		songs = append(songs, song)
 	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to iterate songs")
+	}
+
+	// Вычисляем общее количество страниц
+	totalPages := (totalItems + filter.PageSize - 1) / filter.PageSize
+
+	// Если запрошенная страница больше общего количества страниц, возвращаем ошибку
+	if filter.Page > totalPages {
+		return nil, errors.NewNotFound(fmt.Sprintf("page %d does not exist, total pages: %d", filter.Page, totalPages), nil)
+	}
 
 	// Возвращаем список песен
 	return &models.SongsResponse{
@@ -106,8 +180,11 @@ func (r *PostgresSongRepository) GetSongs(ctx context.Context, filter *models.So
 
 // GetSongByID получает информацию о песне по ее ID
 func (r *PostgresSongRepository) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	ctx, span := r.startQuery(ctx, "getSongByIDQuery", getSongByIDQuery)
+	defer span.End()
+
 	var song models.Song
-	err := r.db.QueryRowContext(ctx, getSongByIDQuery, id).Scan(
+	err := r.db.QueryRow(ctx, getSongByIDQuery, id).Scan(
 		&song.ID,
 		&song.GroupName,
 		&song.SongName,
@@ -117,37 +194,97 @@ func (r *PostgresSongRepository) GetSongByID(ctx context.Context, id int) (*mode
 		&song.CreatedAt,
 		&song.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
+	if stderrors.Is(err, pgx.ErrNoRows) {
 		return nil, errors.NewNotFound("song not found", err)
 	}
 	if err != nil {
-		return nil, errors.NewInternal("failed to get song", err)
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get song")
 	}
 
 	return &song, nil
 }
 
-// CreateSong создает новую песню
+// CreateSong создает новую песню. Уникальность пары группа/песня проверяет
+// сама вставка через ON CONFLICT, поэтому параллельные запросы не дают 500.
 func (r *PostgresSongRepository) CreateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
-	// Проверяем, существует ли уже песня с такими данными
-	var exists bool
-	err := r.db.QueryRowContext(ctx, checkSongExistsForCreateQuery,
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	spanCtx, span := r.startQuery(ctx, "createSongQuery", createSongQuery)
+	err := r.db.QueryRow(spanCtx, createSongQuery,
 		song.GroupName,
 		song.SongName,
-	).Scan(&exists)
+		song.ReleaseDate,
+		song.Text,
+		song.Link,
+		key.Group,
+		key.Song,
+	).Scan(
+		&song.ID,
+		&song.GroupName,
+		&song.SongName,
+		&song.ReleaseDate,
+		&song.Text,
+		&song.Link,
+		&song.CreatedAt,
+		&song.UpdatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		// Строка не вставлена из-за конфликта: песня уже есть
+		span.End()
+		return nil, r.songExistsError(ctx, key, nil)
+	}
+	tracing.End(span, err)
 	if err != nil {
-		return nil, errors.NewInternal("failed to check song existence", err)
+		return nil, r.mapWriteError(ctx, err, song, "failed to create song")
+	}
+
+	return song, nil
+}
+
+// GetSongByKey получает песню по естественному ключу
+func (r *PostgresSongRepository) GetSongByKey(ctx context.Context, groupName, songName string) (*models.Song, error) {
+	key := NewNaturalKey(groupName, songName)
+	ctx, span := r.startQuery(ctx, "getSongByKeyQuery", getSongByKeyQuery)
+	defer span.End()
+
+	var song models.Song
+	err := r.db.QueryRow(ctx, getSongByKeyQuery, key.Group, key.Song).Scan(
+		&song.ID,
+		&song.GroupName,
+		&song.SongName,
+		&song.ReleaseDate,
+		&song.Text,
+		&song.Link,
+		&song.CreatedAt,
+		&song.UpdatedAt,
+	)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.NewNotFound("song not found", err)
 	}
-	if exists {
-		return nil, errors.NewAlreadyExists("song with this group name and song name already exists", nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get song")
 	}
 
-	err = r.db.QueryRowContext(ctx, createSongQuery,
+	return &song, nil
+}
+
+// UpsertSongByKey создает песню или заменяет существующую одним запросом
+// INSERT ... ON CONFLICT DO UPDATE, поэтому параллельные вызовы не создают дублей
+func (r *PostgresSongRepository) UpsertSongByKey(ctx context.Context, song *models.Song) (*models.Song, bool, error) {
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	ctx, span := r.startQuery(ctx, "upsertSongByKeyQuery", upsertSongByKeyQuery)
+	defer span.End()
+
+	var created bool
+	err := r.db.QueryRow(ctx, upsertSongByKeyQuery,
 		song.GroupName,
 		song.SongName,
 		song.ReleaseDate,
 		song.Text,
 		song.Link,
+		key.Group,
+		key.Song,
 	).Scan(
 		&song.ID,
 		&song.GroupName,
@@ -157,37 +294,101 @@ func (r *PostgresSongRepository) CreateSong(ctx context.Context, song *models.So
 		&song.Link,
 		&song.CreatedAt,
 		&song.UpdatedAt,
+		&created,
 	)
 	if err != nil {
-		return nil, errors.NewInternal("failed to create song", err)
+		tracing.RecordError(span, err)
+		return nil, false, mapError(err, "failed to upsert song")
 	}
 
-	return song, nil
+	return song, created, nil
 }
 
-// UpdateSong обновляет информацию о песне
-func (r *PostgresSongRepository) UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
-	// Проверяем, существует ли уже песня с такими данными
-	var exists bool
-	err := r.db.QueryRowContext(ctx, checkSongExistsQuery,
-		song.GroupName,
-		song.SongName,
-		song.ID,
-	).Scan(&exists)
+// CreateSongs массово добавляет песни через COPY во временную таблицу,
+// откуда переносит в songs только те, которых еще нет. События outbox
+// о добавленных песнях загружаются в той же транзакции.
+func (r *PostgresSongRepository) CreateSongs(ctx context.Context, songs []models.Song) (created int, err error) {
+	ctx, span := r.startQuery(ctx, "copySongsImport", insertImportedSongsQuery)
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return nil, errors.NewInternal("failed to check song existence", err)
+		return 0, mapError(err, "failed to begin transaction")
 	}
-	if exists {
-		return nil, errors.NewAlreadyExists("song with this group name and song name already exists", nil)
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, createSongsImportTableQuery); err != nil {
+		return 0, mapError(err, "failed to create import table")
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{songsImportTable}, songsImportColumns,
+		pgx.CopyFromSlice(len(songs), func(i int) ([]any, error) {
+			s := songs[i]
+			key := NewNaturalKey(s.GroupName, s.SongName)
+			return []any{s.GroupName, s.SongName, s.ReleaseDate, s.Text, s.Link, key.Group, key.Song}, nil
+		}),
+	)
+	if err != nil {
+		return 0, mapError(err, "failed to copy songs")
 	}
 
-	err = r.db.QueryRowContext(ctx, updateSongQuery,
+	rows, err := tx.Query(ctx, insertImportedSongsQuery)
+	if err != nil {
+		return 0, mapError(err, "failed to insert songs")
+	}
+	var outbox [][]any
+	for rows.Next() {
+		var song models.Song
+		err := rows.Scan(
+			&song.ID,
+			&song.GroupName,
+			&song.SongName,
+			&song.ReleaseDate,
+			&song.Text,
+			&song.Link,
+			&song.CreatedAt,
+			&song.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return 0, mapError(err, "failed to scan song")
+		}
+		event, err := NewOutboxEvent(events.SongCreated, &song)
+		if err != nil {
+			rows.Close()
+			return 0, errors.NewInternal("failed to encode song event", err)
+		}
+		outbox = append(outbox, []any{event.Type, event.SongID, string(event.Payload)})
+	}
+	if err := rows.Err(); err != nil {
+		return 0, mapError(err, "failed to insert songs")
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"outbox_events"}, outboxEventColumns, pgx.CopyFromRows(outbox)); err != nil {
+		return 0, mapError(err, "failed to save song events")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, mapError(err, "failed to commit songs")
+	}
+	return len(outbox), nil
+}
+
+// UpdateSong обновляет информацию о песне
+func (r *PostgresSongRepository) UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	spanCtx, span := r.startQuery(ctx, "updateSongQuery", updateSongQuery)
+	defer span.End()
+
+	err := r.db.QueryRow(spanCtx, updateSongQuery,
 		song.GroupName,
 		song.SongName,
 		song.ReleaseDate,
 		song.Text,
 		song.Link,
 		song.ID,
+		key.Group,
+		key.Song,
 	).Scan(
 		&song.ID,
 		&song.GroupName,
@@ -198,11 +399,12 @@ func (r *PostgresSongRepository) UpdateSong(ctx context.Context, song *models.So
 		&song.CreatedAt,
 		&song.UpdatedAt,
 	)
-	if err == sql.ErrNoRows {
+	if stderrors.Is(err, pgx.ErrNoRows) {
 		return nil, errors.NewNotFound("song not found", err)
 	}
 	if err != nil {
-		return nil, errors.NewInternal("failed to update song", err)
+		tracing.RecordError(span, err)
+		return nil, r.mapWriteError(ctx, err, song, "failed to update song")
 	}
 
 	return song, nil
@@ -210,19 +412,312 @@ func (r *PostgresSongRepository) UpdateSong(ctx context.Context, song *models.So
 
 // DeleteSong удаляет песню
 func (r *PostgresSongRepository) DeleteSong(ctx context.Context, id int) error {
-	result, err := r.db.ExecContext(ctx, deleteSongQuery, id)
+	ctx, span := r.startQuery(ctx, "deleteSongQuery", deleteSongQuery)
+	defer span.End()
+
+	tag, err := r.db.Exec(ctx, deleteSongQuery, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapError(err, "failed to delete song")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFound("song not found", nil)
+	}
+
+	return nil
+}
+
+// GetGroup получает сведения о группе. Песни группы ищутся по естественному ключу,
+// поэтому регистр и лишние пробелы в названии не важны.
+func (r *PostgresSongRepository) GetGroup(ctx context.Context, groupName string) (*models.GroupDetails, error) {
+	key := NewNaturalKey(groupName, "")
+	ctx, span := r.startQuery(ctx, "getGroupSongCountQuery", getGroupSongCountQuery)
+	defer span.End()
+
+	group := &models.GroupDetails{Name: groupName}
+	if err := r.db.QueryRow(ctx, getGroupSongCountQuery, key.Group).Scan(&group.SongCount); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get group")
+	}
+	if group.SongCount == 0 {
+		return nil, errors.NewNotFound("group not found", nil)
+	}
+
+	return group, nil
+}
+
+// CreateSongRevision сохраняет версию песни
+func (r *PostgresSongRepository) CreateSongRevision(ctx context.Context, song *models.Song) error {
+	ctx, span := r.startQuery(ctx, "createSongRevisionQuery", createSongRevisionQuery)
+	defer span.End()
+
+	_, err := r.db.Exec(ctx, createSongRevisionQuery,
+		song.ID,
+		song.GroupName,
+		song.SongName,
+		song.ReleaseDate,
+		song.Text,
+		song.Link,
+	)
 	if err != nil {
-		return errors.NewInternal("failed to delete song", err)
+		tracing.RecordError(span, err)
+		return mapError(err, "failed to save song revision")
+	}
+
+	return nil
+}
+
+// CountSongRevisions считает версии песни
+func (r *PostgresSongRepository) CountSongRevisions(ctx context.Context, songID int) (int, error) {
+	ctx, span := r.startQuery(ctx, "countSongRevisionsQuery", countSongRevisionsQuery)
+	defer span.End()
+
+	var count int
+	if err := r.db.QueryRow(ctx, countSongRevisionsQuery, songID).Scan(&count); err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapError(err, "failed to count song revisions")
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	return count, nil
+}
+
+// CountSongsByGroup считает песни групп по естественному ключу
+func (r *PostgresSongRepository) CountSongsByGroup(ctx context.Context, groupNames []string) (map[string]int, error) {
+	ctx, span := r.startQuery(ctx, "countSongsByGroupQuery", countSongsByGroupQuery)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, countSongsByGroupQuery, groupKeys(groupNames))
 	if err != nil {
-		return errors.NewInternal("failed to get rows affected", err)
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count group songs")
 	}
+	defer rows.Close()
 
-	if rowsAffected == 0 {
-		return errors.NewNotFound("song not found", nil)
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan group song count")
+		}
+		counts[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count group songs")
+	}
+
+	return counts, nil
+}
+
+// CountRevisionsBySong считает версии песен
+func (r *PostgresSongRepository) CountRevisionsBySong(ctx context.Context, songIDs []int) (map[int]int, error) {
+	ctx, span := r.startQuery(ctx, "countRevisionsBySongQuery", countRevisionsBySongQuery)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, countRevisionsBySongQuery, songIDs)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count song revisions")
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan song revision count")
+		}
+		counts[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count song revisions")
+	}
+
+	return counts, nil
+}
+
+// CreateOutboxEvent записывает событие об изменении песни в outbox
+func (r *PostgresSongRepository) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, span := r.startQuery(ctx, "createOutboxEventQuery", createOutboxEventQuery)
+	defer span.End()
+
+	err := r.db.QueryRow(ctx, createOutboxEventQuery, event.Type, event.SongID, string(event.Payload)).
+		Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapError(err, "failed to save song event")
 	}
 
 	return nil
 }
+
+// GetOutboxEvents возвращает события после afterID
+func (r *PostgresSongRepository) GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	ctx, span := r.startQuery(ctx, "getOutboxEventsQuery", getOutboxEventsQuery)
+	defer span.End()
+
+	rows, err := r.db.Query(ctx, getOutboxEventsQuery, afterID, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get song events")
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.Type, &event.SongID, &payload, &event.CreatedAt); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan song event")
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get song events")
+	}
+
+	return events, nil
+}
+
+// GetLastOutboxEventID возвращает ID последнего события
+func (r *PostgresSongRepository) GetLastOutboxEventID(ctx context.Context) (int64, error) {
+	ctx, span := r.startQuery(ctx, "getLastOutboxEventIDQuery", getLastOutboxEventIDQuery)
+	defer span.End()
+
+	var id int64
+	if err := r.db.QueryRow(ctx, getLastOutboxEventIDQuery).Scan(&id); err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapError(err, "failed to get last song event")
+	}
+	return id, nil
+}
+
+// GetSongChanges возвращает изменения песен после since. Песни и удаления
+// читаются одним пакетом и сливаются по номеру изменения.
+func (r *PostgresSongRepository) GetSongChanges(ctx context.Context, since models.SyncPosition, limit int) ([]models.SongChange, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(getChangedSongsQuery, since.Tx, since.Seq, limit)
+	batch.Queue(getSongTombstonesQuery, since.Tx, since.Seq, limit)
+
+	ctx, span := r.startQuery(ctx, "getSongChangesBatch", getChangedSongsQuery)
+	defer span.End()
+
+	results := r.db.SendBatch(ctx, batch)
+	defer results.Close()
+
+	rows, err := results.Query()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get changed songs")
+	}
+	var changes []models.SongChange
+	for rows.Next() {
+		var change models.SongChange
+		var song models.Song
+		err := rows.Scan(
+			&change.Position.Tx,
+			&change.Position.Seq,
+			&song.ID,
+			&song.GroupName,
+			&song.SongName,
+			&song.ReleaseDate,
+			&song.Text,
+			&song.Link,
+			&song.CreatedAt,
+			&song.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan song")
+		}
+		change.SongID, change.Song = song.ID, &song
+		changes = append(changes, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get changed songs")
+	}
+
+	rows, err = results.Query()
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get deleted songs")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var change models.SongChange
+		if err := rows.Scan(&change.Position.Tx, &change.Position.Seq, &change.SongID); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan deleted song")
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get deleted songs")
+	}
+
+	return firstSongChanges(changes, limit), nil
+}
+
+// GetSyncBounds возвращает границы последовательности изменений
+func (r *PostgresSongRepository) GetSyncBounds(ctx context.Context) (models.SyncBounds, error) {
+	ctx, span := r.startQuery(ctx, "getSyncBoundsQuery", getSyncBoundsQuery)
+	defer span.End()
+
+	var bounds models.SyncBounds
+	if err := r.db.QueryRow(ctx, getSyncBoundsQuery).Scan(&bounds.Horizon.Tx, &bounds.Horizon.Seq, &bounds.Latest); err != nil {
+		tracing.RecordError(span, err)
+		return bounds, mapError(err, "failed to get sync bounds")
+	}
+	return bounds, nil
+}
+
+// PruneTombstones забывает старые удаления песен одним запросом
+func (r *PostgresSongRepository) PruneTombstones(ctx context.Context, before time.Time) (int, error) {
+	ctx, span := r.startQuery(ctx, "pruneSongTombstonesQuery", pruneSongTombstonesQuery)
+	defer span.End()
+
+	var pruned int
+	if err := r.db.QueryRow(ctx, pruneSongTombstonesQuery, before).Scan(&pruned); err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapError(err, "failed to prune song tombstones")
+	}
+	return pruned, nil
+}
+
+// mapWriteError переводит ошибку записи песни в ошибку приложения.
+// При нарушении уникальности в ответ добавляется ID уже существующей песни.
+func (r *PostgresSongRepository) mapWriteError(ctx context.Context, err error, song *models.Song, message string) error {
+	mapped := mapError(err, message)
+	if appErr, ok := errors.As(mapped); ok && appErr.Type == errors.AlreadyExists {
+		return r.songExistsError(ctx, NewNaturalKey(song.GroupName, song.SongName), err)
+	}
+	return mapped
+}
+
+// songExistsError собирает AlreadyExists с ID песни, занявшей естественный ключ.
+// Если песню успели удалить, ошибка возвращается без ID.
+func (r *PostgresSongRepository) songExistsError(ctx context.Context, key NaturalKey, cause error) error {
+	ctx, span := r.startQuery(ctx, "getSongIDByKeyQuery", getSongIDByKeyQuery)
+	defer span.End()
+
+	// Запрос идет мимо транзакции: после нарушения ограничения она уже прервана
+	var id int
+	err := r.pool.QueryRow(ctx, getSongIDByKeyQuery, key.Group, key.Song).Scan(&id)
+	if err != nil && !stderrors.Is(err, pgx.ErrNoRows) {
+		tracing.RecordError(span, err)
+		r.logger.Warn("Failed to look up conflicting song", zap.Error(err))
+	}
+	return errors.NewAlreadyExistsWithID(songExistsMessage, id, cause)
+}
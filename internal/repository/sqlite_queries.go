@@ -0,0 +1,261 @@
+package repository
+
+// Запросы SQLiteSongRepository. Параметры нумерованные (?1), строки фильтров
+// приходят уже приведенными к нижнему регистру и обернутыми в %, см. containsPattern.
+const (
+	// sqliteSongFilter условия фильтра списка песен. Текст ищется через полнотекстовый
+	// индекс songs_fts, в нем LIKE без ESCAPE, иначе SQLite не использует индекс
+	sqliteSongFilter = `
+		WHERE (?1 = '' OR fold(group_name) LIKE ?1 ESCAPE '\')
+		AND (?2 = '' OR fold(song_name) LIKE ?2 ESCAPE '\')
+		AND (?3 IS NULL OR release_date || 'T00:00:00.000000Z' >= ?3)
+		AND (?4 IS NULL OR release_date || 'T00:00:00.000000Z' <= ?4)
+		AND (?5 = '' OR id IN (SELECT rowid FROM songs_fts WHERE songs_fts.text LIKE ?5))
+		AND (?6 = '' OR fold(link) LIKE ?6 ESCAPE '\')`
+
+	// sqliteGetSongsQuery получить страницу песен, при ?9 = false текст не читается
+	sqliteGetSongsQuery = `
+		SELECT id, group_name, song_name, release_date, CASE WHEN ?9 THEN text ELSE '' END, link, created_at, updated_at
+		FROM songs` + sqliteSongFilter + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?7 OFFSET ?8`
+
+	// sqliteCountSongsQuery количество песен с фильтрами
+	sqliteCountSongsQuery = `SELECT COUNT(*) FROM songs` + sqliteSongFilter
+
+	// sqliteGetSongByIDQuery получить песню по id
+	sqliteGetSongByIDQuery = `
+		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM songs
+		WHERE id = ?1`
+
+	// sqliteCreateSongQuery создать песню, при конфликте ключа строка не возвращается
+	sqliteCreateSongQuery = `
+		INSERT INTO songs (group_name, song_name, release_date, text, link, created_at, updated_at, group_key, song_key)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7, ?8)
+		ON CONFLICT (group_key, song_key) DO NOTHING
+		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at`
+
+	// sqliteGetSongByKeyQuery получить песню по естественному ключу
+	sqliteGetSongByKeyQuery = `
+		SELECT id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM songs
+		WHERE group_key = ?1
+		AND song_key = ?2`
+
+	// sqliteGetSongIDByKeyQuery найти ID песни по естественному ключу
+	sqliteGetSongIDByKeyQuery = `SELECT id FROM songs WHERE group_key = ?1 AND song_key = ?2`
+
+	// sqliteUpsertSongByKeyQuery создать песню или заменить существующую с тем же ключом.
+	// Дата выпуска и created_at при замене сохраняются.
+	sqliteUpsertSongByKeyQuery = `
+		INSERT INTO songs (group_name, song_name, release_date, text, link, created_at, updated_at, group_key, song_key)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7, ?8)
+		ON CONFLICT (group_key, song_key) DO UPDATE
+		SET group_name = excluded.group_name,
+			song_name = excluded.song_name,
+			text = excluded.text,
+			link = excluded.link,
+			updated_at = excluded.updated_at
+		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at`
+
+	// sqliteUpdateSongQuery обновить песню
+	sqliteUpdateSongQuery = `
+		UPDATE songs
+		SET group_name = ?1,
+			song_name = ?2,
+			release_date = ?3,
+			text = ?4,
+			link = ?5,
+			updated_at = ?6,
+			group_key = ?7,
+			song_key = ?8
+		WHERE id = ?9
+		RETURNING id, group_name, song_name, release_date, text, link, created_at, updated_at`
+
+	// sqliteDeleteSongQuery удалить песню, версии удаляются каскадом
+	sqliteDeleteSongQuery = `DELETE FROM songs WHERE id = ?1`
+
+	// sqliteGetGroupSongCountQuery количество песен группы по нормализованному названию
+	sqliteGetGroupSongCountQuery = `SELECT COUNT(*) FROM songs WHERE group_key = ?1`
+
+	// sqliteCreateSongRevisionQuery сохранить текущую версию песни следующим номером
+	sqliteCreateSongRevisionQuery = `
+		INSERT INTO song_revisions (song_id, revision, group_name, song_name, release_date, text, link, created_at)
+		SELECT ?1, COALESCE(MAX(revision), 0) + 1, ?2, ?3, ?4, ?5, ?6, ?7
+		FROM song_revisions
+		WHERE song_id = ?1`
+
+	// sqliteCountSongRevisionsQuery количество версий песни
+	sqliteCountSongRevisionsQuery = `SELECT COUNT(*) FROM song_revisions WHERE song_id = ?1`
+
+	// sqliteCreateOutboxEventQuery записать событие об изменении песни
+	sqliteCreateOutboxEventQuery = `
+		INSERT INTO outbox_events (type, song_id, payload, created_at)
+		VALUES (?1, ?2, ?3, ?4)
+		RETURNING id`
+
+	// sqliteGetOutboxEventsQuery события после заданного ID по порядку
+	sqliteGetOutboxEventsQuery = `
+		SELECT id, type, song_id, payload, created_at
+		FROM outbox_events
+		WHERE id > ?1
+		ORDER BY id
+		LIMIT ?2`
+
+	// sqliteGetLastOutboxEventIDQuery ID последнего события, 0 если событий нет
+	sqliteGetLastOutboxEventIDQuery = `SELECT COALESCE(MAX(id), 0) FROM outbox_events`
+
+	// sqliteCountSongsByGroupQuery количество песен групп, ?1 - JSON массив нормализованных названий
+	sqliteCountSongsByGroupQuery = `
+		SELECT group_key, COUNT(*)
+		FROM songs
+		WHERE group_key IN (SELECT value FROM json_each(?1))
+		GROUP BY group_key`
+
+	// sqliteCountRevisionsBySongQuery количество версий песен, ?1 - JSON массив ID
+	sqliteCountRevisionsBySongQuery = `
+		SELECT song_id, COUNT(*)
+		FROM song_revisions
+		WHERE song_id IN (SELECT value FROM json_each(?1))
+		GROUP BY song_id`
+
+	// sqliteGetChangedSongsQuery песни, измененные после ?1, по порядку изменений
+	sqliteGetChangedSongsQuery = `
+		SELECT change_seq, id, group_name, song_name, release_date, text, link, created_at, updated_at
+		FROM songs
+		WHERE change_seq > ?1
+		ORDER BY change_seq
+		LIMIT ?2`
+
+	// sqliteGetSongTombstonesQuery удаления песен после ?1 по порядку изменений
+	sqliteGetSongTombstonesQuery = `
+		SELECT change_seq, song_id
+		FROM song_tombstones
+		WHERE change_seq > ?1
+		ORDER BY change_seq
+		LIMIT ?2`
+
+	// sqliteGetSyncBoundsQuery горизонт синхронизации и последний выданный номер изменения
+	sqliteGetSyncBoundsQuery = `SELECT horizon, last_seq FROM sync_state`
+
+	// sqliteRaiseSyncHorizonQuery сдвигает горизонт на последнее удаление до ?1,
+	// выполняется перед sqlitePruneSongTombstonesQuery в той же транзакции
+	sqliteRaiseSyncHorizonQuery = `
+		UPDATE sync_state
+		SET horizon = MAX(horizon, COALESCE((SELECT MAX(change_seq) FROM song_tombstones WHERE deleted_at < ?1), 0))`
+
+	// sqlitePruneSongTombstonesQuery удаляет записи об удалениях песен до ?1
+	sqlitePruneSongTombstonesQuery = `DELETE FROM song_tombstones WHERE deleted_at < ?1`
+
+	// sqliteWebhookMatchesEvent вебхук w подписан на вид события e, пустой список - на все
+	sqliteWebhookMatchesEvent = `(w.event_types = '' OR instr(',' || w.event_types || ',', ',' || e.type || ',') > 0)`
+
+	// sqliteCreateWebhookQuery зарегистрировать вебхук
+	sqliteCreateWebhookQuery = `
+		INSERT INTO webhooks (url, secret, event_types, created_at)
+		VALUES (?1, ?2, ?3, ?4)
+		RETURNING id`
+
+	// sqliteGetWebhooksQuery все вебхуки по порядку регистрации
+	sqliteGetWebhooksQuery = `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhooks
+		ORDER BY id`
+
+	// sqliteGetWebhookByIDQuery получить вебхук по id
+	sqliteGetWebhookByIDQuery = `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhooks
+		WHERE id = ?1`
+
+	// sqliteDeleteWebhookQuery удалить вебхук, доставки удаляются каскадом
+	sqliteDeleteWebhookQuery = `DELETE FROM webhooks WHERE id = ?1`
+
+	// sqliteLastUndispatchedEventQuery ID последнего из первых ?1 еще не разосланных событий
+	sqliteLastUndispatchedEventQuery = `
+		SELECT MAX(id) FROM (
+			SELECT id FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY id
+			LIMIT ?1
+		)`
+
+	// sqliteEnqueueDeliveriesQuery создать доставки не разосланных событий до ?1 включительно
+	sqliteEnqueueDeliveriesQuery = `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at, created_at, updated_at)
+		SELECT w.id, e.id, ?2, ?2, ?2
+		FROM outbox_events e
+		JOIN webhooks w ON ` + sqliteWebhookMatchesEvent + `
+		WHERE e.dispatched_at IS NULL
+		AND e.id <= ?1`
+
+	// sqliteMarkEventsDispatchedQuery отметить события до ?1 включительно разосланными
+	sqliteMarkEventsDispatchedQuery = `
+		UPDATE outbox_events
+		SET dispatched_at = ?2
+		WHERE dispatched_at IS NULL
+		AND id <= ?1`
+
+	// sqliteClaimDeliveriesQuery до ?2 доставок, время которых пришло к ?1
+	sqliteClaimDeliveriesQuery = `
+		SELECT d.id, d.attempts, w.id, w.url, w.secret, w.event_types, w.created_at,
+			e.id, e.type, e.song_id, e.payload, e.created_at
+		FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id
+		JOIN outbox_events e ON e.id = d.event_id
+		WHERE d.status = 'pending'
+		AND d.next_attempt_at <= ?1
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?2`
+
+	// sqliteLeaseDeliveryQuery считает попытку и откладывает следующую до ?2
+	// на случай, если диспетчер не сообщит итог
+	sqliteLeaseDeliveryQuery = `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+			next_attempt_at = ?2,
+			updated_at = ?3
+		WHERE id = ?1`
+
+	// sqliteCompleteDeliveryQuery сохранить итог попытки доставки,
+	// время следующей попытки меняется только для повтора
+	sqliteCompleteDeliveryQuery = `
+		UPDATE webhook_deliveries
+		SET status = ?2,
+			last_status_code = NULLIF(?3, 0),
+			last_error = NULLIF(?4, ''),
+			next_attempt_at = COALESCE(?5, next_attempt_at),
+			delivered_at = CASE WHEN ?2 = 'delivered' THEN ?6 ELSE delivered_at END,
+			updated_at = ?6
+		WHERE id = ?1`
+
+	// sqliteDeliveriesFilter условия журнала доставок вебхука, пустой ?2 - все статусы
+	sqliteDeliveriesFilter = `
+		WHERE d.webhook_id = ?1
+		AND (?2 = '' OR d.status = ?2)`
+
+	// sqliteGetDeliveriesQuery страница журнала доставок, новые первыми
+	sqliteGetDeliveriesQuery = `
+		SELECT d.id, d.webhook_id, d.event_id, e.type, d.status, d.attempts, d.next_attempt_at,
+			COALESCE(d.last_status_code, 0), COALESCE(d.last_error, ''), d.delivered_at, d.created_at, d.updated_at
+		FROM webhook_deliveries d
+		JOIN outbox_events e ON e.id = d.event_id` + sqliteDeliveriesFilter + `
+		ORDER BY d.id DESC
+		LIMIT ?3 OFFSET ?4`
+
+	// sqliteCountDeliveriesQuery количество доставок в журнале
+	sqliteCountDeliveriesQuery = `SELECT COUNT(*) FROM webhook_deliveries d` + sqliteDeliveriesFilter
+
+	// sqliteReplayDeliveriesQuery заново поставить в очередь доставку вебхуку ?1
+	// разосланных событий начиная с ?2
+	sqliteReplayDeliveriesQuery = `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, next_attempt_at, created_at, updated_at)
+		SELECT w.id, e.id, ?3, ?3, ?3
+		FROM webhooks w
+		JOIN outbox_events e ON e.id >= ?2
+			AND e.dispatched_at IS NOT NULL
+			AND ` + sqliteWebhookMatchesEvent + `
+		WHERE w.id = ?1
+		ORDER BY e.id`
+)
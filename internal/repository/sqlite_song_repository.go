@@ -0,0 +1,799 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+)
+
+// Форматы дат в SQLite. Метка времени фиксированной ширины, чтобы строки
+// сортировались так же, как время
+const (
+	sqliteDateLayout      = "2006-01-02"
+	sqliteTimestampLayout = "2006-01-02T15:04:05.000000Z"
+)
+
+func init() {
+	// fold приводит строку к нижнему регистру по правилам Unicode, встроенные
+	// lower() и LIKE в SQLite понимают только ASCII. Используется в запросах
+	// и триггерах полнотекстового индекса songs_fts.
+	sqlite.MustRegisterDeterministicScalarFunction("fold", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case []byte:
+				return strings.ToLower(string(v)), nil
+			}
+			return args[0], nil
+		})
+}
+
+// sqlQuerier общие методы *sql.DB и *sql.Tx, через которые работает репозиторий
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rowScanner общий метод *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// SQLiteSongRepository хранит песни в файле SQLite через драйвер modernc.org/sqlite
+// без cgo. Повторяет поведение PostgresSongRepository, фильтр по тексту
+// использует полнотекстовый индекс FTS5.
+type SQLiteSongRepository struct {
+	sqlDB *sql.DB
+	// db база или открытая транзакция, через которые выполняются запросы
+	db        sqlQuerier
+	logger    *zap.Logger
+	txOptions TxOptions
+	// inTx репозиторий привязан к транзакции WithinTx
+	inTx bool
+}
+
+// NewSQLiteSongRepository создает репозиторий. SQLite всегда выполняет транзакции
+// последовательно, поэтому из opts учитывается только количество повторов.
+func NewSQLiteSongRepository(db *sql.DB, logger *zap.Logger, opts ...TxOption) SongRepository {
+	txOptions := defaultTxOptions()
+	for _, opt := range opts {
+		opt(&txOptions)
+	}
+	return &SQLiteSongRepository{
+		sqlDB:     db,
+		db:        db,
+		logger:    logger,
+		txOptions: txOptions,
+	}
+}
+
+// GetSongs получает список песен
+func (r *SQLiteSongRepository) GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error) {
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+
+	offset := (filter.Page - 1) * filter.PageSize
+	args := []any{
+		containsPattern(filter.GroupName),
+		containsPattern(filter.SongName),
+		timestampArg(filter.FromDate),
+		timestampArg(filter.ToDate),
+		containsPattern(filter.Text),
+		containsPattern(filter.Link),
+	}
+
+	ctx, span := r.startQuery(ctx, "getSongsQuery", sqliteGetSongsQuery)
+	defer span.End()
+
+	var totalItems int
+	if err := r.db.QueryRowContext(ctx, sqliteCountSongsQuery, args...).Scan(&totalItems); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count songs")
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetSongsQuery, append(args, filter.PageSize, offset, !filter.WithoutText)...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to query songs")
+	}
+	defer rows.Close()
+
+	songs := make([]models.Song, 0)
+	for rows.Next() {
+		var song models.Song
+		if err := scanSQLiteSong(rows, &song); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan song")
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to iterate songs")
+	}
+
+	totalPages := (totalItems + filter.PageSize - 1) / filter.PageSize
+	if filter.Page > totalPages {
+		return nil, errors.NewNotFound(fmt.Sprintf("page %d does not exist, total pages: %d", filter.Page, totalPages), nil)
+	}
+
+	return &models.SongsResponse{
+		Songs:       songs,
+		CurrentPage: filter.Page,
+		TotalPages:  totalPages,
+		TotalItems:  totalItems,
+		PageSize:    filter.PageSize,
+	}, nil
+}
+
+// GetSongByID получает информацию о песне по ее ID
+func (r *SQLiteSongRepository) GetSongByID(ctx context.Context, id int) (*models.Song, error) {
+	ctx, span := r.startQuery(ctx, "getSongByIDQuery", sqliteGetSongByIDQuery)
+	defer span.End()
+
+	var song models.Song
+	err := scanSQLiteSong(r.db.QueryRowContext(ctx, sqliteGetSongByIDQuery, id), &song)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFound("song not found", err)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get song")
+	}
+
+	return &song, nil
+}
+
+// CreateSong создает новую песню, уникальность ключа проверяет ON CONFLICT
+func (r *SQLiteSongRepository) CreateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	spanCtx, span := r.startQuery(ctx, "createSongQuery", sqliteCreateSongQuery)
+	defer span.End()
+
+	err := scanSQLiteSong(r.db.QueryRowContext(spanCtx, sqliteCreateSongQuery,
+		song.GroupName,
+		song.SongName,
+		song.ReleaseDate.Format(sqliteDateLayout),
+		song.Text,
+		song.Link,
+		sqliteNow(),
+		key.Group,
+		key.Song,
+	), song)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		// Строка не вставлена из-за конфликта: песня уже есть
+		return nil, r.songExistsError(ctx, key, nil)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, r.mapWriteError(ctx, err, song, "failed to create song")
+	}
+
+	return song, nil
+}
+
+// GetSongByKey получает песню по естественному ключу
+func (r *SQLiteSongRepository) GetSongByKey(ctx context.Context, groupName, songName string) (*models.Song, error) {
+	key := NewNaturalKey(groupName, songName)
+	ctx, span := r.startQuery(ctx, "getSongByKeyQuery", sqliteGetSongByKeyQuery)
+	defer span.End()
+
+	var song models.Song
+	err := scanSQLiteSong(r.db.QueryRowContext(ctx, sqliteGetSongByKeyQuery, key.Group, key.Song), &song)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFound("song not found", err)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get song")
+	}
+
+	return &song, nil
+}
+
+// UpsertSongByKey создает песню или заменяет существующую. SQLite не сообщает,
+// была ли строка вставлена, поэтому ключ проверяется в той же транзакции.
+func (r *SQLiteSongRepository) UpsertSongByKey(ctx context.Context, song *models.Song) (*models.Song, bool, error) {
+	key := NewNaturalKey(song.GroupName, song.SongName)
+
+	var created bool
+	err := r.WithinTx(ctx, func(repo SongRepository) error {
+		tx := repo.(*SQLiteSongRepository)
+		ctx, span := tx.startQuery(ctx, "upsertSongByKeyQuery", sqliteUpsertSongByKeyQuery)
+		defer span.End()
+
+		var id int
+		err := tx.db.QueryRowContext(ctx, sqliteGetSongIDByKeyQuery, key.Group, key.Song).Scan(&id)
+		if err != nil && !stderrors.Is(err, sql.ErrNoRows) {
+			tracing.RecordError(span, err)
+			return mapSQLiteError(err, "failed to upsert song")
+		}
+		created = stderrors.Is(err, sql.ErrNoRows)
+
+		err = scanSQLiteSong(tx.db.QueryRowContext(ctx, sqliteUpsertSongByKeyQuery,
+			song.GroupName,
+			song.SongName,
+			song.ReleaseDate.Format(sqliteDateLayout),
+			song.Text,
+			song.Link,
+			sqliteNow(),
+			key.Group,
+			key.Song,
+		), song)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return mapSQLiteError(err, "failed to upsert song")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return song, created, nil
+}
+
+// CreateSongs массово добавляет песни в одной транзакции подготовленным выражением,
+// уже существующие пропускает, сохраняет первую версию каждой добавленной
+// и событие о ней в outbox
+func (r *SQLiteSongRepository) CreateSongs(ctx context.Context, songs []models.Song) (created int, err error) {
+	ctx, span := r.startQuery(ctx, "createSongsQuery", sqliteCreateSongQuery)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.WithinTx(ctx, func(repo SongRepository) error {
+		tx := repo.(*SQLiteSongRepository)
+		created = 0
+		for i := range songs {
+			song := songs[i]
+			key := NewNaturalKey(song.GroupName, song.SongName)
+			err := scanSQLiteSong(tx.db.QueryRowContext(ctx, sqliteCreateSongQuery,
+				song.GroupName,
+				song.SongName,
+				song.ReleaseDate.Format(sqliteDateLayout),
+				song.Text,
+				song.Link,
+				sqliteNow(),
+				key.Group,
+				key.Song,
+			), &song)
+			if stderrors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return mapSQLiteError(err, "failed to insert songs")
+			}
+			if err := tx.CreateSongRevision(ctx, &song); err != nil {
+				return err
+			}
+			event, err := NewOutboxEvent(events.SongCreated, &song)
+			if err != nil {
+				return errors.NewInternal("failed to encode song event", err)
+			}
+			if err := tx.CreateOutboxEvent(ctx, event); err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// UpdateSong обновляет информацию о песне
+func (r *SQLiteSongRepository) UpdateSong(ctx context.Context, song *models.Song) (*models.Song, error) {
+	key := NewNaturalKey(song.GroupName, song.SongName)
+	spanCtx, span := r.startQuery(ctx, "updateSongQuery", sqliteUpdateSongQuery)
+	defer span.End()
+
+	err := scanSQLiteSong(r.db.QueryRowContext(spanCtx, sqliteUpdateSongQuery,
+		song.GroupName,
+		song.SongName,
+		song.ReleaseDate.Format(sqliteDateLayout),
+		song.Text,
+		song.Link,
+		sqliteNow(),
+		key.Group,
+		key.Song,
+		song.ID,
+	), song)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFound("song not found", err)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, r.mapWriteError(ctx, err, song, "failed to update song")
+	}
+
+	return song, nil
+}
+
+// DeleteSong удаляет песню
+func (r *SQLiteSongRepository) DeleteSong(ctx context.Context, id int) error {
+	ctx, span := r.startQuery(ctx, "deleteSongQuery", sqliteDeleteSongQuery)
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, sqliteDeleteSongQuery, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapSQLiteError(err, "failed to delete song")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return mapSQLiteError(err, "failed to delete song")
+	}
+	if affected == 0 {
+		return errors.NewNotFound("song not found", nil)
+	}
+
+	return nil
+}
+
+// GetGroup получает сведения о группе по естественному ключу
+func (r *SQLiteSongRepository) GetGroup(ctx context.Context, groupName string) (*models.GroupDetails, error) {
+	key := NewNaturalKey(groupName, "")
+	ctx, span := r.startQuery(ctx, "getGroupSongCountQuery", sqliteGetGroupSongCountQuery)
+	defer span.End()
+
+	group := &models.GroupDetails{Name: groupName}
+	if err := r.db.QueryRowContext(ctx, sqliteGetGroupSongCountQuery, key.Group).Scan(&group.SongCount); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get group")
+	}
+	if group.SongCount == 0 {
+		return nil, errors.NewNotFound("group not found", nil)
+	}
+
+	return group, nil
+}
+
+// CreateSongRevision сохраняет версию песни
+func (r *SQLiteSongRepository) CreateSongRevision(ctx context.Context, song *models.Song) error {
+	ctx, span := r.startQuery(ctx, "createSongRevisionQuery", sqliteCreateSongRevisionQuery)
+	defer span.End()
+
+	_, err := r.db.ExecContext(ctx, sqliteCreateSongRevisionQuery,
+		song.ID,
+		song.GroupName,
+		song.SongName,
+		song.ReleaseDate.Format(sqliteDateLayout),
+		song.Text,
+		song.Link,
+		sqliteNow(),
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapSQLiteError(err, "failed to save song revision")
+	}
+
+	return nil
+}
+
+// CountSongRevisions считает версии песни
+func (r *SQLiteSongRepository) CountSongRevisions(ctx context.Context, songID int) (int, error) {
+	ctx, span := r.startQuery(ctx, "countSongRevisionsQuery", sqliteCountSongRevisionsQuery)
+	defer span.End()
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, sqliteCountSongRevisionsQuery, songID).Scan(&count); err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapSQLiteError(err, "failed to count song revisions")
+	}
+
+	return count, nil
+}
+
+// CountSongsByGroup считает песни групп по естественному ключу
+func (r *SQLiteSongRepository) CountSongsByGroup(ctx context.Context, groupNames []string) (map[string]int, error) {
+	ctx, span := r.startQuery(ctx, "countSongsByGroupQuery", sqliteCountSongsByGroupQuery)
+	defer span.End()
+
+	keys, err := json.Marshal(groupKeys(groupNames))
+	if err != nil {
+		return nil, errors.NewInternal("failed to encode group names", err)
+	}
+	rows, err := r.db.QueryContext(ctx, sqliteCountSongsByGroupQuery, string(keys))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count group songs")
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan group song count")
+		}
+		counts[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count group songs")
+	}
+
+	return counts, nil
+}
+
+// CountRevisionsBySong считает версии песен
+func (r *SQLiteSongRepository) CountRevisionsBySong(ctx context.Context, songIDs []int) (map[int]int, error) {
+	ctx, span := r.startQuery(ctx, "countRevisionsBySongQuery", sqliteCountRevisionsBySongQuery)
+	defer span.End()
+
+	ids, err := json.Marshal(songIDs)
+	if err != nil {
+		return nil, errors.NewInternal("failed to encode song IDs", err)
+	}
+	rows, err := r.db.QueryContext(ctx, sqliteCountRevisionsBySongQuery, string(ids))
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count song revisions")
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan song revision count")
+		}
+		counts[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count song revisions")
+	}
+
+	return counts, nil
+}
+
+// CreateOutboxEvent записывает событие об изменении песни в outbox
+func (r *SQLiteSongRepository) CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	ctx, span := r.startQuery(ctx, "createOutboxEventQuery", sqliteCreateOutboxEventQuery)
+	defer span.End()
+
+	createdAt := now()
+	err := r.db.QueryRowContext(ctx, sqliteCreateOutboxEventQuery,
+		event.Type,
+		event.SongID,
+		string(event.Payload),
+		createdAt.Format(sqliteTimestampLayout),
+	).Scan(&event.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapSQLiteError(err, "failed to save song event")
+	}
+	event.CreatedAt = createdAt
+
+	return nil
+}
+
+// GetOutboxEvents возвращает события после afterID. Транзакции записи в SQLite
+// идут строго по очереди, поэтому события фиксируются по возрастанию ID.
+func (r *SQLiteSongRepository) GetOutboxEvents(ctx context.Context, afterID int64, limit int) ([]models.OutboxEvent, error) {
+	ctx, span := r.startQuery(ctx, "getOutboxEventsQuery", sqliteGetOutboxEventsQuery)
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetOutboxEventsQuery, afterID, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get song events")
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var event models.OutboxEvent
+		var payload, createdAt string
+		if err := rows.Scan(&event.ID, &event.Type, &event.SongID, &payload, &createdAt); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan song event")
+		}
+		event.Payload = []byte(payload)
+		if event.CreatedAt, err = parseSQLiteTimestamp(createdAt); err != nil {
+			tracing.RecordError(span, err)
+			return nil, errors.NewInternal("failed to scan song event", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get song events")
+	}
+
+	return events, nil
+}
+
+// GetLastOutboxEventID возвращает ID последнего события
+func (r *SQLiteSongRepository) GetLastOutboxEventID(ctx context.Context) (int64, error) {
+	ctx, span := r.startQuery(ctx, "getLastOutboxEventIDQuery", sqliteGetLastOutboxEventIDQuery)
+	defer span.End()
+
+	var id int64
+	if err := r.db.QueryRowContext(ctx, sqliteGetLastOutboxEventIDQuery).Scan(&id); err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapSQLiteError(err, "failed to get last song event")
+	}
+	return id, nil
+}
+
+// GetSongChanges возвращает изменения песен после since. Транзакции записи в SQLite
+// идут строго по очереди, поэтому изменения упорядочены одним номером и Tx всегда 0.
+func (r *SQLiteSongRepository) GetSongChanges(ctx context.Context, since models.SyncPosition, limit int) ([]models.SongChange, error) {
+	if since.Tx != 0 {
+		// Все изменения этого хранилища идут раньше места с транзакцией
+		return []models.SongChange{}, nil
+	}
+
+	ctx, span := r.startQuery(ctx, "getChangedSongsQuery", sqliteGetChangedSongsQuery)
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetChangedSongsQuery, since.Seq, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get changed songs")
+	}
+	defer rows.Close()
+
+	var changes []models.SongChange
+	for rows.Next() {
+		var change models.SongChange
+		var song models.Song
+		if err := scanSQLiteSong(prefixScanner{row: rows, prefix: []any{&change.Position.Seq}}, &song); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan song")
+		}
+		change.SongID, change.Song = song.ID, &song
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get changed songs")
+	}
+
+	deleted, err := r.getSongTombstones(ctx, since.Seq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return firstSongChanges(append(changes, deleted...), limit), nil
+}
+
+// getSongTombstones возвращает удаления песен после since
+func (r *SQLiteSongRepository) getSongTombstones(ctx context.Context, since int64, limit int) ([]models.SongChange, error) {
+	ctx, span := r.startQuery(ctx, "getSongTombstonesQuery", sqliteGetSongTombstonesQuery)
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetSongTombstonesQuery, since, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get deleted songs")
+	}
+	defer rows.Close()
+
+	var changes []models.SongChange
+	for rows.Next() {
+		var change models.SongChange
+		if err := rows.Scan(&change.Position.Seq, &change.SongID); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan deleted song")
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get deleted songs")
+	}
+	return changes, nil
+}
+
+// GetSyncBounds возвращает границы последовательности изменений
+func (r *SQLiteSongRepository) GetSyncBounds(ctx context.Context) (models.SyncBounds, error) {
+	ctx, span := r.startQuery(ctx, "getSyncBoundsQuery", sqliteGetSyncBoundsQuery)
+	defer span.End()
+
+	var bounds models.SyncBounds
+	if err := r.db.QueryRowContext(ctx, sqliteGetSyncBoundsQuery).Scan(&bounds.Horizon.Seq, &bounds.Latest); err != nil {
+		tracing.RecordError(span, err)
+		return bounds, mapSQLiteError(err, "failed to get sync bounds")
+	}
+	return bounds, nil
+}
+
+// PruneTombstones сдвигает горизонт и забывает старые удаления песен в одной транзакции
+func (r *SQLiteSongRepository) PruneTombstones(ctx context.Context, before time.Time) (pruned int, err error) {
+	ctx, span := r.startQuery(ctx, "pruneSongTombstonesQuery", sqlitePruneSongTombstonesQuery)
+	defer func() { tracing.End(span, err) }()
+
+	cutoff := before.UTC().Format(sqliteTimestampLayout)
+	err = r.WithinTx(ctx, func(repo SongRepository) error {
+		tx := repo.(*SQLiteSongRepository)
+		if _, err := tx.db.ExecContext(ctx, sqliteRaiseSyncHorizonQuery, cutoff); err != nil {
+			return mapSQLiteError(err, "failed to raise sync horizon")
+		}
+		result, err := tx.db.ExecContext(ctx, sqlitePruneSongTombstonesQuery, cutoff)
+		if err != nil {
+			return mapSQLiteError(err, "failed to prune song tombstones")
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return mapSQLiteError(err, "failed to prune song tombstones")
+		}
+		pruned = int(affected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// WithinTx выполняет fn в транзакции с репозиторием, привязанным к ней.
+// Транзакции SQLite сериализуемы, уровень изоляции не используется.
+// Занятая другим процессом база приводит к повтору fn целиком.
+func (r *SQLiteSongRepository) WithinTx(ctx context.Context, fn func(repo SongRepository) error, opts ...TxOption) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	options := r.txOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := r.runTx(ctx, fn)
+		if err == nil || !isSQLiteBusy(err) || attempt >= options.MaxRetries {
+			return err
+		}
+
+		delay := txRetryBaseDelay<<attempt + time.Duration(rand.Int63n(int64(txRetryBaseDelay)))
+		logging.FromContext(ctx, r.logger).Warn("Retrying transaction, database is busy",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx выполняет одну попытку транзакции
+func (r *SQLiteSongRepository) runTx(ctx context.Context, fn func(repo SongRepository) error) error {
+	tx, err := r.sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return mapSQLiteError(err, "failed to begin transaction")
+	}
+	// После Commit откат ничего не делает
+	defer func() { _ = tx.Rollback() }()
+
+	txRepo := &SQLiteSongRepository{
+		sqlDB:     r.sqlDB,
+		db:        tx,
+		logger:    r.logger,
+		txOptions: r.txOptions,
+		inTx:      true,
+	}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return mapSQLiteError(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// mapWriteError переводит ошибку записи песни. При нарушении уникальности
+// в ошибку добавляется ID уже существующей песни.
+func (r *SQLiteSongRepository) mapWriteError(ctx context.Context, err error, song *models.Song, message string) error {
+	mapped := mapSQLiteError(err, message)
+	if appErr, ok := errors.As(mapped); ok && appErr.Type == errors.AlreadyExists {
+		return r.songExistsError(ctx, NewNaturalKey(song.GroupName, song.SongName), err)
+	}
+	return mapped
+}
+
+// songExistsError ошибка о занятом естественном ключе с ID существующей песни.
+// В SQLite ошибка выражения не прерывает транзакцию, поэтому поиск идет в ней же.
+func (r *SQLiteSongRepository) songExistsError(ctx context.Context, key NaturalKey, cause error) error {
+	var id int
+	if err := r.db.QueryRowContext(ctx, sqliteGetSongIDByKeyQuery, key.Group, key.Song).Scan(&id); err != nil {
+		return errors.NewAlreadyExists(songExistsMessage, cause)
+	}
+	return errors.NewAlreadyExistsWithID(songExistsMessage, id, cause)
+}
+
+// startQuery открывает клиентский спан на выполнение одного SQL запроса
+func (r *SQLiteSongRepository) startQuery(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return startQuery(ctx, r.logger, sqliteSystem, name, query)
+}
+
+// scanSQLiteSong читает строку песни, даты хранятся текстом
+func scanSQLiteSong(row rowScanner, song *models.Song) error {
+	var releaseDate, createdAt, updatedAt string
+	err := row.Scan(
+		&song.ID,
+		&song.GroupName,
+		&song.SongName,
+		&releaseDate,
+		&song.Text,
+		&song.Link,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if song.ReleaseDate, err = time.Parse(sqliteDateLayout, releaseDate); err != nil {
+		return fmt.Errorf("invalid release_date %q: %w", releaseDate, err)
+	}
+	if song.CreatedAt, err = time.Parse(sqliteTimestampLayout, createdAt); err != nil {
+		return fmt.Errorf("invalid created_at %q: %w", createdAt, err)
+	}
+	if song.UpdatedAt, err = time.Parse(sqliteTimestampLayout, updatedAt); err != nil {
+		return fmt.Errorf("invalid updated_at %q: %w", updatedAt, err)
+	}
+	return nil
+}
+
+// prefixScanner читает первые колонки строки в prefix, остальные отдает вызывающему
+type prefixScanner struct {
+	row    rowScanner
+	prefix []any
+}
+
+func (s prefixScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(s.prefix[:len(s.prefix):len(s.prefix)], dest...)...)
+}
+
+// containsPattern шаблон LIKE для поиска подстроки без учета регистра, как ILIKE '%value%'
+func containsPattern(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "%" + strings.ToLower(value) + "%"
+}
+
+// timestampArg граница фильтра по дате в формате меток времени SQLite
+func timestampArg(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(sqliteTimestampLayout)
+}
+
+// sqliteNow текущее время записи с точностью до микросекунд
+func sqliteNow() string {
+	return now().Format(sqliteTimestampLayout)
+}
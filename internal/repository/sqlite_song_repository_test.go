@@ -0,0 +1,71 @@
+package repository_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/repository/repotest"
+	"github.com/testTask/migrations"
+	"go.uber.org/zap"
+)
+
+func TestSQLiteSongRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.SongRepository {
+		return repository.NewSQLiteSongRepository(openSQLite(t), zap.NewNop())
+	})
+}
+
+func TestSQLiteWebhookRepository(t *testing.T) {
+	repotest.RunWebhooks(t, func(t *testing.T) (repository.SongRepository, repository.WebhookRepository) {
+		db := openSQLite(t)
+		return repository.NewSQLiteSongRepository(db, zap.NewNop()), repository.NewSQLiteWebhookRepository(db, zap.NewNop())
+	})
+}
+
+// openSQLite открывает новую базу во временном каталоге теста и применяет к ней миграции
+func openSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "songs.db") +
+		"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	migrateSQLite(t, dsn)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// migrateSQLite применяет встроенные миграции SQLite к новой базе
+func migrateSQLite(t *testing.T, dsn string) {
+	t.Helper()
+
+	src, err := iofs.New(migrations.SQLiteFS, migrations.SQLiteDir)
+	if err != nil {
+		t.Fatalf("failed to open migrations: %v", err)
+	}
+	// Драйвер миграций закрывает базу вместе с собой
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	driver, err := sqlite.WithInstance(db, &sqlite.Config{})
+	if err != nil {
+		t.Fatalf("failed to create migration driver: %v", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, "sqlite", driver)
+	if err != nil {
+		t.Fatalf("failed to create migrator: %v", err)
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// SQLiteWebhookRepository хранит вебхуки в файле SQLite. Транзакции SQLite
+// выполняются по очереди, поэтому очередь разбирается без блокировок строк.
+type SQLiteWebhookRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewSQLiteWebhookRepository создает репозиторий вебхуков поверх открытой базы
+func NewSQLiteWebhookRepository(db *sql.DB, logger *zap.Logger) WebhookRepository {
+	return &SQLiteWebhookRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CreateWebhook регистрирует вебхук
+func (r *SQLiteWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "createWebhookQuery", sqliteCreateWebhookQuery)
+	defer span.End()
+
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	createdAt := now()
+	err := r.db.QueryRowContext(ctx, sqliteCreateWebhookQuery,
+		webhook.URL,
+		webhook.Secret,
+		strings.Join(webhook.EventTypes, ","),
+		createdAt.Format(sqliteTimestampLayout),
+	).Scan(&webhook.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to create webhook")
+	}
+	webhook.CreatedAt = createdAt
+
+	return webhook, nil
+}
+
+// GetWebhooks возвращает все вебхуки по порядку регистрации
+func (r *SQLiteWebhookRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "getWebhooksQuery", sqliteGetWebhooksQuery)
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetWebhooksQuery)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to query webhooks")
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := scanSQLiteWebhook(rows, &webhook); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan webhook")
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to iterate webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhookByID получает вебхук по ID
+func (r *SQLiteWebhookRepository) GetWebhookByID(ctx context.Context, id int) (*models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "getWebhookByIDQuery", sqliteGetWebhookByIDQuery)
+	defer span.End()
+
+	var webhook models.Webhook
+	err := scanSQLiteWebhook(r.db.QueryRowContext(ctx, sqliteGetWebhookByIDQuery, id), &webhook)
+	if stderrors.Is(err, sql.ErrNoRows) {
+		return nil, errors.NewNotFound("webhook not found", err)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to get webhook")
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет вебхук, доставки удаляются каскадом
+func (r *SQLiteWebhookRepository) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, span := r.startQuery(ctx, "deleteWebhookQuery", sqliteDeleteWebhookQuery)
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, sqliteDeleteWebhookQuery, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapSQLiteError(err, "failed to delete webhook")
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return mapSQLiteError(err, "failed to delete webhook")
+	}
+	if affected == 0 {
+		return errors.NewNotFound("webhook not found", nil)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries создает доставки событий до границы, найденной в той же транзакции
+func (r *SQLiteWebhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (enqueued int, err error) {
+	ctx, span := r.startQuery(ctx, "enqueueDeliveriesQuery", sqliteEnqueueDeliveriesQuery)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.withinTx(ctx, func(tx *sql.Tx) error {
+		var lastID sql.NullInt64
+		if err := tx.QueryRowContext(ctx, sqliteLastUndispatchedEventQuery, limit).Scan(&lastID); err != nil {
+			return mapSQLiteError(err, "failed to enqueue deliveries")
+		}
+		if !lastID.Valid {
+			return nil
+		}
+
+		nowArg := sqliteNow()
+		if _, err := tx.ExecContext(ctx, sqliteEnqueueDeliveriesQuery, lastID.Int64, nowArg); err != nil {
+			return mapSQLiteError(err, "failed to enqueue deliveries")
+		}
+		result, err := tx.ExecContext(ctx, sqliteMarkEventsDispatchedQuery, lastID.Int64, nowArg)
+		if err != nil {
+			return mapSQLiteError(err, "failed to enqueue deliveries")
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return mapSQLiteError(err, "failed to enqueue deliveries")
+		}
+		enqueued = int(affected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return enqueued, nil
+}
+
+// ClaimDeliveries читает доставки, время которых пришло, и откладывает их в той же транзакции
+func (r *SQLiteWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) (deliveries []models.PendingDelivery, err error) {
+	ctx, span := r.startQuery(ctx, "claimDeliveriesQuery", sqliteClaimDeliveriesQuery)
+	defer func() { tracing.End(span, err) }()
+
+	err = r.withinTx(ctx, func(tx *sql.Tx) error {
+		current := now()
+		rows, err := tx.QueryContext(ctx, sqliteClaimDeliveriesQuery, current.Format(sqliteTimestampLayout), limit)
+		if err != nil {
+			return mapSQLiteError(err, "failed to claim deliveries")
+		}
+		defer rows.Close()
+
+		deliveries = nil
+		for rows.Next() {
+			var d models.PendingDelivery
+			var eventTypes, webhookCreatedAt, payload, eventCreatedAt string
+			err := rows.Scan(
+				&d.ID,
+				&d.Attempt,
+				&d.Webhook.ID,
+				&d.Webhook.URL,
+				&d.Webhook.Secret,
+				&eventTypes,
+				&webhookCreatedAt,
+				&d.Event.ID,
+				&d.Event.Type,
+				&d.Event.SongID,
+				&payload,
+				&eventCreatedAt,
+			)
+			if err != nil {
+				return mapSQLiteError(err, "failed to scan delivery")
+			}
+			d.Attempt++
+			d.Webhook.EventTypes = splitEventTypes(eventTypes)
+			d.Event.Payload = []byte(payload)
+			if d.Webhook.CreatedAt, err = parseSQLiteTimestamp(webhookCreatedAt); err != nil {
+				return errors.NewInternal("failed to scan delivery", err)
+			}
+			if d.Event.CreatedAt, err = parseSQLiteTimestamp(eventCreatedAt); err != nil {
+				return errors.NewInternal("failed to scan delivery", err)
+			}
+			deliveries = append(deliveries, d)
+		}
+		if err := rows.Err(); err != nil {
+			return mapSQLiteError(err, "failed to claim deliveries")
+		}
+		rows.Close()
+
+		nextAttemptAt := current.Add(lease).Format(sqliteTimestampLayout)
+		for _, d := range deliveries {
+			_, err := tx.ExecContext(ctx, sqliteLeaseDeliveryQuery, d.ID, nextAttemptAt, current.Format(sqliteTimestampLayout))
+			if err != nil {
+				return mapSQLiteError(err, "failed to claim deliveries")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CompleteDelivery сохраняет итог попытки доставки
+func (r *SQLiteWebhookRepository) CompleteDelivery(ctx context.Context, id int64, result *models.DeliveryResult) error {
+	ctx, span := r.startQuery(ctx, "completeDeliveryQuery", sqliteCompleteDeliveryQuery)
+	defer span.End()
+
+	var nextAttemptAt any
+	if result.Status == models.DeliveryPending {
+		nextAttemptAt = result.NextAttemptAt.UTC().Format(sqliteTimestampLayout)
+	}
+	_, err := r.db.ExecContext(ctx, sqliteCompleteDeliveryQuery,
+		id,
+		result.Status,
+		result.StatusCode,
+		result.Error,
+		nextAttemptAt,
+		sqliteNow(),
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapSQLiteError(err, "failed to save delivery result")
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает страницу журнала доставок
+func (r *SQLiteWebhookRepository) GetDeliveries(ctx context.Context, filter *models.DeliveryFilter) (*models.DeliveriesResponse, error) {
+	setDeliveryFilterDefaults(filter)
+	offset := (filter.Page - 1) * filter.PageSize
+
+	ctx, span := r.startQuery(ctx, "getDeliveriesQuery", sqliteGetDeliveriesQuery)
+	defer span.End()
+
+	var totalItems int
+	err := r.db.QueryRowContext(ctx, sqliteCountDeliveriesQuery, filter.WebhookID, filter.Status).Scan(&totalItems)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to count deliveries")
+	}
+
+	rows, err := r.db.QueryContext(ctx, sqliteGetDeliveriesQuery, filter.WebhookID, filter.Status, filter.PageSize, offset)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to query deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := scanSQLiteDelivery(rows, &d); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapSQLiteError(err, "failed to scan delivery")
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapSQLiteError(err, "failed to iterate deliveries")
+	}
+
+	return newDeliveriesResponse(deliveries, filter, totalItems), nil
+}
+
+// ReplayDeliveries ставит события в очередь заново одним запросом
+func (r *SQLiteWebhookRepository) ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (int, error) {
+	ctx, span := r.startQuery(ctx, "replayDeliveriesQuery", sqliteReplayDeliveriesQuery)
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, sqliteReplayDeliveriesQuery, webhookID, fromEventID, sqliteNow())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapSQLiteError(err, "failed to replay deliveries")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, mapSQLiteError(err, "failed to replay deliveries")
+	}
+
+	return int(affected), nil
+}
+
+// withinTx выполняет fn в одной транзакции
+func (r *SQLiteWebhookRepository) withinTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return mapSQLiteError(err, "failed to begin transaction")
+	}
+	// После Commit откат ничего не делает
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return mapSQLiteError(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+func (r *SQLiteWebhookRepository) startQuery(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return startQuery(ctx, r.logger, sqliteSystem, name, query)
+}
+
+// scanSQLiteWebhook читает строку вебхука, виды событий хранятся через запятую
+func scanSQLiteWebhook(row rowScanner, webhook *models.Webhook) error {
+	var eventTypes, createdAt string
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &eventTypes, &createdAt); err != nil {
+		return err
+	}
+	webhook.EventTypes = splitEventTypes(eventTypes)
+
+	var err error
+	webhook.CreatedAt, err = parseSQLiteTimestamp(createdAt)
+	return err
+}
+
+// scanSQLiteDelivery читает строку журнала доставок
+func scanSQLiteDelivery(row rowScanner, d *models.WebhookDelivery) error {
+	var nextAttemptAt, createdAt, updatedAt string
+	var deliveredAt sql.NullString
+	err := row.Scan(
+		&d.ID,
+		&d.WebhookID,
+		&d.EventID,
+		&d.EventType,
+		&d.Status,
+		&d.Attempts,
+		&nextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&deliveredAt,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	if d.NextAttemptAt, err = parseSQLiteTimestamp(nextAttemptAt); err != nil {
+		return err
+	}
+	if d.CreatedAt, err = parseSQLiteTimestamp(createdAt); err != nil {
+		return err
+	}
+	if d.UpdatedAt, err = parseSQLiteTimestamp(updatedAt); err != nil {
+		return err
+	}
+	if deliveredAt.Valid {
+		t, err := parseSQLiteTimestamp(deliveredAt.String)
+		if err != nil {
+			return err
+		}
+		d.DeliveredAt = &t
+	}
+	return nil
+}
+
+// parseSQLiteTimestamp разбирает метку времени, записанную sqliteNow
+func parseSQLiteTimestamp(value string) (time.Time, error) {
+	t, err := time.Parse(sqliteTimestampLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %w", value, err)
+	}
+	return t, nil
+}
+
+// splitEventTypes разбирает список видов событий, пустая строка - все события
+func splitEventTypes(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
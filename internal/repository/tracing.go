@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// statementNameKey атрибут спана с именем SQL запроса из queries.go
+const statementNameKey = attribute.Key("db.statement.name")
+
+// dbSystem база данных в именах и атрибутах спанов
+type dbSystem struct {
+	name      string
+	attribute attribute.KeyValue
+}
+
+var (
+	postgresSystem = dbSystem{name: "postgres", attribute: semconv.DBSystemPostgreSQL}
+	sqliteSystem   = dbSystem{name: "sqlite", attribute: semconv.DBSystemSqlite}
+)
+
+// startQuery открывает клиентский спан на выполнение одного SQL запроса
+// и пишет отладочную запись в логгер текущего запроса
+func (r *PostgresSongRepository) startQuery(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return startQuery(ctx, r.logger, postgresSystem, name, query)
+}
+
+func startQuery(ctx context.Context, logger *zap.Logger, system dbSystem, name, query string) (context.Context, trace.Span) {
+	logging.FromContext(ctx, logger).Debug("Executing query", zap.String("statement", name))
+
+	return tracing.Tracer().Start(ctx, system.name+"."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			system.attribute,
+			statementNameKey.String(name),
+			semconv.DBStatement(query),
+		),
+	)
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/testTask/internal/logging"
+	"go.uber.org/zap"
+)
+
+// IsolationLevel уровень изоляции транзакции
+type IsolationLevel string
+
+const (
+	ReadCommitted  IsolationLevel = "read_committed"
+	RepeatableRead IsolationLevel = "repeatable_read"
+	Serializable   IsolationLevel = "serializable"
+)
+
+// IsolationLevels все поддерживаемые уровни изоляции
+var IsolationLevels = []IsolationLevel{ReadCommitted, RepeatableRead, Serializable}
+
+// Параметры повтора транзакции после конфликта сериализации
+const (
+	defaultTxMaxRetries = 3
+	txRetryBaseDelay    = 10 * time.Millisecond
+)
+
+// TxOptions параметры транзакции WithinTx
+type TxOptions struct {
+	Isolation IsolationLevel
+	// MaxRetries сколько раз повторить транзакцию после конфликта сериализации или дедлока
+	MaxRetries int
+}
+
+// TxOption меняет параметры транзакции
+type TxOption func(*TxOptions)
+
+// WithIsolation задает уровень изоляции транзакции
+func WithIsolation(level IsolationLevel) TxOption {
+	return func(o *TxOptions) { o.Isolation = level }
+}
+
+// WithMinIsolation повышает уровень изоляции до level, более строгий уровень из конфигурации сохраняется
+func WithMinIsolation(level IsolationLevel) TxOption {
+	return func(o *TxOptions) {
+		if isolationRank(o.Isolation) < isolationRank(level) {
+			o.Isolation = level
+		}
+	}
+}
+
+// WithMaxRetries задает количество повторов после конфликта сериализации
+func WithMaxRetries(n int) TxOption {
+	return func(o *TxOptions) { o.MaxRetries = n }
+}
+
+// defaultTxOptions параметры транзакций, если репозиторию не передано других
+func defaultTxOptions() TxOptions {
+	return TxOptions{Isolation: ReadCommitted, MaxRetries: defaultTxMaxRetries}
+}
+
+// querier общие методы пула и транзакции pgx, через которые работает репозиторий
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// WithinTx выполняет fn в транзакции с репозиторием, привязанным к ней.
+// Ошибка fn откатывает транзакцию, конфликт сериализации или дедлок приводят
+// к повтору fn целиком, поэтому fn не должна иметь побочных эффектов вне базы.
+// Вложенный вызов выполняется в уже открытой транзакции.
+func (r *PostgresSongRepository) WithinTx(ctx context.Context, fn func(repo SongRepository) error, opts ...TxOption) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	options := r.txOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	isoLevel, err := pgxIsoLevel(options.Isolation)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := r.runTx(ctx, pgx.TxOptions{IsoLevel: isoLevel}, fn)
+		if err == nil || !isRetryable(err) || attempt >= options.MaxRetries {
+			return err
+		}
+
+		// Небольшая случайная пауза, чтобы конкурирующие транзакции разошлись
+		delay := txRetryBaseDelay<<attempt + time.Duration(rand.Int63n(int64(txRetryBaseDelay)))
+		logging.FromContext(ctx, r.logger).Warn("Retrying transaction after conflict",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx выполняет одну попытку транзакции
+func (r *PostgresSongRepository) runTx(ctx context.Context, options pgx.TxOptions, fn func(repo SongRepository) error) error {
+	tx, err := r.pool.BeginTx(ctx, options)
+	if err != nil {
+		return mapError(err, "failed to begin transaction")
+	}
+	// После Commit откат ничего не делает
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	txRepo := &PostgresSongRepository{
+		pool:      r.pool,
+		db:        tx,
+		logger:    r.logger,
+		txOptions: r.txOptions,
+		inTx:      true,
+	}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return mapError(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// isRetryable сообщает, можно ли повторить транзакцию после этой ошибки
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !stderrors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == pgerrcode.SerializationFailure || pgErr.Code == pgerrcode.DeadlockDetected
+}
+
+// isolationRank порядок уровней изоляции от слабого к строгому
+func isolationRank(level IsolationLevel) int {
+	for i, l := range IsolationLevels {
+		if l == level {
+			return i
+		}
+	}
+	return 0
+}
+
+// pgxIsoLevel переводит уровень изоляции в значение pgx
+func pgxIsoLevel(level IsolationLevel) (pgx.TxIsoLevel, error) {
+	switch level {
+	case ReadCommitted, "":
+		return pgx.ReadCommitted, nil
+	case RepeatableRead:
+		return pgx.RepeatableRead, nil
+	case Serializable:
+		return pgx.Serializable, nil
+	}
+	return "", fmt.Errorf("unknown isolation level %q", level)
+}
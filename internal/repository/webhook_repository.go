@@ -0,0 +1,317 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// WebhookRepository хранит вебхуки и очередь доставки событий outbox
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	GetWebhookByID(ctx context.Context, id int) (*models.Webhook, error)
+	// DeleteWebhook удаляет вебхук вместе с его доставками
+	DeleteWebhook(ctx context.Context, id int) error
+	// EnqueueDeliveries раскладывает до limit еще не разосланных событий outbox
+	// по вебхукам, подписанным на их вид. Возвращает количество разосланных событий.
+	EnqueueDeliveries(ctx context.Context, limit int) (int, error)
+	// ClaimDeliveries забирает до limit доставок, время которых пришло, и откладывает
+	// их следующую попытку на lease, чтобы их не взял параллельный диспетчер
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error)
+	// CompleteDelivery сохраняет итог попытки доставки
+	CompleteDelivery(ctx context.Context, id int64, result *models.DeliveryResult) error
+	// GetDeliveries возвращает журнал доставок вебхука, новые первыми
+	GetDeliveries(ctx context.Context, filter *models.DeliveryFilter) (*models.DeliveriesResponse, error)
+	// ReplayDeliveries заново ставит в очередь доставку вебхуку всех уже разосланных
+	// событий начиная с fromEventID. Возвращает количество новых доставок.
+	ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (int, error)
+}
+
+// PostgresWebhookRepository хранит вебхуки в PostgreSQL. Несколько экземпляров
+// сервиса разбирают очередь параллельно: строки берутся через SKIP LOCKED.
+type PostgresWebhookRepository struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// NewPostgresWebhookRepository создает репозиторий вебхуков поверх пула соединений
+func NewPostgresWebhookRepository(pool *pgxpool.Pool, logger *zap.Logger) WebhookRepository {
+	return &PostgresWebhookRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// CreateWebhook регистрирует вебхук
+func (r *PostgresWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "createWebhookQuery", createWebhookQuery)
+	defer span.End()
+
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	err := r.pool.QueryRow(ctx, createWebhookQuery, webhook.URL, webhook.Secret, webhook.EventTypes).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to create webhook")
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks возвращает все вебхуки по порядку регистрации
+func (r *PostgresWebhookRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "getWebhooksQuery", getWebhooksQuery)
+	defer span.End()
+
+	rows, err := r.pool.Query(ctx, getWebhooksQuery)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to query webhooks")
+	}
+	defer rows.Close()
+
+	webhooks := make([]models.Webhook, 0)
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.EventTypes, &webhook.CreatedAt); err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan webhook")
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to iterate webhooks")
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhookByID получает вебхук по ID
+func (r *PostgresWebhookRepository) GetWebhookByID(ctx context.Context, id int) (*models.Webhook, error) {
+	ctx, span := r.startQuery(ctx, "getWebhookByIDQuery", getWebhookByIDQuery)
+	defer span.End()
+
+	var webhook models.Webhook
+	err := r.pool.QueryRow(ctx, getWebhookByIDQuery, id).
+		Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.EventTypes, &webhook.CreatedAt)
+	if stderrors.Is(err, pgx.ErrNoRows) {
+		return nil, errors.NewNotFound("webhook not found", err)
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to get webhook")
+	}
+
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет вебхук, доставки удаляются каскадом
+func (r *PostgresWebhookRepository) DeleteWebhook(ctx context.Context, id int) error {
+	ctx, span := r.startQuery(ctx, "deleteWebhookQuery", deleteWebhookQuery)
+	defer span.End()
+
+	tag, err := r.pool.Exec(ctx, deleteWebhookQuery, id)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapError(err, "failed to delete webhook")
+	}
+	if tag.RowsAffected() == 0 {
+		return errors.NewNotFound("webhook not found", nil)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries создает доставки и отмечает события разосланными одним запросом
+func (r *PostgresWebhookRepository) EnqueueDeliveries(ctx context.Context, limit int) (int, error) {
+	ctx, span := r.startQuery(ctx, "enqueueDeliveriesQuery", enqueueDeliveriesQuery)
+	defer span.End()
+
+	tag, err := r.pool.Exec(ctx, enqueueDeliveriesQuery, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapError(err, "failed to enqueue deliveries")
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+// ClaimDeliveries забирает доставки, время которых пришло
+func (r *PostgresWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.PendingDelivery, error) {
+	ctx, span := r.startQuery(ctx, "claimDeliveriesQuery", claimDeliveriesQuery)
+	defer span.End()
+
+	rows, err := r.pool.Query(ctx, claimDeliveriesQuery, limit, lease.Seconds())
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to claim deliveries")
+	}
+	defer rows.Close()
+
+	var deliveries []models.PendingDelivery
+	for rows.Next() {
+		var d models.PendingDelivery
+		var payload []byte
+		err := rows.Scan(
+			&d.ID,
+			&d.Attempt,
+			&d.Webhook.ID,
+			&d.Webhook.URL,
+			&d.Webhook.Secret,
+			&d.Webhook.EventTypes,
+			&d.Webhook.CreatedAt,
+			&d.Event.ID,
+			&d.Event.Type,
+			&d.Event.SongID,
+			&payload,
+			&d.Event.CreatedAt,
+		)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan delivery")
+		}
+		d.Event.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to claim deliveries")
+	}
+
+	return deliveries, nil
+}
+
+// CompleteDelivery сохраняет итог попытки доставки
+func (r *PostgresWebhookRepository) CompleteDelivery(ctx context.Context, id int64, result *models.DeliveryResult) error {
+	ctx, span := r.startQuery(ctx, "completeDeliveryQuery", completeDeliveryQuery)
+	defer span.End()
+
+	var nextAttemptAt *time.Time
+	if result.Status == models.DeliveryPending {
+		nextAttemptAt = &result.NextAttemptAt
+	}
+	_, err := r.pool.Exec(ctx, completeDeliveryQuery, id, result.Status, result.StatusCode, result.Error, nextAttemptAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return mapError(err, "failed to save delivery result")
+	}
+
+	return nil
+}
+
+// GetDeliveries возвращает страницу журнала доставок
+func (r *PostgresWebhookRepository) GetDeliveries(ctx context.Context, filter *models.DeliveryFilter) (*models.DeliveriesResponse, error) {
+	setDeliveryFilterDefaults(filter)
+	offset := (filter.Page - 1) * filter.PageSize
+
+	ctx, span := r.startQuery(ctx, "getDeliveriesQuery", getDeliveriesQuery)
+	defer span.End()
+
+	var totalItems int
+	if err := r.pool.QueryRow(ctx, countDeliveriesQuery, filter.WebhookID, filter.Status).Scan(&totalItems); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to count deliveries")
+	}
+
+	rows, err := r.pool.Query(ctx, getDeliveriesQuery, filter.WebhookID, filter.Status, filter.PageSize, offset)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to query deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.EventID,
+			&d.EventType,
+			&d.Status,
+			&d.Attempts,
+			&d.NextAttemptAt,
+			&d.LastStatusCode,
+			&d.LastError,
+			&d.DeliveredAt,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, mapError(err, "failed to scan delivery")
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, mapError(err, "failed to iterate deliveries")
+	}
+
+	return newDeliveriesResponse(deliveries, filter, totalItems), nil
+}
+
+// ReplayDeliveries ставит события в очередь заново одним запросом
+func (r *PostgresWebhookRepository) ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (int, error) {
+	ctx, span := r.startQuery(ctx, "replayDeliveriesQuery", replayDeliveriesQuery)
+	defer span.End()
+
+	tag, err := r.pool.Exec(ctx, replayDeliveriesQuery, webhookID, fromEventID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return 0, mapError(err, "failed to replay deliveries")
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *PostgresWebhookRepository) startQuery(ctx context.Context, name, query string) (context.Context, trace.Span) {
+	return startQuery(ctx, r.logger, postgresSystem, name, query)
+}
+
+// setDeliveryFilterDefaults подставляет страницу и размер страницы по умолчанию
+func setDeliveryFilterDefaults(filter *models.DeliveryFilter) {
+	if filter.PageSize <= 0 {
+		filter.PageSize = 10
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+}
+
+// newDeliveriesResponse собирает страницу журнала. В отличие от списка песен,
+// пустой журнал или страница за его концом не ошибка: доставок может еще не быть.
+func newDeliveriesResponse(deliveries []models.WebhookDelivery, filter *models.DeliveryFilter, totalItems int) *models.DeliveriesResponse {
+	return &models.DeliveriesResponse{
+		Deliveries:  deliveries,
+		CurrentPage: filter.Page,
+		TotalPages:  (totalItems + filter.PageSize - 1) / filter.PageSize,
+		TotalItems:  totalItems,
+		PageSize:    filter.PageSize,
+	}
+}
+
+// eventTypeMatches проверяет, подписан ли вебхук с видами событий eventTypes на eventType
+func eventTypeMatches(eventTypes []string, eventType string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, t := range eventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
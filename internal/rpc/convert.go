@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"time"
+
+	songsv1 "github.com/testTask/api/songs/v1"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/validation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// dateLayout формат дат выпуска в сообщениях
+const dateLayout = "2006-01-02"
+
+// newSong переводит песню в сообщение
+func newSong(song *models.Song) *songsv1.Song {
+	return &songsv1.Song{
+		Id:          int64(song.ID),
+		GroupName:   song.GroupName,
+		SongName:    song.SongName,
+		ReleaseDate: song.ReleaseDate.Format(dateLayout),
+		Text:        song.Text,
+		Link:        song.Link,
+		CreatedAt:   timestamppb.New(song.CreatedAt),
+		UpdatedAt:   timestamppb.New(song.UpdatedAt),
+	}
+}
+
+// songFilter переводит фильтр из сообщения, пустой фильтр выбирает все песни
+func songFilter(filter *songsv1.SongFilter) (*models.SongFilter, error) {
+	v := validation.New()
+	result := &models.SongFilter{
+		GroupName: filter.GetGroupName(),
+		SongName:  filter.GetSongName(),
+		FromDate:  date(v, "filter.from_date", filter.GetFromDate()),
+		ToDate:    date(v, "filter.to_date", filter.GetToDate()),
+		Text:      filter.GetText(),
+		Link:      filter.GetLink(),
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// date разбирает дату в формате 2006-01-02, пустая строка означает отсутствие даты
+func date(v *validation.Errors, field, raw string) *time.Time {
+	if raw == "" {
+		return nil
+	}
+	value, err := time.Parse(dateLayout, raw)
+	if err != nil {
+		v.Add(field, "must be a date in format YYYY-MM-DD")
+		return nil
+	}
+	return &value
+}
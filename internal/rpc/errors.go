@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+
+	"github.com/testTask/internal/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// errorDomain домен ошибок в google.rpc.ErrorInfo
+const errorDomain = "music-library"
+
+// codeByErrorType соответствие типов ошибок приложения кодам gRPC
+var codeByErrorType = map[errors.ErrorType]codes.Code{
+	errors.NotFound:       codes.NotFound,
+	errors.BadRequest:     codes.InvalidArgument,
+	errors.Validation:     codes.InvalidArgument,
+	errors.AlreadyExists:  codes.AlreadyExists,
+	errors.ResyncRequired: codes.FailedPrecondition,
+	errors.Internal:       codes.Internal,
+}
+
+// newStatus собирает статус gRPC из ошибки приложения. Код ошибки REST API уходит
+// в ErrorInfo, ошибки полей в BadRequest. Детали внутренних ошибок наружу не отдаем.
+func newStatus(err error) *status.Status {
+	switch {
+	case stderrors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, "request canceled")
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	appErr, ok := errors.As(err)
+	code, known := codes.Internal, false
+	if ok {
+		code, known = codeByErrorType[appErr.Type]
+	}
+	if !known || code == codes.Internal {
+		return withDetails(status.New(codes.Internal, "internal server error"), &errdetails.ErrorInfo{
+			Reason: string(errors.Internal),
+			Domain: errorDomain,
+		})
+	}
+
+	info := &errdetails.ErrorInfo{Reason: string(appErr.Type), Domain: errorDomain}
+	if appErr.ExistingID != 0 {
+		info.Metadata = map[string]string{"existing_id": strconv.Itoa(appErr.ExistingID)}
+	}
+	details := []protoiface.MessageV1{info}
+	if len(appErr.Fields) > 0 {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(appErr.Fields))
+		for i, field := range appErr.Fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message}
+		}
+		details = append(details, &errdetails.BadRequest{FieldViolations: violations})
+	}
+	return withDetails(status.New(code, appErr.Message), details...)
+}
+
+// withDetails добавляет подробности к статусу, без них статус остается пригодным
+func withDetails(st *status.Status, details ...protoiface.MessageV1) *status.Status {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st
+	}
+	return detailed
+}
@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/middleware"
+	"github.com/testTask/internal/tracing"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey ключ метаданных с идентификатором запроса, как заголовок X-Request-ID в HTTP
+const requestIDKey = "x-request-id"
+
+// unaryInterceptor оборачивает обычные вызовы в startCall
+func unaryInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, finish := startCall(ctx, info.FullMethod, logger)
+		resp, err := handler(ctx, req)
+		finish(err)
+		return resp, err
+	}
+}
+
+// streamInterceptor оборачивает потоковые вызовы в startCall
+func streamInterceptor(logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, finish := startCall(stream.Context(), info.FullMethod, logger)
+		err := handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+		finish(err)
+		return err
+	}
+}
+
+// startCall делает для вызова то же, что middleware для HTTP запроса: продолжает трейс
+// клиента, принимает или генерирует идентификатор запроса и кладет в контекст логгер с ним.
+// Возвращенная функция логирует результат вызова и закрывает спан.
+func startCall(ctx context.Context, method string, logger *zap.Logger) (context.Context, func(error)) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	name := strings.TrimPrefix(method, "/")
+	serviceName, methodName, _ := strings.Cut(name, "/")
+	ctx, span := tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(serviceName),
+			semconv.RPCMethod(methodName),
+		),
+	)
+
+	var requestID string
+	if values := md.Get(requestIDKey); len(values) > 0 && middleware.IsValidRequestID(values[0]) {
+		requestID = values[0]
+	} else {
+		requestID = middleware.NewRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID))
+
+	fields := []zap.Field{zap.String("request_id", requestID)}
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+	}
+	requestLogger := logger.With(fields...)
+	ctx = logging.WithRequestID(ctx, requestID)
+	ctx = logging.WithLogger(ctx, requestLogger)
+
+	requestLogger.Info("Incoming gRPC call", zap.String("method", method))
+
+	return ctx, func(err error) {
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int64(int64(code)))
+		if code == codes.Internal || code == codes.Unknown {
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		span.End()
+
+		requestLogger.Info("gRPC call completed",
+			zap.String("method", method),
+			zap.String("code", code.String()),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
+}
+
+// contextStream подменяет контекст потока контекстом с логгером и спаном
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataCarrier дает propagator доступ к метаданным вызова
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,167 @@
+// Package rpc реализует gRPC API песен поверх SongService
+package rpc
+
+import (
+	"context"
+
+	songsv1 "github.com/testTask/api/songs/v1"
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/service"
+	"github.com/testTask/internal/validation"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// SongServer реализует songsv1.SongServiceServer
+type SongServer struct {
+	songsv1.UnimplementedSongServiceServer
+
+	service *service.SongService
+	logger  *zap.Logger
+}
+
+func NewSongServer(service *service.SongService, logger *zap.Logger) *SongServer {
+	return &SongServer{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// NewServer создает gRPC сервер с сервисом песен, логированием и трассировкой вызовов
+func NewServer(songs *SongServer, logger *zap.Logger, options ...grpc.ServerOption) *grpc.Server {
+	options = append(options,
+		grpc.ChainUnaryInterceptor(unaryInterceptor(logger)),
+		grpc.ChainStreamInterceptor(streamInterceptor(logger)),
+	)
+	server := grpc.NewServer(options...)
+	songsv1.RegisterSongServiceServer(server, songs)
+	return server
+}
+
+// List возвращает страницу песен по фильтру
+func (s *SongServer) List(ctx context.Context, req *songsv1.ListRequest) (*songsv1.ListResponse, error) {
+	filter, err := songFilter(req.GetFilter())
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	filter.Page = int(req.GetPage())
+	filter.PageSize = int(req.GetPageSize())
+
+	response, err := s.service.GetSongs(ctx, filter)
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+
+	songs := make([]*songsv1.Song, len(response.Songs))
+	for i := range response.Songs {
+		songs[i] = newSong(&response.Songs[i])
+	}
+	return &songsv1.ListResponse{
+		Songs:       songs,
+		CurrentPage: int32(response.CurrentPage),
+		TotalPages:  int32(response.TotalPages),
+		TotalItems:  int32(response.TotalItems),
+		PageSize:    int32(response.PageSize),
+	}, nil
+}
+
+// Get возвращает песню по ID
+func (s *SongServer) Get(ctx context.Context, req *songsv1.GetRequest) (*songsv1.Song, error) {
+	details, err := s.service.GetSong(ctx, int(req.GetId()), nil)
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	return newSong(&details.Song), nil
+}
+
+// GetLyrics возвращает страницу куплетов песни
+func (s *SongServer) GetLyrics(ctx context.Context, req *songsv1.GetLyricsRequest) (*songsv1.GetLyricsResponse, error) {
+	lyrics, err := s.service.GetLyrics(ctx, int(req.GetSongId()), int(req.GetPage()), int(req.GetPageSize()))
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	return &songsv1.GetLyricsResponse{
+		Text:        lyrics.Text,
+		CurrentPage: int32(lyrics.CurrentPage),
+		TotalPages:  int32(lyrics.TotalPages),
+		PageSize:    int32(lyrics.PageSize),
+	}, nil
+}
+
+// Create создает песню
+func (s *SongServer) Create(ctx context.Context, req *songsv1.CreateRequest) (*songsv1.Song, error) {
+	song, err := s.service.CreateSong(ctx, &models.SongRequest{
+		GroupName: req.GetGroupName(),
+		SongName:  req.GetSongName(),
+		Text:      req.GetText(),
+		Link:      req.GetLink(),
+	})
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	return newSong(song), nil
+}
+
+// Update частично обновляет песню
+func (s *SongServer) Update(ctx context.Context, req *songsv1.UpdateRequest) (*songsv1.Song, error) {
+	song, err := s.service.UpdateSong(ctx, int(req.GetId()), &models.SongRequest{
+		GroupName: req.GetGroupName(),
+		SongName:  req.GetSongName(),
+		Text:      req.GetText(),
+		Link:      req.GetLink(),
+	})
+	if err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	return newSong(song), nil
+}
+
+// Delete удаляет песню
+func (s *SongServer) Delete(ctx context.Context, req *songsv1.DeleteRequest) (*songsv1.DeleteResponse, error) {
+	if err := s.service.DeleteSong(ctx, int(req.GetId())); err != nil {
+		return nil, s.fail(ctx, err)
+	}
+	return &songsv1.DeleteResponse{}, nil
+}
+
+// Export отправляет все песни по фильтру, читая их страницами наибольшего размера.
+// Песни, измененные во время выгрузки, могут пропасть или повториться между страницами.
+func (s *SongServer) Export(req *songsv1.ExportRequest, stream songsv1.SongService_ExportServer) error {
+	ctx := stream.Context()
+	filter, err := songFilter(req.GetFilter())
+	if err != nil {
+		return s.fail(ctx, err)
+	}
+	filter.PageSize = validation.MaxPageSize
+
+	for filter.Page = 1; ; filter.Page++ {
+		response, err := s.service.GetSongs(ctx, filter)
+		// Когда подходящих песен нет, сервис сообщает, что первой страницы нет
+		if appErr, ok := errors.As(err); ok && appErr.Type == errors.NotFound && filter.Page == 1 {
+			return nil
+		}
+		if err != nil {
+			return s.fail(ctx, err)
+		}
+
+		for i := range response.Songs {
+			if err := stream.Send(newSong(&response.Songs[i])); err != nil {
+				return err
+			}
+		}
+		if filter.Page >= response.TotalPages {
+			return nil
+		}
+	}
+}
+
+// fail переводит ошибку приложения в статус gRPC и логирует внутренние ошибки
+func (s *SongServer) fail(ctx context.Context, err error) error {
+	st := newStatus(err)
+	if appErr, ok := errors.As(err); !ok || appErr.Type == errors.Internal {
+		logging.FromContext(ctx, s.logger).Error("gRPC call failed", zap.Error(err))
+	}
+	return st.Err()
+}
@@ -0,0 +1,167 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	songsv1 "github.com/testTask/api/songs/v1"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/rpc"
+	"github.com/testTask/internal/service"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient запускает gRPC сервер поверх repo в памяти процесса и возвращает клиента к нему
+func newClient(t *testing.T, repo repository.SongRepository) songsv1.SongServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := rpc.NewServer(rpc.NewSongServer(service.NewSongService(repo, zap.NewNop()), zap.NewNop()), zap.NewNop())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return songsv1.NewSongServiceClient(conn)
+}
+
+func TestSongLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t, repository.NewMemorySongRepository())
+
+	created, err := client.Create(ctx, &songsv1.CreateRequest{GroupName: "Muse", SongName: "Hysteria", Text: "first\n\nsecond"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.GetId() == 0 || created.GetReleaseDate() == "" || created.GetCreatedAt() == nil {
+		t.Errorf("created song %v", created)
+	}
+
+	updated, err := client.Update(ctx, &songsv1.UpdateRequest{Id: created.GetId(), Link: "https://example.com/hysteria"})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.GetSongName() != "Hysteria" || updated.GetLink() != "https://example.com/hysteria" {
+		t.Errorf("updated song %v", updated)
+	}
+
+	list, err := client.List(ctx, &songsv1.ListRequest{Filter: &songsv1.SongFilter{GroupName: "muse"}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if list.GetTotalItems() != 1 || len(list.GetSongs()) != 1 || list.GetSongs()[0].GetId() != created.GetId() {
+		t.Errorf("list %v", list)
+	}
+
+	lyrics, err := client.GetLyrics(ctx, &songsv1.GetLyricsRequest{SongId: created.GetId(), Page: 2, PageSize: 1})
+	if err != nil {
+		t.Fatalf("GetLyrics: %v", err)
+	}
+	if lyrics.GetText() != "second" || lyrics.GetTotalPages() != 2 {
+		t.Errorf("lyrics %v", lyrics)
+	}
+
+	if _, err := client.Delete(ctx, &songsv1.DeleteRequest{Id: created.GetId()}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := client.Get(ctx, &songsv1.GetRequest{Id: created.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get of deleted song: %v, want NotFound", err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	ctx := context.Background()
+	client := newClient(t, repository.NewMemorySongRepository())
+
+	_, err := client.List(ctx, &songsv1.ListRequest{Filter: &songsv1.SongFilter{FromDate: "yesterday"}, PageSize: 1000})
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("invalid list: %v, want InvalidArgument", err)
+	}
+	fields := map[string]bool{}
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields[violation.GetField()] = true
+			}
+		}
+	}
+	if !fields["filter.from_date"] {
+		t.Errorf("field violations %v, want filter.from_date", fields)
+	}
+
+	song, err := client.Create(ctx, &songsv1.CreateRequest{GroupName: "Muse", SongName: "Hysteria"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_, err = client.Create(ctx, &songsv1.CreateRequest{GroupName: "MUSE", SongName: "hysteria"})
+	st = status.Convert(err)
+	if st.Code() != codes.AlreadyExists {
+		t.Fatalf("duplicate: %v, want AlreadyExists", err)
+	}
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		if detail, ok := detail.(*errdetails.ErrorInfo); ok {
+			info = detail
+		}
+	}
+	if info == nil || info.GetReason() != "ALREADY_EXISTS" || info.GetMetadata()["existing_id"] != fmt.Sprint(song.GetId()) {
+		t.Errorf("error info %v, want ALREADY_EXISTS with existing_id %d", info, song.GetId())
+	}
+}
+
+func TestExportStreamsAllPages(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemorySongRepository()
+	songs := make([]models.Song, 150)
+	for i := range songs {
+		songs[i] = models.Song{GroupName: "Muse", SongName: fmt.Sprintf("Song %d", i)}
+	}
+	songs = append(songs, models.Song{GroupName: "Queen", SongName: "Innuendo"})
+	if _, err := repo.CreateSongs(ctx, songs); err != nil {
+		t.Fatalf("CreateSongs: %v", err)
+	}
+	client := newClient(t, repo)
+
+	count := func(filter *songsv1.SongFilter) int {
+		t.Helper()
+		stream, err := client.Export(ctx, &songsv1.ExportRequest{Filter: filter})
+		if err != nil {
+			t.Fatalf("Export: %v", err)
+		}
+		seen := map[int64]bool{}
+		for {
+			song, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return len(seen)
+			}
+			if err != nil {
+				t.Fatalf("Recv: %v", err)
+			}
+			seen[song.GetId()] = true
+		}
+	}
+
+	if got := count(&songsv1.SongFilter{GroupName: "muse"}); got != 150 {
+		t.Errorf("exported %d songs of Muse, want 150", got)
+	}
+	if got := count(&songsv1.SongFilter{GroupName: "Nobody"}); got != 0 {
+		t.Errorf("exported %d songs of unknown group, want 0", got)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/tracing"
+	"github.com/testTask/internal/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// eventBatchSize сколько событий читать из outbox за раз
+const eventBatchSize = 100
+
+// EventService читает события об изменении песен из outbox для потока событий
+type EventService struct {
+	repo   repository.SongRepository
+	logger *zap.Logger
+}
+
+func NewEventService(repo repository.SongRepository, logger *zap.Logger) *EventService {
+	return &EventService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// log возвращает логгер текущего запроса с его идентификатором
+func (s *EventService) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// OpenStream проверяет фильтр и возвращает ID, после которого начинать поток.
+// Без lastEventID поток начинается с новых событий.
+func (s *EventService) OpenStream(ctx context.Context, filter *models.EventFilter, lastEventID *int64) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.OpenStream")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Opening event stream",
+		zap.String("group", filter.GroupName),
+		zap.Strings("eventTypes", filter.Types),
+		zap.Int64p("lastEventId", lastEventID))
+
+	v := validation.New()
+	validation.EventFilter(v, filter)
+	if lastEventID != nil {
+		v.Check(*lastEventID >= 0, "Last-Event-ID", "must not be negative")
+	}
+	if err := v.Err(); err != nil {
+		return 0, err
+	}
+
+	if lastEventID != nil {
+		return *lastEventID, nil
+	}
+	return s.repo.GetLastOutboxEventID(ctx)
+}
+
+// GetEvents читает очередную пачку событий после afterID и возвращает подходящие
+// под фильтр вместе с ID последнего прочитанного события. Если новых событий нет,
+// возвращается afterID.
+func (s *EventService) GetEvents(ctx context.Context, afterID int64, filter *models.EventFilter) (_ []models.OutboxEvent, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "EventService.GetEvents", attribute.Int64("event.after_id", afterID))
+	defer func() { tracing.End(span, err) }()
+
+	batch, err := s.repo.GetOutboxEvents(ctx, afterID, eventBatchSize)
+	if err != nil {
+		return nil, afterID, err
+	}
+	if len(batch) == 0 {
+		return nil, afterID, nil
+	}
+
+	group := repository.NewNaturalKey(filter.GroupName, "").Group
+	matched := make([]models.OutboxEvent, 0, len(batch))
+	for _, event := range batch {
+		if len(filter.Types) > 0 && !containsString(filter.Types, event.Type) {
+			continue
+		}
+		if group != "" {
+			var song struct {
+				GroupName string `json:"group_name"`
+			}
+			if err := json.Unmarshal(event.Payload, &song); err != nil {
+				return nil, afterID, errors.NewInternal("failed to decode song event", err)
+			}
+			if repository.NewNaturalKey(song.GroupName, "").Group != group {
+				continue
+			}
+		}
+		matched = append(matched, event)
+	}
+	return matched, batch[len(batch)-1].ID, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -6,8 +6,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/logging"
 	"github.com/testTask/internal/models"
 	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/tracing"
+	"github.com/testTask/internal/validation"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -23,9 +28,17 @@ func NewSongService(repo repository.SongRepository, logger *zap.Logger) *SongSer
 	}
 }
 
+// log возвращает логгер текущего запроса с его идентификатором
+func (s *SongService) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
 // GetSongs получает список песен с опциональным фильтром
-func (s *SongService) GetSongs(ctx context.Context, filter *models.SongFilter) (*models.SongsResponse, error) {
-	s.logger.Info("Getting songs with filter",
+func (s *SongService) GetSongs(ctx context.Context, filter *models.SongFilter) (_ *models.SongsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetSongs")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting songs with filter",
 		zap.String("group", filter.GroupName),
 		zap.String("song", filter.SongName),
 		zap.Any("fromDate", filter.FromDate),
@@ -35,16 +48,31 @@ func (s *SongService) GetSongs(ctx context.Context, filter *models.SongFilter) (
 		zap.Int("page", filter.Page),
 		zap.Int("pageSize", filter.PageSize))
 
+	v := validation.New()
+	validation.SongFilter(v, filter)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	return s.repo.GetSongs(ctx, filter)
 }
 
 // GetLyrics получает текст песни с опциональным фильтром
-func (s *SongService) GetLyrics(ctx context.Context, id, page, pageSize int) (*models.LyricsResponse, error) {
-	s.logger.Info("Getting lyrics",
+func (s *SongService) GetLyrics(ctx context.Context, id, page, pageSize int) (_ *models.LyricsResponse, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetLyrics", attribute.Int("song.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting lyrics",
 		zap.Int("songId", id),
 		zap.Int("page", page),
 		zap.Int("pageSize", pageSize))
 
+	v := validation.New()
+	validation.Pagination(v, page, pageSize)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	song, err := s.repo.GetSongByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -54,7 +82,7 @@ func (s *SongService) GetLyrics(ctx context.Context, id, page, pageSize int) (*m
 		return nil, errors.NewLyricsNotFound("lyrics not found", nil)
 	}
 
-	verses := strings.Split(song.Text, "\n\n")
+	verses := SplitVerses(song.Text)
 	if page <= 0 {
 		page = 1
 	}
@@ -81,12 +109,95 @@ func (s *SongService) GetLyrics(ctx context.Context, id, page, pageSize int) (*m
 	}, nil
 }
 
+// GetSong получает песню по ID и встраивает связанные данные из include
+func (s *SongService) GetSong(ctx context.Context, id int, include []string) (_ *models.SongDetails, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetSong", attribute.Int("song.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting song",
+		zap.Int("id", id),
+		zap.Strings("include", include))
+
+	v := validation.New()
+	validation.Values(v, "include", include, models.SongIncludes)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	song, err := s.repo.GetSongByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &models.SongDetails{Song: *song}
+	for _, name := range include {
+		switch name {
+		case models.IncludeLyrics:
+			details.Lyrics = SplitVerses(song.Text)
+		case models.IncludeGroup:
+			if details.Group, err = s.repo.GetGroup(ctx, song.GroupName); err != nil {
+				return nil, err
+			}
+		case models.IncludeRevisions:
+			count, err := s.repo.CountSongRevisions(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			details.RevisionCount = &count
+		}
+	}
+
+	return details, nil
+}
+
+// GetGroups получает сведения о нескольких группах одним запросом. Результат
+// выровнен по names, для группы без песен в нем nil.
+func (s *SongService) GetGroups(ctx context.Context, names []string) (_ []*models.GroupDetails, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetGroups", attribute.Int("group.count", len(names)))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Debug("Getting groups", zap.Strings("names", names))
+
+	counts, err := s.repo.CountSongsByGroup(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*models.GroupDetails, len(names))
+	for i, name := range names {
+		if count := counts[repository.NewNaturalKey(name, "").Group]; count > 0 {
+			groups[i] = &models.GroupDetails{Name: name, SongCount: count}
+		}
+	}
+	return groups, nil
+}
+
+// GetRevisionCounts получает количество сохраненных версий нескольких песен одним
+// запросом. Песен без версий в результате нет.
+func (s *SongService) GetRevisionCounts(ctx context.Context, ids []int) (_ map[int]int, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetRevisionCounts", attribute.Int("song.count", len(ids)))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Debug("Getting revision counts", zap.Ints("ids", ids))
+
+	return s.repo.CountRevisionsBySong(ctx, ids)
+}
+
 // CreateSong создает новую песню
-func (s *SongService) CreateSong(ctx context.Context, req *models.SongRequest) (*models.Song, error) {
-	s.logger.Info("Creating new song",
+func (s *SongService) CreateSong(ctx context.Context, req *models.SongRequest) (_ *models.Song, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.CreateSong")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Creating new song",
 		zap.String("group", req.GroupName),
 		zap.String("song", req.SongName))
 
+	v := validation.New()
+	validation.SongRequest(v, req, false)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	// Создаем песню с предоставленными данными
 	song := &models.Song{
 		GroupName:   req.GroupName,
@@ -96,41 +207,175 @@ func (s *SongService) CreateSong(ctx context.Context, req *models.SongRequest) (
 		Link:        req.Link,
 	}
 
-	return s.repo.CreateSong(ctx, song)
+	// Песня, ее первая версия и событие о создании сохраняются вместе
+	err = s.repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		if _, err := repo.CreateSong(ctx, song); err != nil {
+			return err
+		}
+		if err := repo.CreateSongRevision(ctx, song); err != nil {
+			return err
+		}
+		return writeEvent(ctx, repo, events.SongCreated, song)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return song, nil
+}
+
+// GetSongByKey получает песню по группе и названию без учета регистра и лишних пробелов
+func (s *SongService) GetSongByKey(ctx context.Context, groupName, songName string) (_ *models.Song, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.GetSongByKey")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting song by key",
+		zap.String("group", groupName),
+		zap.String("song", songName))
+
+	v := validation.New()
+	validation.SongKey(v, groupName, songName)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetSongByKey(ctx, groupName, songName)
+}
+
+// UpsertSongByKey создает песню или заменяет песню с той же группой и названием.
+// Второе значение сообщает, была ли песня создана.
+func (s *SongService) UpsertSongByKey(ctx context.Context, req *models.SongRequest) (_ *models.Song, created bool, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.UpsertSongByKey")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Upserting song by key",
+		zap.String("group", req.GroupName),
+		zap.String("song", req.SongName))
+
+	v := validation.New()
+	validation.SongRequest(v, req, false)
+	if err := v.Err(); err != nil {
+		return nil, false, err
+	}
+
+	// Дата выпуска используется только при создании, замена ее не трогает
+	song := &models.Song{
+		GroupName:   req.GroupName,
+		SongName:    req.SongName,
+		ReleaseDate: time.Now(),
+		Text:        req.Text,
+		Link:        req.Link,
+	}
+
+	err = s.repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		_, isNew, err := repo.UpsertSongByKey(ctx, song)
+		if err != nil {
+			return err
+		}
+		created = isNew
+		if err := repo.CreateSongRevision(ctx, song); err != nil {
+			return err
+		}
+		eventType := events.SongUpdated
+		if isNew {
+			eventType = events.SongCreated
+		}
+		return writeEvent(ctx, repo, eventType, song)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return song, created, nil
 }
 
 // UpdateSong обновляет существующую песню
-func (s *SongService) UpdateSong(ctx context.Context, id int, req *models.SongRequest) (*models.Song, error) {
-	s.logger.Info("Updating song",
+func (s *SongService) UpdateSong(ctx context.Context, id int, req *models.SongRequest) (_ *models.Song, err error) {
+	ctx, span := tracing.Start(ctx, "SongService.UpdateSong", attribute.Int("song.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Updating song",
 		zap.Int("id", id),
 		zap.String("group", req.GroupName),
 		zap.String("song", req.SongName))
 
-	song, err := s.repo.GetSongByID(ctx, id)
-	if err != nil {
+	// При обновлении пустые поля не меняются, поэтому обязательность не проверяем
+	v := validation.New()
+	validation.SongRequest(v, req, true)
+	if err := v.Err(); err != nil {
 		return nil, err
 	}
 
-	// Обновляем только предоставленные поля
-	if req.GroupName != "" {
-		song.GroupName = req.GroupName
-	}
-	if req.SongName != "" {
-		song.SongName = req.SongName
-	}
-	if req.Text != "" {
-		song.Text = req.Text
-	}
-	if req.Link != "" {
-		song.Link = req.Link
-	}
-	song.UpdatedAt = time.Now()
+	// Чтение, изменение и запись версии идут в одной транзакции. Repeatable read
+	// не даст молча затереть параллельное изменение: такая транзакция повторится.
+	var song *models.Song
+	err = s.repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		found, err := repo.GetSongByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		song = found
 
-	return s.repo.UpdateSong(ctx, song)
+		// Обновляем только предоставленные поля
+		if req.GroupName != "" {
+			song.GroupName = req.GroupName
+		}
+		if req.SongName != "" {
+			song.SongName = req.SongName
+		}
+		if req.Text != "" {
+			song.Text = req.Text
+		}
+		if req.Link != "" {
+			song.Link = req.Link
+		}
+		song.UpdatedAt = time.Now()
+
+		if _, err := repo.UpdateSong(ctx, song); err != nil {
+			return err
+		}
+		if err := repo.CreateSongRevision(ctx, song); err != nil {
+			return err
+		}
+		return writeEvent(ctx, repo, events.SongUpdated, song)
+	}, repository.WithMinIsolation(repository.RepeatableRead))
+	if err != nil {
+		return nil, err
+	}
+	return song, nil
 }
 
 // DeleteSong удаляет существующую песню
-func (s *SongService) DeleteSong(ctx context.Context, id int) error {
-	s.logger.Info("Deleting song", zap.Int("id", id))
-	return s.repo.DeleteSong(ctx, id)
+func (s *SongService) DeleteSong(ctx context.Context, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "SongService.DeleteSong", attribute.Int("song.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Deleting song", zap.Int("id", id))
+
+	// В событие об удалении попадает песня в том виде, в каком ее удалили
+	return s.repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		song, err := repo.GetSongByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := repo.DeleteSong(ctx, id); err != nil {
+			return err
+		}
+		return writeEvent(ctx, repo, events.SongDeleted, song)
+	})
+}
+
+// writeEvent записывает событие об изменении песни в outbox текущей транзакции
+func writeEvent(ctx context.Context, repo repository.SongRepository, eventType events.Type, song *models.Song) error {
+	event, err := repository.NewOutboxEvent(eventType, song)
+	if err != nil {
+		return errors.NewInternal("failed to encode song event", err)
+	}
+	return repo.CreateOutboxEvent(ctx, event)
+}
+
+// SplitVerses делит текст песни на куплеты по пустым строкам
+func SplitVerses(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n\n")
 }
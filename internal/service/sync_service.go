@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/tracing"
+	"github.com/testTask/internal/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// defaultSyncLimit количество изменений в ответе синхронизации без параметра limit
+const defaultSyncLimit = 100
+
+// syncTokenVersion префикс токена синхронизации с транзакцией и номером изменения
+const syncTokenVersion = "v2:"
+
+// syncTokenV1 префикс токенов, выданных до появления транзакции в токене.
+// В них только номер, такие изменения записаны с нулевой транзакцией.
+const syncTokenV1 = "v1:"
+
+// SyncService отдает изменения библиотеки для синхронизации офлайн клиентов
+type SyncService struct {
+	repo   repository.SongRepository
+	logger *zap.Logger
+}
+
+func NewSyncService(repo repository.SongRepository, logger *zap.Logger) *SyncService {
+	return &SyncService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// log возвращает логгер текущего запроса с его идентификатором
+func (s *SyncService) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// GetChanges возвращает до limit изменений после токена. Пустой токен начинает
+// синхронизацию с нуля. Токен старше горизонта синхронизации или не выданный
+// этой базой дает ResyncRequired: клиент мог пропустить удаления.
+func (s *SyncService) GetChanges(ctx context.Context, token string, limit int) (_ *models.SyncResponse, err error) {
+	ctx, span := tracing.Start(ctx, "SyncService.GetChanges", attribute.Int("sync.limit", limit))
+	defer func() { tracing.End(span, err) }()
+
+	v := validation.New()
+	var since models.SyncPosition
+	if token != "" {
+		var ok bool
+		since, ok = decodeSyncToken(token)
+		v.Check(ok, "since", "must be a next_token of a previous sync response")
+	}
+	validation.Limit(v, "limit", limit, validation.MaxSyncLimit)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = defaultSyncLimit
+	}
+
+	s.log(ctx).Info("Getting song changes", zap.Int64("sinceTx", since.Tx), zap.Int64("sinceSeq", since.Seq), zap.Int("limit", limit))
+
+	// Горизонт и изменения читаются из одного снимка: чистка удалений между
+	// ними не должна остаться незамеченной
+	var changes []models.SongChange
+	err = s.repo.WithinTx(ctx, func(repo repository.SongRepository) error {
+		if token != "" {
+			bounds, err := repo.GetSyncBounds(ctx)
+			if err != nil {
+				return err
+			}
+			if since.Before(bounds.Horizon) {
+				return errors.NewResyncRequired("sync token is too old, deleted songs are already forgotten: start a full sync without since", nil)
+			}
+			if since.Seq > bounds.Latest {
+				return errors.NewResyncRequired("sync token was not issued by this library: start a full sync without since", nil)
+			}
+		}
+
+		// Лишнее изменение показывает, что за пачкой есть продолжение
+		changes, err = repo.GetSongChanges(ctx, since, limit+1)
+		return err
+	}, repository.WithMinIsolation(repository.RepeatableRead))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SyncResponse{
+		Songs:     []models.Song{},
+		Deleted:   []int{},
+		NextToken: encodeSyncToken(since),
+		HasMore:   len(changes) > limit,
+	}
+	if response.HasMore {
+		changes = changes[:limit]
+	}
+	for _, change := range changes {
+		if change.Song != nil {
+			response.Songs = append(response.Songs, *change.Song)
+		} else {
+			response.Deleted = append(response.Deleted, change.SongID)
+		}
+	}
+	if len(changes) > 0 {
+		response.NextToken = encodeSyncToken(changes[len(changes)-1].Position)
+	}
+	return response, nil
+}
+
+// PruneTombstones забывает удаления песен старше retention
+func (s *SyncService) PruneTombstones(ctx context.Context, retention time.Duration) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "SyncService.PruneTombstones")
+	defer func() { tracing.End(span, err) }()
+
+	pruned, err := s.repo.PruneTombstones(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if pruned > 0 {
+		s.log(ctx).Info("Pruned song tombstones", zap.Int("count", pruned), zap.Duration("retention", retention))
+	}
+	return pruned, nil
+}
+
+// encodeSyncToken собирает непрозрачный для клиента токен из места в последовательности изменений
+func encodeSyncToken(position models.SyncPosition) string {
+	raw := syncTokenVersion + strconv.FormatInt(position.Tx, 10) + ":" + strconv.FormatInt(position.Seq, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeSyncToken достает место в последовательности изменений из токена
+func decodeSyncToken(token string) (models.SyncPosition, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return models.SyncPosition{}, false
+	}
+	var tx, seq string
+	if digits, ok := strings.CutPrefix(string(raw), syncTokenV1); ok {
+		tx, seq = "0", digits
+	} else if digits, ok := strings.CutPrefix(string(raw), syncTokenVersion); ok {
+		if tx, seq, ok = strings.Cut(digits, ":"); !ok {
+			return models.SyncPosition{}, false
+		}
+	} else {
+		return models.SyncPosition{}, false
+	}
+
+	var position models.SyncPosition
+	if position.Tx, err = strconv.ParseInt(tx, 10, 64); err != nil || position.Tx < 0 {
+		return models.SyncPosition{}, false
+	}
+	if position.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil || position.Seq < 0 {
+		return models.SyncPosition{}, false
+	}
+	return position, true
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+
+	"github.com/testTask/internal/logging"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/tracing"
+	"github.com/testTask/internal/validation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// WebhookService управляет подписками на события и журналом их доставки
+type WebhookService struct {
+	repo   repository.WebhookRepository
+	logger *zap.Logger
+}
+
+func NewWebhookService(repo repository.WebhookRepository, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// log возвращает логгер текущего запроса с его идентификатором
+func (s *WebhookService) log(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// CreateWebhook регистрирует вебхук
+func (s *WebhookService) CreateWebhook(ctx context.Context, req *models.WebhookRequest) (_ *models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Creating webhook",
+		zap.String("url", req.URL),
+		zap.Strings("eventTypes", req.EventTypes))
+
+	v := validation.New()
+	validation.WebhookRequest(v, req)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.CreateWebhook(ctx, &models.Webhook{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+	})
+}
+
+// GetWebhooks возвращает все вебхуки
+func (s *WebhookService) GetWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetWebhooks")
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting webhooks")
+	return s.repo.GetWebhooks(ctx)
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом его доставок
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.DeleteWebhook", attribute.Int("webhook.id", id))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Deleting webhook", zap.Int("id", id))
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+// GetDeliveries возвращает страницу журнала доставок вебхука
+func (s *WebhookService) GetDeliveries(ctx context.Context, filter *models.DeliveryFilter) (_ *models.DeliveriesResponse, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDeliveries", attribute.Int("webhook.id", filter.WebhookID))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Getting webhook deliveries",
+		zap.Int("webhookId", filter.WebhookID),
+		zap.String("status", filter.Status),
+		zap.Int("page", filter.Page),
+		zap.Int("pageSize", filter.PageSize))
+
+	v := validation.New()
+	validation.DeliveryFilter(v, filter)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	// Пустой журнал несуществующего вебхука отличаем от пустого журнала существующего
+	if _, err := s.repo.GetWebhookByID(ctx, filter.WebhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(ctx, filter)
+}
+
+// ReplayDeliveries заново ставит в очередь доставку вебхуку всех разосланных
+// событий, начиная с fromEventID
+func (s *WebhookService) ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (_ *models.ReplayResponse, err error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ReplayDeliveries", attribute.Int("webhook.id", webhookID))
+	defer func() { tracing.End(span, err) }()
+
+	s.log(ctx).Info("Replaying webhook deliveries",
+		zap.Int("webhookId", webhookID),
+		zap.Int64("fromEventId", fromEventID))
+
+	v := validation.New()
+	v.Check(fromEventID >= 0, "from_event_id", "must not be negative")
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetWebhookByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	enqueued, err := s.repo.ReplayDeliveries(ctx, webhookID, fromEventID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReplayResponse{Enqueued: enqueued}, nil
+}
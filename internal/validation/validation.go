@@ -0,0 +1,179 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/models"
+)
+
+// Ограничения, совпадающие со схемой таблицы songs
+const (
+	MaxGroupNameLength = 255
+	MaxSongNameLength  = 255
+	MaxLinkLength      = 255
+)
+
+// Ограничения, совпадающие со схемой таблицы webhooks
+const (
+	MaxWebhookURLLength    = 2048
+	MinWebhookSecretLength = 16
+	MaxWebhookSecretLength = 255
+)
+
+// MaxPageSize максимальный размер страницы в списках
+const MaxPageSize = 100
+
+// MaxSyncLimit максимальное количество изменений в одном ответе синхронизации
+const MaxSyncLimit = 1000
+
+// eventTypes виды событий, на которые можно подписать вебхук
+var eventTypes = []string{string(events.SongCreated), string(events.SongUpdated), string(events.SongDeleted)}
+
+// Errors собирает ошибки по всем полям, чтобы вернуть их клиенту разом
+type Errors struct {
+	fields []errors.FieldError
+}
+
+// New создает пустой набор ошибок
+func New() *Errors {
+	return &Errors{}
+}
+
+// Add добавляет ошибку поля
+func (v *Errors) Add(field, message string) {
+	v.fields = append(v.fields, errors.FieldError{Field: field, Message: message})
+}
+
+// Check добавляет ошибку поля, если условие не выполнено
+func (v *Errors) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, message)
+	}
+}
+
+// Err возвращает ошибку Validation со всеми полями или nil
+func (v *Errors) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return errors.NewValidationFields("request validation failed", v.fields)
+}
+
+// SongRequest проверяет запрос на создание или обновление песни.
+// При partial пустые поля означают "не менять" и не считаются ошибкой.
+func SongRequest(v *Errors, req *models.SongRequest, partial bool) {
+	name(v, "group", req.GroupName, MaxGroupNameLength, partial)
+	name(v, "song", req.SongName, MaxSongNameLength, partial)
+
+	if req.Link != "" {
+		v.Check(utf8.RuneCountInString(req.Link) <= MaxLinkLength, "link", maxLengthMessage(MaxLinkLength))
+		v.Check(isHTTPURL(req.Link), "link", "must be an absolute http or https URL")
+	}
+}
+
+// SongKey проверяет естественный ключ песни: группу и название
+func SongKey(v *Errors, groupName, songName string) {
+	name(v, "group", groupName, MaxGroupNameLength, false)
+	name(v, "song", songName, MaxSongNameLength, false)
+}
+
+// Values проверяет, что все значения списочного параметра входят в allowed
+func Values(v *Errors, field string, values, allowed []string) {
+	for _, value := range values {
+		v.Check(contains(allowed, value), field, fmt.Sprintf("unknown value %q, expected any of %s", value, strings.Join(allowed, ", ")))
+	}
+}
+
+// SongFilter проверяет параметры фильтрации и пагинации списка песен
+func SongFilter(v *Errors, filter *models.SongFilter) {
+	v.Check(utf8.RuneCountInString(filter.GroupName) <= MaxGroupNameLength, "group_name", maxLengthMessage(MaxGroupNameLength))
+	v.Check(utf8.RuneCountInString(filter.SongName) <= MaxSongNameLength, "song_name", maxLengthMessage(MaxSongNameLength))
+	v.Check(utf8.RuneCountInString(filter.Link) <= MaxLinkLength, "link", maxLengthMessage(MaxLinkLength))
+
+	if filter.FromDate != nil && filter.ToDate != nil {
+		v.Check(!filter.FromDate.After(*filter.ToDate), "from_date", "must not be after to_date")
+	}
+
+	Pagination(v, filter.Page, filter.PageSize)
+}
+
+// Pagination проверяет номер и размер страницы, 0 означает значение по умолчанию
+func Pagination(v *Errors, page, pageSize int) {
+	v.Check(page >= 0, "page", "must not be negative")
+	Limit(v, "page_size", pageSize, MaxPageSize)
+}
+
+// Limit проверяет необязательное ограничение количества записей, 0 означает значение по умолчанию
+func Limit(v *Errors, field string, value, maxValue int) {
+	v.Check(value >= 0 && value <= maxValue, field, fmt.Sprintf("must be between 1 and %d, or 0 for the default", maxValue))
+}
+
+// WebhookRequest проверяет запрос на регистрацию вебхука
+func WebhookRequest(v *Errors, req *models.WebhookRequest) {
+	if req.URL == "" {
+		v.Add("url", "is required")
+	} else {
+		v.Check(utf8.RuneCountInString(req.URL) <= MaxWebhookURLLength, "url", maxLengthMessage(MaxWebhookURLLength))
+		v.Check(isHTTPURL(req.URL), "url", "must be an absolute http or https URL")
+	}
+
+	secretLength := utf8.RuneCountInString(req.Secret)
+	v.Check(secretLength >= MinWebhookSecretLength && secretLength <= MaxWebhookSecretLength, "secret",
+		fmt.Sprintf("must be between %d and %d characters", MinWebhookSecretLength, MaxWebhookSecretLength))
+
+	Values(v, "event_types", req.EventTypes, eventTypes)
+}
+
+// EventFilter проверяет фильтр потока событий
+func EventFilter(v *Errors, filter *models.EventFilter) {
+	v.Check(utf8.RuneCountInString(filter.GroupName) <= MaxGroupNameLength, "group", maxLengthMessage(MaxGroupNameLength))
+	Values(v, "event_types", filter.Types, eventTypes)
+}
+
+// DeliveryFilter проверяет параметры журнала доставок вебхука
+func DeliveryFilter(v *Errors, filter *models.DeliveryFilter) {
+	if filter.Status != "" {
+		Values(v, "status", []string{filter.Status}, models.DeliveryStatuses)
+	}
+	Pagination(v, filter.Page, filter.PageSize)
+}
+
+// name проверяет обязательное строковое поле с ограничением длины
+func name(v *Errors, field, value string, maxLength int, partial bool) {
+	if value == "" {
+		v.Check(partial, field, "is required")
+		return
+	}
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "must not be blank")
+		return
+	}
+	v.Check(utf8.RuneCountInString(value) <= maxLength, field, maxLengthMessage(maxLength))
+}
+
+// isHTTPURL проверяет, что строка - абсолютный http(s) URL
+func isHTTPURL(value string) bool {
+	u, err := url.ParseRequestURI(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func maxLengthMessage(maxLength int) string {
+	return fmt.Sprintf("must be at most %d characters", maxLength)
+}
@@ -0,0 +1,242 @@
+package validation_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/errors"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/validation"
+)
+
+// fieldErrors возвращает ошибки полей из v, nil если ошибок нет
+func fieldErrors(t *testing.T, v *validation.Errors) []errors.FieldError {
+	t.Helper()
+	err := v.Err()
+	if err == nil {
+		return nil
+	}
+	appErr, ok := errors.As(err)
+	if !ok || appErr.Type != errors.Validation {
+		t.Fatalf("Err() = %v, want a VALIDATION error", err)
+	}
+	return appErr.Fields
+}
+
+func TestErrors(t *testing.T) {
+	v := validation.New()
+	if err := v.Err(); err != nil {
+		t.Fatalf("empty Err() = %v, want nil", err)
+	}
+
+	v.Check(true, "page", "must not be negative")
+	v.Check(false, "group", "is required")
+	v.Add("song", "is required")
+	want := []errors.FieldError{
+		{Field: "group", Message: "is required"},
+		{Field: "song", Message: "is required"},
+	}
+	if got := fieldErrors(t, v); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields %v, want %v", got, want)
+	}
+}
+
+func TestSongRequest(t *testing.T) {
+	long := strings.Repeat("я", validation.MaxGroupNameLength+1)
+
+	tests := []struct {
+		name    string
+		req     models.SongRequest
+		partial bool
+		want    []errors.FieldError
+	}{
+		{
+			name: "valid",
+			req:  models.SongRequest{GroupName: "Muse", SongName: "Hysteria", Link: "https://example.com/hysteria"},
+		},
+		{
+			name: "missing names",
+			req:  models.SongRequest{},
+			want: []errors.FieldError{{Field: "group", Message: "is required"}, {Field: "song", Message: "is required"}},
+		},
+		{
+			name:    "partial update without names",
+			req:     models.SongRequest{Text: "new text"},
+			partial: true,
+		},
+		{
+			name:    "blank name in partial update",
+			req:     models.SongRequest{GroupName: "  "},
+			partial: true,
+			want:    []errors.FieldError{{Field: "group", Message: "must not be blank"}},
+		},
+		{
+			name: "too long name counted in characters",
+			req:  models.SongRequest{GroupName: long, SongName: strings.Repeat("я", validation.MaxSongNameLength)},
+			want: []errors.FieldError{{Field: "group", Message: "must be at most 255 characters"}},
+		},
+		{
+			name: "relative link",
+			req:  models.SongRequest{GroupName: "Muse", SongName: "Hysteria", Link: "/songs/1"},
+			want: []errors.FieldError{{Field: "link", Message: "must be an absolute http or https URL"}},
+		},
+		{
+			name: "link with another scheme",
+			req:  models.SongRequest{GroupName: "Muse", SongName: "Hysteria", Link: "ftp://example.com/hysteria"},
+			want: []errors.FieldError{{Field: "link", Message: "must be an absolute http or https URL"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			validation.SongRequest(v, &tt.req, tt.partial)
+			if got := fieldErrors(t, v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSongFilter(t *testing.T) {
+	day := func(value string) *time.Time {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatalf("bad date %q: %v", value, err)
+		}
+		return &date
+	}
+
+	tests := []struct {
+		name   string
+		filter models.SongFilter
+		want   []errors.FieldError
+	}{
+		{
+			name:   "empty",
+			filter: models.SongFilter{},
+		},
+		{
+			name:   "same day range",
+			filter: models.SongFilter{FromDate: day("2024-01-01"), ToDate: day("2024-01-01")},
+		},
+		{
+			name:   "reversed range",
+			filter: models.SongFilter{FromDate: day("2024-02-01"), ToDate: day("2024-01-01")},
+			want:   []errors.FieldError{{Field: "from_date", Message: "must not be after to_date"}},
+		},
+		{
+			name:   "too long names",
+			filter: models.SongFilter{GroupName: strings.Repeat("a", 256), SongName: strings.Repeat("a", 256)},
+			want: []errors.FieldError{
+				{Field: "group_name", Message: "must be at most 255 characters"},
+				{Field: "song_name", Message: "must be at most 255 characters"},
+			},
+		},
+		{
+			name:   "all errors at once",
+			filter: models.SongFilter{Link: strings.Repeat("a", 256), Page: -1, PageSize: 101},
+			want: []errors.FieldError{
+				{Field: "link", Message: "must be at most 255 characters"},
+				{Field: "page", Message: "must not be negative"},
+				{Field: "page_size", Message: "must be between 1 and 100, or 0 for the default"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			validation.SongFilter(v, &tt.filter)
+			if got := fieldErrors(t, v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	pageSizeError := errors.FieldError{Field: "page_size", Message: "must be between 1 and 100, or 0 for the default"}
+
+	tests := []struct {
+		name     string
+		page     int
+		pageSize int
+		want     []errors.FieldError
+	}{
+		{name: "defaults", page: 0, pageSize: 0},
+		{name: "first page", page: 1, pageSize: 1},
+		{name: "largest page", page: 1000, pageSize: validation.MaxPageSize},
+		{name: "negative page", page: -1, pageSize: 10, want: []errors.FieldError{{Field: "page", Message: "must not be negative"}}},
+		{name: "negative page size", page: 1, pageSize: -1, want: []errors.FieldError{pageSizeError}},
+		{name: "page size over the limit", page: 1, pageSize: validation.MaxPageSize + 1, want: []errors.FieldError{pageSizeError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			validation.Pagination(v, tt.page, tt.pageSize)
+			if got := fieldErrors(t, v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookRequest(t *testing.T) {
+	secret := strings.Repeat("s", validation.MinWebhookSecretLength)
+	secretError := errors.FieldError{Field: "secret", Message: "must be between 16 and 255 characters"}
+	// Путь из кириллицы: в байтах длиннее ограничения, в символах укладывается
+	cyrillicURL := "https://example.com/" + strings.Repeat("я", validation.MaxWebhookURLLength-len("https://example.com/"))
+
+	tests := []struct {
+		name string
+		req  models.WebhookRequest
+		want []errors.FieldError
+	}{
+		{
+			name: "valid",
+			req:  models.WebhookRequest{URL: "https://example.com/hooks", Secret: secret, EventTypes: []string{"song.deleted"}},
+		},
+		{
+			name: "longest url counted in characters",
+			req:  models.WebhookRequest{URL: cyrillicURL, Secret: secret},
+		},
+		{
+			name: "too long url",
+			req:  models.WebhookRequest{URL: cyrillicURL + "я", Secret: secret},
+			want: []errors.FieldError{{Field: "url", Message: "must be at most 2048 characters"}},
+		},
+		{
+			name: "missing url and secret",
+			req:  models.WebhookRequest{},
+			want: []errors.FieldError{{Field: "url", Message: "is required"}, secretError},
+		},
+		{
+			name: "relative url",
+			req:  models.WebhookRequest{URL: "/hooks", Secret: secret},
+			want: []errors.FieldError{{Field: "url", Message: "must be an absolute http or https URL"}},
+		},
+		{
+			name: "short secret counted in characters",
+			req:  models.WebhookRequest{URL: "https://example.com/hooks", Secret: strings.Repeat("я", validation.MinWebhookSecretLength-1)},
+			want: []errors.FieldError{secretError},
+		},
+		{
+			name: "unknown event type",
+			req:  models.WebhookRequest{URL: "https://example.com/hooks", Secret: secret, EventTypes: []string{"song.played"}},
+			want: []errors.FieldError{{
+				Field:   "event_types",
+				Message: `unknown value "song.played", expected any of song.created, song.updated, song.deleted`,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validation.New()
+			validation.WebhookRequest(v, &tt.req)
+			if got := fieldErrors(t, v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fields %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
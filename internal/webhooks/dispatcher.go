@@ -0,0 +1,258 @@
+// Package webhooks доставляет события из outbox на зарегистрированные вебхуки.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/testTask/internal/metrics"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// Заголовки запроса с событием
+const (
+	HeaderWebhookID  = "X-Webhook-Id"
+	HeaderDeliveryID = "X-Delivery-Id"
+	HeaderEventID    = "X-Event-Id"
+	HeaderEventType  = "X-Event-Type"
+	// HeaderTimestamp время отправки в секундах Unix, входит в подпись
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature подпись вида sha256=<hex>, см. Sign
+	HeaderSignature = "X-Signature-256"
+)
+
+const (
+	// batchSize сколько событий раскладывать и доставок отправлять за один проход
+	batchSize = 100
+	// leaseMargin запас аренды доставки сверх таймаута запроса на запись результата
+	leaseMargin = 30 * time.Second
+	// maxErrorLength сколько байт ошибки сохранять в журнале доставок
+	maxErrorLength = 1000
+)
+
+// Итоги попытки доставки в метрике
+const (
+	resultDelivered = "delivered"
+	resultRetry     = "retry"
+	resultFailed    = "failed"
+)
+
+// Config настройки диспетчера
+type Config struct {
+	PollInterval    time.Duration
+	Timeout         time.Duration
+	MaxAttempts     int
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+}
+
+// Dispatcher раскладывает события outbox по вебхукам и доставляет их с повторами.
+// Несколько экземпляров сервиса могут работать с одной базой: доставку берет
+// в работу только один из них.
+type Dispatcher struct {
+	repo       repository.WebhookRepository
+	config     Config
+	client     *http.Client
+	logger     *zap.Logger
+	wake       chan struct{}
+	deliveries metric.Int64Counter
+}
+
+// NewDispatcher создает диспетчер и регистрирует метрику доставок
+func NewDispatcher(repo repository.WebhookRepository, cfg Config, logger *zap.Logger) (*Dispatcher, error) {
+	deliveries, err := metrics.Meter().Int64Counter("webhook.deliveries",
+		metric.WithDescription("Webhook delivery attempts by result"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook metric: %w", err)
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		config: cfg,
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// Перенаправление считается неуспешной доставкой, а не поводом отправить событие на другой адрес
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		logger:     logger,
+		wake:       make(chan struct{}, 1),
+		deliveries: deliveries,
+	}, nil
+}
+
+// Run доставляет события до отмены ctx. Доставки, прерванные остановкой,
+// повторятся после истечения их аренды.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Wake просит диспетчер проверить новые события, не дожидаясь интервала опроса
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// poll раскладывает новые события и отправляет все доставки, время которых пришло
+func (d *Dispatcher) poll(ctx context.Context) {
+	for ctx.Err() == nil {
+		enqueued, err := d.repo.EnqueueDeliveries(ctx, batchSize)
+		if err != nil {
+			d.logger.Error("Failed to enqueue webhook deliveries", zap.Error(err))
+			break
+		}
+		if enqueued < batchSize {
+			break
+		}
+	}
+
+	for ctx.Err() == nil {
+		claimed, err := d.repo.ClaimDeliveries(ctx, batchSize, d.config.Timeout+leaseMargin)
+		if err != nil {
+			d.logger.Error("Failed to claim webhook deliveries", zap.Error(err))
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range claimed {
+			wg.Add(1)
+			go func(delivery models.PendingDelivery) {
+				defer wg.Done()
+				d.deliver(ctx, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(claimed) < batchSize {
+			return
+		}
+	}
+}
+
+// deliver отправляет событие и сохраняет итог попытки
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.PendingDelivery) {
+	statusCode, err := d.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Остановка прервала запрос, это не вина получателя
+		return
+	}
+
+	result := &models.DeliveryResult{Status: models.DeliveryDelivered, StatusCode: statusCode}
+	outcome := resultDelivered
+	if err != nil {
+		result.Error = truncate(err.Error(), maxErrorLength)
+		if delivery.Attempt >= d.config.MaxAttempts {
+			result.Status = models.DeliveryFailed
+			outcome = resultFailed
+		} else {
+			result.Status = models.DeliveryPending
+			result.NextAttemptAt = time.Now().Add(d.backoff(delivery.Attempt))
+			outcome = resultRetry
+		}
+
+		d.logger.Warn("Webhook delivery failed",
+			zap.Int64("deliveryId", delivery.ID),
+			zap.Int("webhookId", delivery.Webhook.ID),
+			zap.Int64("eventId", delivery.Event.ID),
+			zap.Int("attempt", delivery.Attempt),
+			zap.String("status", result.Status),
+			zap.Error(err))
+	}
+	d.deliveries.Add(ctx, 1, metric.WithAttributes(attribute.String("result", outcome)))
+
+	if err := d.repo.CompleteDelivery(ctx, delivery.ID, result); err != nil {
+		d.logger.Error("Failed to save webhook delivery result",
+			zap.Int64("deliveryId", delivery.ID),
+			zap.Error(err))
+	}
+}
+
+// send отправляет событие получателю. Успехом считается только ответ 2xx.
+func (d *Dispatcher) send(ctx context.Context, delivery models.PendingDelivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "music-library-webhooks")
+	req.Header.Set(HeaderWebhookID, strconv.Itoa(delivery.Webhook.ID))
+	req.Header.Set(HeaderDeliveryID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderEventID, strconv.FormatInt(delivery.Event.ID, 10))
+	req.Header.Set(HeaderEventType, delivery.Event.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но дочитываем его, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff задержка перед повтором после неудачной попытки attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.RetryBackoff
+	for i := 1; i < attempt && delay < d.config.RetryMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.config.RetryMaxBackoff)
+}
+
+// Sign подписывает тело запроса: sha256= и HMAC-SHA256 ключом secret от строки
+// "<timestamp>.<body>" в hex. Получатель считает подпись так же и сравнивает
+// ее с заголовком X-Signature-256, а по timestamp отбрасывает старые запросы.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// truncate обрезает строку до maxLength байт, не разрезая символы
+func truncate(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	return strings.ToValidUTF8(s[:maxLength], "")
+}
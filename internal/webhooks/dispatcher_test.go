@@ -0,0 +1,163 @@
+package webhooks_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/events"
+	"github.com/testTask/internal/models"
+	"github.com/testTask/internal/repository"
+	"github.com/testTask/internal/webhooks"
+	"go.uber.org/zap"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver получатель вебхуков, который отвечает кодами из statuses по очереди
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	received []models.OutboxEvent
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("failed to read body: %v", err)
+		return
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+	if err != nil {
+		rc.t.Errorf("invalid timestamp header: %v", err)
+	}
+	if got, want := r.Header.Get(webhooks.HeaderSignature), webhooks.Sign(secret, timestamp, body); got != want {
+		rc.t.Errorf("signature %q, want %q", got, want)
+	}
+
+	var event models.OutboxEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		rc.t.Errorf("invalid event %s: %v", body, err)
+	}
+	if got := r.Header.Get(webhooks.HeaderEventType); got != event.Type {
+		rc.t.Errorf("event type header %q, want %q", got, event.Type)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.received = append(rc.received, event)
+	status := http.StatusNoContent
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// setup создает вебхук на сервер с receiver, событие о новой песне и запускает диспетчер
+func setup(t *testing.T, rc *receiver, maxAttempts int) repository.WebhookRepository {
+	t.Helper()
+	ctx := context.Background()
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	songs := repository.NewMemorySongRepository()
+	repo := repository.NewMemoryWebhookRepository(songs)
+	if _, err := repo.CreateWebhook(ctx, &models.Webhook{URL: server.URL, Secret: secret}); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	song, err := songs.CreateSong(ctx, &models.Song{GroupName: "Muse", SongName: "Hysteria", ReleaseDate: time.Now()})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	event, err := repository.NewOutboxEvent(events.SongCreated, song)
+	if err != nil {
+		t.Fatalf("NewOutboxEvent: %v", err)
+	}
+	if err := songs.CreateOutboxEvent(ctx, event); err != nil {
+		t.Fatalf("CreateOutboxEvent: %v", err)
+	}
+
+	dispatcher, err := webhooks.NewDispatcher(repo, webhooks.Config{
+		PollInterval:    5 * time.Millisecond,
+		Timeout:         time.Second,
+		MaxAttempts:     maxAttempts,
+		RetryBackoff:    5 * time.Millisecond,
+		RetryMaxBackoff: 10 * time.Millisecond,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewDispatcher: %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dispatcher.Run(runCtx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return repo
+}
+
+// waitDelivery ждет, пока доставка первому вебхуку выйдет из статуса pending
+func waitDelivery(t *testing.T, repo repository.WebhookRepository) models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		log, err := repo.GetDeliveries(context.Background(), &models.DeliveryFilter{WebhookID: 1})
+		if err != nil {
+			t.Fatalf("GetDeliveries: %v", err)
+		}
+		if len(log.Deliveries) == 1 && log.Deliveries[0].Status != models.DeliveryPending {
+			return log.Deliveries[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("delivery is still pending")
+	return models.WebhookDelivery{}
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	rc := &receiver{t: t, statuses: []int{http.StatusInternalServerError, http.StatusServiceUnavailable}}
+	repo := setup(t, rc, 5)
+
+	delivery := waitDelivery(t, repo)
+	if delivery.Status != models.DeliveryDelivered || delivery.Attempts != 3 || delivery.LastStatusCode != http.StatusNoContent {
+		t.Fatalf("delivery %+v, want delivered on third attempt", delivery)
+	}
+	if delivery.LastError != "" || delivery.DeliveredAt == nil {
+		t.Errorf("delivered delivery keeps error or has no delivery time: %+v", delivery)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.received) != 3 {
+		t.Fatalf("receiver got %d requests, want 3", len(rc.received))
+	}
+	if event := rc.received[2]; event.ID != delivery.EventID || event.Type != string(events.SongCreated) || len(event.Payload) == 0 {
+		t.Errorf("received event %+v", event)
+	}
+}
+
+func TestDispatcherFailsAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{t: t, statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusMovedPermanently}}
+	repo := setup(t, rc, 3)
+
+	delivery := waitDelivery(t, repo)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 3 {
+		t.Fatalf("delivery %+v, want failed after 3 attempts", delivery)
+	}
+	if delivery.LastStatusCode != http.StatusMovedPermanently || delivery.LastError != "unexpected status 301" {
+		t.Errorf("delivery keeps last status %d and error %q", delivery.LastStatusCode, delivery.LastError)
+	}
+}
@@ -0,0 +1,7 @@
+-- Restore uniqueness by the exact spelling
+ALTER TABLE songs DROP CONSTRAINT songs_natural_key;
+ALTER TABLE songs ADD CONSTRAINT songs_group_name_song_name_key UNIQUE (group_name, song_name);
+
+ALTER TABLE songs
+    DROP COLUMN group_key,
+    DROP COLUMN song_key;
@@ -0,0 +1,18 @@
+-- Normalized natural key of a song. The application fills it on every write
+-- using repository.NewNaturalKey, the same rules are repeated here only to
+-- backfill existing rows.
+ALTER TABLE songs
+    ADD COLUMN group_key VARCHAR(255),
+    ADD COLUMN song_key VARCHAR(255);
+
+UPDATE songs SET
+    group_key = lower(regexp_replace(regexp_replace(group_name, '^\s+|\s+$', '', 'g'), '\s+', ' ', 'g')),
+    song_key = lower(regexp_replace(regexp_replace(song_name, '^\s+|\s+$', '', 'g'), '\s+', ' ', 'g'));
+
+ALTER TABLE songs
+    ALTER COLUMN group_key SET NOT NULL,
+    ALTER COLUMN song_key SET NOT NULL;
+
+-- Uniqueness is defined by the natural key instead of the exact spelling
+ALTER TABLE songs DROP CONSTRAINT songs_group_name_song_name_key;
+ALTER TABLE songs ADD CONSTRAINT songs_natural_key UNIQUE (group_key, song_key);
@@ -0,0 +1,2 @@
+-- Drop the song history table
+DROP TABLE IF EXISTS song_revisions;
@@ -0,0 +1,18 @@
+-- History of song versions, one row per create or update
+CREATE TABLE IF NOT EXISTS song_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    song_id INTEGER NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    song_name VARCHAR(255) NOT NULL,
+    release_date DATE,
+    text TEXT,
+    link VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (song_id, revision)
+);
+
+-- Existing songs get their current state as the first revision
+INSERT INTO song_revisions (song_id, revision, group_name, song_name, release_date, text, link)
+SELECT id, 1, group_name, song_name, release_date, text, link
+FROM songs;
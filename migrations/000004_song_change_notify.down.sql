@@ -0,0 +1,3 @@
+-- Stop publishing song changes
+DROP TRIGGER IF EXISTS songs_notify_change ON songs;
+DROP FUNCTION IF EXISTS notify_song_change();
@@ -0,0 +1,25 @@
+-- Publish every song change to the song_changes channel so that other
+-- instances can drop their caches. The payload is small JSON: operation,
+-- song id and group name; subscribers read the song itself if they need it.
+CREATE OR REPLACE FUNCTION notify_song_change() RETURNS trigger AS $$
+DECLARE
+    song RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        song := OLD;
+    ELSE
+        song := NEW;
+    END IF;
+
+    PERFORM pg_notify('song_changes', json_build_object(
+        'op', TG_OP,
+        'id', song.id,
+        'group_name', song.group_name
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_notify_change
+    AFTER INSERT OR UPDATE OR DELETE ON songs
+    FOR EACH ROW EXECUTE FUNCTION notify_song_change();
@@ -0,0 +1,10 @@
+-- Drop the change sequence and tombstones
+DROP TRIGGER IF EXISTS songs_tombstone ON songs;
+DROP TRIGGER IF EXISTS songs_change_seq ON songs;
+DROP FUNCTION IF EXISTS record_song_tombstone();
+DROP FUNCTION IF EXISTS set_song_change_seq();
+DROP FUNCTION IF EXISTS next_song_change_seq();
+DROP TABLE IF EXISTS sync_horizon;
+DROP TABLE IF EXISTS song_tombstones;
+ALTER TABLE songs DROP COLUMN IF EXISTS change_seq;
+DROP SEQUENCE IF EXISTS song_change_seq;
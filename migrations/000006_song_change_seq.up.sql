@@ -0,0 +1,66 @@
+-- Monotonic change sequence for delta sync. Every insert and update of a song
+-- takes the next value into songs.change_seq, every delete leaves a tombstone
+-- with its own value. Values are taken under an advisory lock, so they commit
+-- in increasing order and a client resuming after the last value it has seen
+-- misses nothing. 000008_song_change_txid replaces the lock with transaction ids.
+CREATE SEQUENCE IF NOT EXISTS song_change_seq;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS change_seq BIGINT;
+
+-- Existing songs count as changed in the order they were created
+UPDATE songs SET change_seq = id WHERE change_seq IS NULL;
+SELECT setval('song_change_seq', MAX(change_seq)) FROM songs HAVING MAX(change_seq) IS NOT NULL;
+
+ALTER TABLE songs ALTER COLUMN change_seq SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS songs_change_seq_idx ON songs (change_seq);
+
+-- Deleted songs. Tombstones older than the retention period are pruned
+CREATE TABLE IF NOT EXISTS song_tombstones (
+    song_id INTEGER PRIMARY KEY,
+    change_seq BIGINT NOT NULL UNIQUE,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS song_tombstones_deleted_at_idx ON song_tombstones (deleted_at);
+
+-- Oldest change_seq a sync token may point to. Pruning tombstones moves it to
+-- the last pruned value: an older token could miss deletions and needs a full resync.
+CREATE TABLE IF NOT EXISTS sync_horizon (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    seq BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO sync_horizon DEFAULT VALUES ON CONFLICT DO NOTHING;
+
+CREATE OR REPLACE FUNCTION next_song_change_seq() RETURNS BIGINT AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(122550254464888);
+    RETURN nextval('song_change_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_song_change_seq() RETURNS trigger AS $$
+BEGIN
+    NEW.change_seq := next_song_change_seq();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_change_seq
+    BEFORE INSERT OR UPDATE ON songs
+    FOR EACH ROW EXECUTE FUNCTION set_song_change_seq();
+
+CREATE OR REPLACE FUNCTION record_song_tombstone() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO song_tombstones (song_id, change_seq)
+    VALUES (OLD.id, next_song_change_seq())
+    ON CONFLICT (song_id) DO UPDATE
+    SET change_seq = EXCLUDED.change_seq,
+        deleted_at = EXCLUDED.deleted_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_tombstone
+    AFTER DELETE ON songs
+    FOR EACH ROW EXECUTE FUNCTION record_song_tombstone();
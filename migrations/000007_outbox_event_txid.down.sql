@@ -0,0 +1,3 @@
+-- Drop the transaction id of outbox events
+DROP INDEX IF EXISTS outbox_events_txid_id_idx;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS txid;
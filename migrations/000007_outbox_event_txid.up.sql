@@ -0,0 +1,10 @@
+-- Transaction that wrote the event. Event ids are taken when the row is
+-- inserted, not when the transaction commits, so a reader walking by id alone
+-- could skip an event committed after a higher one. Readers instead return only
+-- events of transactions older than pg_snapshot_xmin(pg_current_snapshot()),
+-- which have all finished, in (txid, id) order: any event that becomes visible
+-- later belongs to a newer transaction and sorts after everything already read.
+-- Existing events get the txid of this migration and keep their id order.
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE INDEX IF NOT EXISTS outbox_events_txid_id_idx ON outbox_events (txid, id);
@@ -0,0 +1,33 @@
+-- Restore the trigger functions of 000006_song_change_seq and drop the
+-- transaction ids of song changes
+CREATE OR REPLACE FUNCTION record_song_tombstone() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO song_tombstones (song_id, change_seq)
+    VALUES (OLD.id, next_song_change_seq())
+    ON CONFLICT (song_id) DO UPDATE
+    SET change_seq = EXCLUDED.change_seq,
+        deleted_at = EXCLUDED.deleted_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_song_change_seq() RETURNS trigger AS $$
+BEGIN
+    NEW.change_seq := next_song_change_seq();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION next_song_change_seq() RETURNS BIGINT AS $$
+BEGIN
+    -- Song writes take numbers one at a time, so numbers commit in increasing order
+    PERFORM pg_advisory_xact_lock(122550254464888);
+    RETURN nextval('song_change_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS song_tombstones_change_txid_seq_idx;
+DROP INDEX IF EXISTS songs_change_txid_seq_idx;
+ALTER TABLE sync_horizon DROP COLUMN IF EXISTS txid;
+ALTER TABLE song_tombstones DROP COLUMN IF EXISTS change_txid;
+ALTER TABLE songs DROP COLUMN IF EXISTS change_txid;
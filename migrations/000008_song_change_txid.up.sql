@@ -0,0 +1,39 @@
+-- Number song changes from song_change_seq without a lock. A number is taken
+-- when the row is written, not when the transaction commits, so, as with
+-- outbox events (see 000007_outbox_event_txid), every change records the
+-- transaction that wrote it. Sync readers return only changes of transactions
+-- older than pg_snapshot_xmin(pg_current_snapshot()), in (txid, seq) order.
+-- Changes written before this migration were numbered under a lock and keep
+-- txid 0, so tokens issued before it continue where they stopped.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS change_txid xid8 NOT NULL DEFAULT '0';
+ALTER TABLE song_tombstones ADD COLUMN IF NOT EXISTS change_txid xid8 NOT NULL DEFAULT '0';
+ALTER TABLE sync_horizon ADD COLUMN IF NOT EXISTS txid xid8 NOT NULL DEFAULT '0';
+
+CREATE INDEX IF NOT EXISTS songs_change_txid_seq_idx ON songs (change_txid, change_seq);
+CREATE INDEX IF NOT EXISTS song_tombstones_change_txid_seq_idx ON song_tombstones (change_txid, change_seq);
+
+CREATE OR REPLACE FUNCTION next_song_change_seq() RETURNS BIGINT AS $$
+BEGIN
+    RETURN nextval('song_change_seq');
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION set_song_change_seq() RETURNS trigger AS $$
+BEGIN
+    NEW.change_seq := next_song_change_seq();
+    NEW.change_txid := pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION record_song_tombstone() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO song_tombstones (song_id, change_seq, change_txid)
+    VALUES (OLD.id, next_song_change_seq(), pg_current_xact_id())
+    ON CONFLICT (song_id) DO UPDATE
+    SET change_seq = EXCLUDED.change_seq,
+        change_txid = EXCLUDED.change_txid,
+        deleted_at = EXCLUDED.deleted_at;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,43 @@
+// Package migrations содержит SQL миграции схемы, встроенные в бинарник,
+// и описание таблиц, которое эти миграции должны давать в итоге.
+package migrations
+
+import "embed"
+
+// FS файлы миграций для источника iofs golang-migrate
+//
+//go:embed *.sql
+var FS embed.FS
+
+// SQLiteFS миграции хранилища SQLite в каталоге sqlite, у них своя нумерация
+//
+//go:embed sqlite/*.sql
+var SQLiteFS embed.FS
+
+// SQLiteDir каталог миграций SQLite внутри SQLiteFS
+const SQLiteDir = "sqlite"
+
+// Column ожидаемая колонка таблицы, как ее описывает information_schema.columns
+type Column struct {
+	Name      string
+	DataType  string
+	MaxLength int
+	Nullable  bool
+}
+
+// SongsColumns колонки таблицы songs после применения всех миграций.
+// Меняется вместе с миграциями, по нему проверяется расхождение живой схемы.
+var SongsColumns = []Column{
+	{Name: "id", DataType: "integer"},
+	{Name: "group_name", DataType: "character varying", MaxLength: 255},
+	{Name: "song_name", DataType: "character varying", MaxLength: 255},
+	{Name: "release_date", DataType: "date", Nullable: true},
+	{Name: "text", DataType: "text", Nullable: true},
+	{Name: "link", DataType: "character varying", MaxLength: 255, Nullable: true},
+	{Name: "created_at", DataType: "timestamp without time zone", Nullable: true},
+	{Name: "updated_at", DataType: "timestamp without time zone", Nullable: true},
+	{Name: "group_key", DataType: "character varying", MaxLength: 255},
+	{Name: "song_key", DataType: "character varying", MaxLength: 255},
+	{Name: "change_seq", DataType: "bigint"},
+	{Name: "change_txid", DataType: "xid8"},
+}
@@ -0,0 +1,7 @@
+-- Drop the SQLite schema
+DROP TRIGGER IF EXISTS songs_fts_delete;
+DROP TRIGGER IF EXISTS songs_fts_update;
+DROP TRIGGER IF EXISTS songs_fts_insert;
+DROP TABLE IF EXISTS songs_fts;
+DROP TABLE IF EXISTS song_revisions;
+DROP TABLE IF EXISTS songs;
@@ -0,0 +1,51 @@
+-- SQLite schema of the music library. It gives the same tables as the
+-- PostgreSQL migrations 000001-000003 in one step. Dates are stored as
+-- YYYY-MM-DD and timestamps as fixed-width UTC text, so they sort as strings.
+CREATE TABLE IF NOT EXISTS songs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    group_name TEXT NOT NULL CHECK (length(group_name) <= 255),
+    song_name TEXT NOT NULL CHECK (length(song_name) <= 255),
+    release_date TEXT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '' CHECK (length(link) <= 255),
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    -- Normalized natural key, filled by the application with repository.NewNaturalKey
+    group_key TEXT NOT NULL,
+    song_key TEXT NOT NULL,
+    CONSTRAINT songs_natural_key UNIQUE (group_key, song_key)
+);
+
+CREATE INDEX IF NOT EXISTS songs_created_at_idx ON songs (created_at DESC, id DESC);
+
+-- History of song versions, one row per create or update
+CREATE TABLE IF NOT EXISTS song_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    song_id INTEGER NOT NULL REFERENCES songs (id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    group_name TEXT NOT NULL,
+    song_name TEXT NOT NULL,
+    release_date TEXT NOT NULL,
+    text TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    created_at TEXT NOT NULL,
+    UNIQUE (song_id, revision)
+);
+
+-- Full-text index for the text filter. The trigram tokenizer answers
+-- LIKE '%value%' from the index. Texts are lowercased by fold(), a function
+-- registered by the application, because SQLite's lower() and LIKE only
+-- fold ASCII letters.
+CREATE VIRTUAL TABLE IF NOT EXISTS songs_fts USING fts5(text, tokenize = 'trigram');
+
+CREATE TRIGGER IF NOT EXISTS songs_fts_insert AFTER INSERT ON songs BEGIN
+    INSERT INTO songs_fts (rowid, text) VALUES (new.id, fold(new.text));
+END;
+
+CREATE TRIGGER IF NOT EXISTS songs_fts_update AFTER UPDATE OF text ON songs BEGIN
+    UPDATE songs_fts SET text = fold(new.text) WHERE rowid = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS songs_fts_delete AFTER DELETE ON songs BEGIN
+    DELETE FROM songs_fts WHERE rowid = old.id;
+END;
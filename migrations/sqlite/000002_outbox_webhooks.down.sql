@@ -0,0 +1,4 @@
+-- Drop webhooks and the outbox
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS outbox_events;
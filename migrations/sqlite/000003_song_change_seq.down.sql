@@ -0,0 +1,8 @@
+-- Drop the change sequence and tombstones
+DROP TRIGGER IF EXISTS songs_tombstone;
+DROP TRIGGER IF EXISTS songs_change_seq_update;
+DROP TRIGGER IF EXISTS songs_change_seq_insert;
+DROP TABLE IF EXISTS song_tombstones;
+DROP TABLE IF EXISTS sync_state;
+DROP INDEX IF EXISTS songs_change_seq_idx;
+ALTER TABLE songs DROP COLUMN change_seq;
@@ -0,0 +1,47 @@
+-- Monotonic change sequence for delta sync, as PostgreSQL migration 000006.
+-- SQLite has no sequences, the last issued value lives in sync_state together
+-- with the sync horizon. Write transactions are serialized, so values commit
+-- in increasing order.
+ALTER TABLE songs ADD COLUMN change_seq INTEGER NOT NULL DEFAULT 0;
+
+-- Existing songs count as changed in the order they were created
+UPDATE songs SET change_seq = id;
+
+CREATE INDEX IF NOT EXISTS songs_change_seq_idx ON songs (change_seq);
+
+-- last_seq is the last issued change_seq, horizon the oldest one a sync token
+-- may point to: pruning tombstones moves it to the last pruned value
+CREATE TABLE IF NOT EXISTS sync_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    last_seq INTEGER NOT NULL,
+    horizon INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT OR IGNORE INTO sync_state (id, last_seq) SELECT 1, COALESCE(MAX(change_seq), 0) FROM songs;
+
+-- Deleted songs. deleted_at uses the same fixed-width layout as other timestamps
+CREATE TABLE IF NOT EXISTS song_tombstones (
+    song_id INTEGER PRIMARY KEY,
+    change_seq INTEGER NOT NULL UNIQUE,
+    deleted_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS song_tombstones_deleted_at_idx ON song_tombstones (deleted_at);
+
+-- Updating change_seq itself is not in the column list, so the triggers do not fire each other
+CREATE TRIGGER IF NOT EXISTS songs_change_seq_insert AFTER INSERT ON songs BEGIN
+    UPDATE sync_state SET last_seq = last_seq + 1;
+    UPDATE songs SET change_seq = (SELECT last_seq FROM sync_state) WHERE id = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS songs_change_seq_update
+AFTER UPDATE OF group_name, song_name, release_date, text, link, updated_at ON songs BEGIN
+    UPDATE sync_state SET last_seq = last_seq + 1;
+    UPDATE songs SET change_seq = (SELECT last_seq FROM sync_state) WHERE id = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS songs_tombstone AFTER DELETE ON songs BEGIN
+    UPDATE sync_state SET last_seq = last_seq + 1;
+    INSERT OR REPLACE INTO song_tombstones (song_id, change_seq, deleted_at)
+    VALUES (old.id, (SELECT last_seq FROM sync_state), strftime('%Y-%m-%dT%H:%M:%f000Z', 'now'));
+END;
@@ -0,0 +1,227 @@
+// Package client клиент HTTP API музыкальной библиотеки
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix префикс маршрутов REST API
+const apiPrefix = "/api/v1"
+
+// RetryPolicy правила повтора запросов. Ответ 429 повторяется для любого метода,
+// ответы 5xx и сетевые ошибки только для идемпотентных GET, PUT и DELETE.
+type RetryPolicy struct {
+	// MaxRetries сколько раз повторить запрос после первой попытки, 0 выключает повторы
+	MaxRetries int
+	// MinBackoff пауза перед первым повтором, дальше она удваивается
+	MinBackoff time.Duration
+	// MaxBackoff наибольшая пауза между попытками без заголовка Retry-After
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy правила повтора по умолчанию
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// Client клиент API, безопасен для использования из нескольких горутин
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+}
+
+// Option настройка клиента
+type Option func(*Client)
+
+// WithHTTPClient задает HTTP клиент, например с таймаутом или TLS настройками
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetryPolicy задает правила повтора запросов
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// New создает клиента к серверу по адресу baseURL, например http://localhost:8080
+func New(baseURL string, options ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: want http(s)://host[:port]", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+// endpoint собирает адрес маршрута API с параметрами запроса
+func (c *Client) endpoint(path string, query url.Values) string {
+	u := *c.baseURL
+	u.Path += apiPrefix + path
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// do выполняет запрос с повторами и декодирует JSON ответа в out, если он не nil.
+// Возвращает HTTP статус успешного ответа, ответ с ошибкой переводится в *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) (int, error) {
+	resp, err := c.send(ctx, method, path, query, body, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// send отправляет запрос, повторяя его по правилам c.retry, и возвращает успешный ответ.
+// Тело body кодируется в JSON один раз и отправляется заново при каждой попытке.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any, header http.Header) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode %s %s request: %w", method, path, err)
+		}
+	}
+	target := c.endpoint(path, query)
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", "application/json")
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.httpClient.Do(req)
+		retryable := attempt < c.retry.MaxRetries && ctx.Err() == nil
+		if err != nil {
+			if !retryable || !idempotent(method) {
+				return nil, err
+			}
+			if err := c.wait(ctx, attempt, ""); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		if retryable && retryStatus(method, resp.StatusCode) {
+			retryAfter := resp.Header.Get("Retry-After")
+			// Тело дочитывается, чтобы соединение вернулось в пул
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := c.wait(ctx, attempt, retryAfter); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+}
+
+// idempotent методы, повтор которых не меняет результат
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryStatus сообщает, стоит ли повторить запрос с таким ответом
+func retryStatus(method string, status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= http.StatusInternalServerError && idempotent(method)
+}
+
+// wait ждет перед повтором: сколько просит Retry-After, иначе экспоненциально с разбросом
+func (c *Client) wait(ctx context.Context, attempt int, retryAfter string) error {
+	delay, ok := parseRetryAfter(retryAfter)
+	if !ok {
+		delay = c.backoff(attempt)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// backoff пауза перед повтором номер attempt+1, случайная в верхней половине окна,
+// чтобы клиенты не повторяли запросы одновременно
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.MaxBackoff
+	if attempt < 32 && c.retry.MinBackoff<<attempt < c.retry.MaxBackoff {
+		delay = c.retry.MinBackoff << attempt
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде HTTP даты
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,360 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/testTask/internal/app"
+	"github.com/testTask/internal/config"
+	"github.com/testTask/pkg/client"
+	"go.uber.org/zap"
+)
+
+// fastRetry повторы без заметных пауз, чтобы тесты не ждали
+var fastRetry = client.RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// newHandler собирает обработчик всех маршрутов приложения поверх хранилища в памяти
+func newHandler(t *testing.T) http.Handler {
+	t.Helper()
+	cfg, _, err := config.Load([]string{
+		"--storage=memory",
+		"--grpc.enabled=false",
+		"--webhooks.enabled=false",
+		"--tracing.exporter=none",
+		"--events.poll_interval=50ms",
+	})
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	application := app.New(cfg, zap.NewNop())
+	if err := application.Initialize(); err != nil {
+		t.Fatalf("failed to initialize app: %v", err)
+	}
+	t.Cleanup(func() { _ = application.Close(context.Background()) })
+	return application.Handler()
+}
+
+// newClient запускает handler в httptest сервере и возвращает клиента к нему
+func newClient(t *testing.T, handler http.Handler) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL, client.WithRetryPolicy(fastRetry))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestSongLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newHandler(t))
+
+	created, err := c.CreateSong(ctx, client.SongRequest{GroupName: "Muse", SongName: "Hysteria", Text: "first\n\nsecond"})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	if created.ID == 0 || created.GroupName != "Muse" || created.ReleaseDate.IsZero() {
+		t.Errorf("created song %+v", created)
+	}
+
+	details, err := c.GetSong(ctx, created.ID, client.GetSongOptions{Include: []string{client.IncludeLyrics, client.IncludeGroup}})
+	if err != nil {
+		t.Fatalf("GetSong: %v", err)
+	}
+	if len(details.Lyrics) != 2 || details.Group == nil || details.Group.SongCount != 1 {
+		t.Errorf("song details %+v", details)
+	}
+
+	lyrics, err := c.GetLyrics(ctx, created.ID, 2, 1)
+	if err != nil {
+		t.Fatalf("GetLyrics: %v", err)
+	}
+	if lyrics.Text != "second" || lyrics.TotalPages != 2 {
+		t.Errorf("lyrics %+v", lyrics)
+	}
+
+	updated, err := c.UpdateSong(ctx, created.ID, client.SongRequest{Link: "https://example.com/hysteria"})
+	if err != nil {
+		t.Fatalf("UpdateSong: %v", err)
+	}
+	if updated.SongName != "Hysteria" || updated.Link != "https://example.com/hysteria" {
+		t.Errorf("updated song %+v", updated)
+	}
+
+	byKey, err := c.GetSongByKey(ctx, "muse", "HYSTERIA")
+	if err != nil {
+		t.Fatalf("GetSongByKey: %v", err)
+	}
+	if byKey.ID != created.ID {
+		t.Errorf("song by key %d, want %d", byKey.ID, created.ID)
+	}
+
+	replaced, isNew, err := c.UpsertSongByKey(ctx, client.SongRequest{GroupName: "Muse", SongName: "Hysteria", Text: "replaced"})
+	if err != nil {
+		t.Fatalf("UpsertSongByKey: %v", err)
+	}
+	if isNew || replaced.ID != created.ID || replaced.Text != "replaced" {
+		t.Errorf("replaced song %+v, created %v", replaced, isNew)
+	}
+	inserted, isNew, err := c.UpsertSongByKey(ctx, client.SongRequest{GroupName: "Queen", SongName: "Innuendo"})
+	if err != nil {
+		t.Fatalf("UpsertSongByKey: %v", err)
+	}
+	if !isNew || inserted.ID == created.ID {
+		t.Errorf("inserted song %+v, created %v", inserted, isNew)
+	}
+
+	page, err := c.ListSongs(ctx, client.SongFilter{GroupName: "mus", Fields: []string{"id", "song_name"}})
+	if err != nil {
+		t.Fatalf("ListSongs: %v", err)
+	}
+	if page.TotalItems != 1 || len(page.Songs) != 1 || page.Songs[0].SongName != "Hysteria" || page.Songs[0].GroupName != "" {
+		t.Errorf("songs page %+v", page)
+	}
+
+	changes, err := c.Sync(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(changes.Songs) != 2 || changes.NextToken == "" || changes.HasMore {
+		t.Errorf("sync %+v", changes)
+	}
+
+	if err := c.DeleteSong(ctx, created.ID); err != nil {
+		t.Fatalf("DeleteSong: %v", err)
+	}
+	if _, err := c.GetSong(ctx, created.ID, client.GetSongOptions{}); !client.IsType(err, client.NotFound) {
+		t.Errorf("GetSong of deleted song: %v, want NOT_FOUND", err)
+	}
+
+	changes, err = c.Sync(ctx, changes.NextToken, 0)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != created.ID {
+		t.Errorf("sync after delete %+v", changes)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newHandler(t))
+
+	_, err := c.CreateSong(ctx, client.SongRequest{GroupName: " ", SongName: "Hysteria"})
+	apiErr, ok := client.As(err)
+	if !ok || apiErr.Type != client.Validation || apiErr.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid song: %v, want VALIDATION", err)
+	}
+	if len(apiErr.Fields) == 0 || apiErr.Fields[0].Field != "group" || apiErr.RequestID == "" {
+		t.Errorf("validation error %+v", apiErr)
+	}
+
+	song, err := c.CreateSong(ctx, client.SongRequest{GroupName: "Muse", SongName: "Hysteria"})
+	if err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+	_, err = c.CreateSong(ctx, client.SongRequest{GroupName: "MUSE", SongName: "hysteria"})
+	if apiErr, ok := client.As(err); !ok || apiErr.Type != client.AlreadyExists || apiErr.ExistingID != song.ID {
+		t.Errorf("duplicate song: %v, want ALREADY_EXISTS with existing ID %d", err, song.ID)
+	}
+
+	if _, err := c.ListSongs(ctx, client.SongFilter{PageSize: 1000}); !client.IsType(err, client.Validation) {
+		t.Errorf("too large page: %v, want VALIDATION", err)
+	}
+	if _, err := c.Sync(ctx, "garbage", 0); err == nil {
+		t.Error("Sync with invalid token succeeded")
+	}
+}
+
+func TestSongsIteratorReadsAllPages(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newHandler(t))
+
+	for i := 0; i < 25; i++ {
+		if _, err := c.CreateSong(ctx, client.SongRequest{GroupName: "Muse", SongName: fmt.Sprintf("Song %d", i)}); err != nil {
+			t.Fatalf("CreateSong: %v", err)
+		}
+	}
+	if _, err := c.CreateSong(ctx, client.SongRequest{GroupName: "Queen", SongName: "Innuendo"}); err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+
+	songs, err := c.Songs(ctx, client.SongFilter{GroupName: "muse", PageSize: 10}).All()
+	if err != nil {
+		t.Fatalf("Songs: %v", err)
+	}
+	seen := map[int]bool{}
+	for _, song := range songs {
+		seen[song.ID] = true
+	}
+	if len(songs) != 25 || len(seen) != 25 {
+		t.Errorf("read %d songs, %d unique, want 25", len(songs), len(seen))
+	}
+
+	// Пустой список сервер сообщает ошибкой первой страницы, итератор считает его пустым
+	songs, err = c.Songs(ctx, client.SongFilter{GroupName: "Nobody"}).All()
+	if err != nil || len(songs) != 0 {
+		t.Errorf("songs of unknown group: %d, %v, want none", len(songs), err)
+	}
+
+	it := c.Songs(ctx, client.SongFilter{FromDate: time.Now().AddDate(1, 0, 0), ToDate: time.Now(), PageSize: 10})
+	if it.Next() || !client.IsType(it.Err(), client.Validation) {
+		t.Errorf("invalid filter: %v, want VALIDATION", it.Err())
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newHandler(t))
+
+	webhook, err := c.CreateWebhook(ctx, client.WebhookRequest{
+		URL:        "https://example.com/hooks/songs",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{client.EventSongCreated},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := c.CreateSong(ctx, client.SongRequest{GroupName: "Muse", SongName: "Hysteria"}); err != nil {
+		t.Fatalf("CreateSong: %v", err)
+	}
+
+	webhooks, err := c.ListWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	if len(webhooks) != 1 || webhooks[0].ID != webhook.ID {
+		t.Errorf("webhooks %+v", webhooks)
+	}
+
+	// Диспетчер в тесте не запущен, поэтому журнал доставок пуст и повторять нечего
+	if enqueued, err := c.ReplayDeliveries(ctx, webhook.ID, 0); err != nil || enqueued != 0 {
+		t.Errorf("ReplayDeliveries: %d, %v, want 0", enqueued, err)
+	}
+	deliveries, err := c.Deliveries(ctx, webhook.ID, client.DeliveryFilter{Status: client.DeliveryPending}).All()
+	if err != nil || len(deliveries) != 0 {
+		t.Errorf("Deliveries: %d, %v, want none", len(deliveries), err)
+	}
+	if _, err := c.ListDeliveries(ctx, webhook.ID, client.DeliveryFilter{Status: "lost"}); !client.IsType(err, client.Validation) {
+		t.Errorf("deliveries with unknown status: %v, want VALIDATION", err)
+	}
+
+	if err := c.DeleteWebhook(ctx, webhook.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := c.ListDeliveries(ctx, webhook.ID, client.DeliveryFilter{}); !client.IsType(err, client.NotFound) {
+		t.Errorf("deliveries of deleted webhook: %v, want NOT_FOUND", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := newClient(t, newHandler(t))
+
+	stream, err := c.Events(ctx, client.EventFilter{GroupName: "muse"})
+	if err != nil {
+		t.Fatalf("Events: %v", err)
+	}
+	defer stream.Close()
+
+	for _, group := range []string{"Queen", "Muse"} {
+		if _, err := c.CreateSong(ctx, client.SongRequest{GroupName: group, SongName: "Song"}); err != nil {
+			t.Fatalf("CreateSong: %v", err)
+		}
+	}
+
+	if !stream.Next() {
+		t.Fatalf("stream stopped: %v", stream.Err())
+	}
+	event := stream.Event()
+	if event.Type != client.EventSongCreated || event.SongID == 0 || stream.LastEventID() != event.ID {
+		t.Errorf("event %+v, last event ID %d", event, stream.LastEventID())
+	}
+
+	_ = stream.Close()
+	if stream.Next() || stream.Err() != client.ErrStreamClosed {
+		t.Errorf("closed stream: %v, want ErrStreamClosed", stream.Err())
+	}
+}
+
+// flakyHandler отвечает status на первые failures запросов, затем передает их next
+func flakyHandler(next http.Handler, status, failures int, requests *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	request := client.SongRequest{GroupName: "Muse", SongName: "Hysteria"}
+
+	tests := []struct {
+		name     string
+		status   int
+		failures int
+		call     func(c *client.Client) error
+		wantErr  client.ErrorType
+		wantReqs int32
+	}{
+		{
+			name:     "GET retried on 503",
+			status:   http.StatusServiceUnavailable,
+			failures: 2,
+			call:     func(c *client.Client) error { _, err := c.ListWebhooks(ctx); return err },
+			wantReqs: 3,
+		},
+		{
+			name:     "POST retried on 429",
+			status:   http.StatusTooManyRequests,
+			failures: 1,
+			call:     func(c *client.Client) error { _, err := c.CreateSong(ctx, request); return err },
+			wantReqs: 2,
+		},
+		{
+			name:     "POST not retried on 503",
+			status:   http.StatusServiceUnavailable,
+			failures: 1,
+			call:     func(c *client.Client) error { _, err := c.CreateSong(ctx, request); return err },
+			wantErr:  client.Internal,
+			wantReqs: 1,
+		},
+		{
+			name:     "retries exhausted",
+			status:   http.StatusTooManyRequests,
+			failures: 5,
+			call:     func(c *client.Client) error { return c.DeleteSong(ctx, 1) },
+			wantErr:  client.TooManyRequests,
+			wantReqs: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			c := newClient(t, flakyHandler(newHandler(t), tt.status, tt.failures, &requests))
+
+			err := tt.call(c)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("call failed: %v", err)
+			}
+			if tt.wantErr != "" && !client.IsType(err, tt.wantErr) {
+				t.Errorf("error %v, want %s", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantReqs {
+				t.Errorf("%d requests, want %d", got, tt.wantReqs)
+			}
+		})
+	}
+}
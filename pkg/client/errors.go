@@ -0,0 +1,122 @@
+package client
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrorType код ошибки API, совпадает с типами ошибок сервера
+type ErrorType string
+
+const (
+	NotFound      ErrorType = "NOT_FOUND"
+	BadRequest    ErrorType = "BAD_REQUEST"
+	Internal      ErrorType = "INTERNAL"
+	Validation    ErrorType = "VALIDATION"
+	AlreadyExists ErrorType = "ALREADY_EXISTS"
+	// ResyncRequired токен синхронизации устарел, нужна полная синхронизация
+	ResyncRequired ErrorType = "RESYNC_REQUIRED"
+	// TooManyRequests сервер или прокси перед ним ограничил частоту запросов
+	TooManyRequests ErrorType = "TOO_MANY_REQUESTS"
+)
+
+// typeByStatus тип ошибки для ответов без тела application/problem+json,
+// например от прокси перед сервером
+var typeByStatus = map[int]ErrorType{
+	http.StatusNotFound:            NotFound,
+	http.StatusBadRequest:          BadRequest,
+	http.StatusUnprocessableEntity: Validation,
+	http.StatusConflict:            AlreadyExists,
+	http.StatusGone:                ResyncRequired,
+	http.StatusTooManyRequests:     TooManyRequests,
+}
+
+// FieldError ошибка проверки отдельного поля запроса
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error ответ API с ошибкой, разобранный из application/problem+json (RFC 7807)
+type Error struct {
+	// StatusCode HTTP статус ответа
+	StatusCode int
+	Type       ErrorType
+	// Title текст HTTP статуса, Detail описание ошибки от сервера
+	Title  string
+	Detail string
+	// Instance путь запроса, RequestID его идентификатор в логах сервера
+	Instance  string
+	RequestID string
+	// Fields ошибки по отдельным полям, заполняется для Validation
+	Fields []FieldError
+	// ExistingID идентификатор уже существующей песни, заполняется для AlreadyExists
+	ExistingID int
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%s (%d): %s", e.Type, e.StatusCode, e.Detail)
+	}
+	return fmt.Sprintf("%s (%d)", e.Type, e.StatusCode)
+}
+
+// As ищет *Error в цепочке обернутых ошибок
+func As(err error) (*Error, bool) {
+	var apiErr *Error
+	if stderrors.As(err, &apiErr) {
+		return apiErr, true
+	}
+	return nil, false
+}
+
+// IsType сообщает, что err ответ API с ошибкой типа errorType
+func IsType(err error, errorType ErrorType) bool {
+	apiErr, ok := As(err)
+	return ok && apiErr.Type == errorType
+}
+
+// problem тело ответа с ошибкой
+type problem struct {
+	Title      string       `json:"title"`
+	Status     int          `json:"status"`
+	Detail     string       `json:"detail"`
+	Instance   string       `json:"instance"`
+	Code       ErrorType    `json:"code"`
+	RequestID  string       `json:"request_id"`
+	Errors     []FieldError `json:"errors"`
+	ExistingID int          `json:"existing_id"`
+}
+
+// decodeError переводит ответ с ошибкой в *Error. Тип берется из поля code,
+// а если тело не problem+json, то по HTTP статусу.
+func decodeError(resp *http.Response) *Error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Title:      http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+
+	var body problem
+	if raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20)); err == nil && json.Unmarshal(raw, &body) == nil && body.Code != "" {
+		apiErr.Type = body.Code
+		apiErr.Title = body.Title
+		apiErr.Detail = body.Detail
+		apiErr.Instance = body.Instance
+		apiErr.Fields = body.Errors
+		apiErr.ExistingID = body.ExistingID
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+		return apiErr
+	}
+
+	apiErr.Type = Internal
+	if errorType, ok := typeByStatus[resp.StatusCode]; ok {
+		apiErr.Type = errorType
+	}
+	return apiErr
+}
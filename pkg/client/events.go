@@ -0,0 +1,203 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrStreamClosed поток событий закрыт вызовом Close
+var ErrStreamClosed = errors.New("event stream closed")
+
+// EventStream поток событий об изменении песен. После обрыва соединения поток
+// переподключается с Last-Event-ID и продолжает с первого непрочитанного события.
+type EventStream struct {
+	client *Client
+	ctx    context.Context
+	filter EventFilter
+
+	mu     sync.Mutex
+	body   io.ReadCloser
+	closed bool
+
+	reader *bufio.Reader
+	event  Event
+	// lastEventID позиция последнего прочитанного события или пропущенных фильтром
+	lastEventID int64
+	// reconnects переподключения подряд без единого прочитанного кадра
+	reconnects int
+	err        error
+}
+
+// Events открывает поток событий по фильтру. Без filter.LastEventID поток начинается
+// с новых событий. Поток нужно закрыть вызовом Close.
+func (c *Client) Events(ctx context.Context, filter EventFilter) (*EventStream, error) {
+	s := &EventStream{
+		client:      c,
+		ctx:         ctx,
+		filter:      filter,
+		lastEventID: filter.LastEventID,
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect открывает соединение, продолжая поток после lastEventID
+func (s *EventStream) connect() error {
+	query := url.Values{}
+	setString(query, "group", s.filter.GroupName)
+	setList(query, "event_types", s.filter.Types)
+	header := http.Header{"Accept": {"text/event-stream"}}
+	if s.lastEventID > 0 {
+		header.Set("Last-Event-ID", strconv.FormatInt(s.lastEventID, 10))
+	}
+
+	resp, err := s.client.send(s.ctx, http.MethodGet, "/events", query, nil, header)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		resp.Body.Close()
+		return ErrStreamClosed
+	}
+	s.body = resp.Body
+	s.reader = bufio.NewReader(resp.Body)
+	return nil
+}
+
+// Next ждет следующее событие и сообщает, получено ли оно. Возвращает false после
+// Close, отмены контекста или ошибки переподключения, ее вернет Err.
+func (s *EventStream) Next() bool {
+	for s.err == nil {
+		event, ok, err := s.readFrame()
+		if err == nil {
+			s.reconnects = 0
+			if ok {
+				s.event = event
+				return true
+			}
+			continue
+		}
+
+		if s.isClosed() {
+			s.err = ErrStreamClosed
+		} else if ctxErr := s.ctx.Err(); ctxErr != nil {
+			s.err = ctxErr
+		} else if s.reconnects >= s.client.retry.MaxRetries {
+			s.err = fmt.Errorf("event stream interrupted: %w", err)
+		} else if waitErr := s.client.wait(s.ctx, s.reconnects, ""); waitErr != nil {
+			s.err = waitErr
+		} else {
+			s.reconnects++
+			s.closeBody()
+			if connectErr := s.connect(); connectErr != nil {
+				s.err = connectErr
+			}
+		}
+	}
+	return false
+}
+
+// readFrame читает один кадр потока. ok false для кадров без события:
+// только с позицией пропущенных фильтром событий или с комментарием.
+func (s *EventStream) readFrame() (event Event, ok bool, err error) {
+	var id, data string
+	var hasID bool
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return Event{}, false, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		name, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "id":
+			id, hasID = value, true
+		case "data":
+			if data != "" {
+				data += "\n"
+			}
+			data += value
+		}
+	}
+
+	if hasID {
+		if position, err := strconv.ParseInt(id, 10, 64); err == nil {
+			s.lastEventID = position
+		}
+	}
+	if data == "" {
+		return Event{}, false, nil
+	}
+	// Испорченное событие переподключение не исправит, поток на нем останавливается
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		s.err = fmt.Errorf("failed to decode event %s: %w", id, err)
+		return Event{}, false, nil
+	}
+	return event, true, nil
+}
+
+// Event текущее событие, доступно после Next, вернувшего true
+func (s *EventStream) Event() Event {
+	return s.event
+}
+
+// LastEventID позиция в потоке, с которой его можно продолжить через EventFilter.LastEventID
+func (s *EventStream) LastEventID() int64 {
+	return s.lastEventID
+}
+
+// Err ошибка, на которой остановился поток. После Close это ErrStreamClosed.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close закрывает поток, ожидающий Next возвращает false. Можно вызывать из другой горутины.
+func (s *EventStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.body != nil {
+		return s.body.Close()
+	}
+	return nil
+}
+
+func (s *EventStream) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *EventStream) closeBody() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.body != nil {
+		s.body.Close()
+		s.body = nil
+	}
+}
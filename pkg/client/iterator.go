@@ -0,0 +1,93 @@
+package client
+
+import "context"
+
+// maxPageSize наибольший размер страницы, который принимает сервер
+const maxPageSize = 100
+
+// pageFunc читает страницу page и возвращает ее элементы и число страниц
+type pageFunc[T any] func(ctx context.Context, page int) ([]T, int, error)
+
+// Iterator обходит все страницы списка, запрашивая следующую по мере чтения:
+//
+//	it := c.Songs(ctx, filter)
+//	for it.Next() {
+//		song := it.Value()
+//	}
+//	if err := it.Err(); err != nil { ... }
+//
+// Записи, измененные во время обхода, могут пропасть или повториться между страницами.
+type Iterator[T any] struct {
+	ctx   context.Context
+	fetch pageFunc[T]
+	// emptyNotFound сервер отвечает на пустой список ошибкой NotFound первой страницы
+	emptyNotFound bool
+
+	page       int
+	totalPages int
+	items      []T
+	index      int
+	current    T
+	done       bool
+	err        error
+}
+
+func newIterator[T any](ctx context.Context, startPage int, emptyNotFound bool, fetch pageFunc[T]) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:           ctx,
+		fetch:         fetch,
+		emptyNotFound: emptyNotFound,
+		page:          max(startPage, 1) - 1,
+	}
+}
+
+// Next переходит к следующему элементу и сообщает, есть ли он
+func (it *Iterator[T]) Next() bool {
+	for it.index >= len(it.items) {
+		if it.done || it.err != nil {
+			return false
+		}
+		if it.totalPages > 0 && it.page >= it.totalPages {
+			it.done = true
+			return false
+		}
+
+		it.page++
+		items, totalPages, err := it.fetch(it.ctx, it.page)
+		if err != nil {
+			if it.emptyNotFound && it.totalPages == 0 && IsType(err, NotFound) {
+				it.done = true
+				return false
+			}
+			it.err = err
+			return false
+		}
+		it.items, it.index, it.totalPages = items, 0, totalPages
+		if len(items) == 0 {
+			it.done = true
+		}
+	}
+
+	it.current = it.items[it.index]
+	it.index++
+	return true
+}
+
+// Value текущий элемент, доступен после Next, вернувшего true
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err ошибка, на которой остановился обход, nil если список прочитан целиком
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All читает все оставшиеся элементы
+func (it *Iterator[T]) All() ([]T, error) {
+	var all []T
+	for it.Next() {
+		all = append(all, it.Value())
+	}
+	return all, it.Err()
+}
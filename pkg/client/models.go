@@ -0,0 +1,231 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout формат дат в параметрах запроса
+const dateLayout = "2006-01-02"
+
+// Song песня
+type Song struct {
+	ID          int       `json:"id"`
+	GroupName   string    `json:"group_name"`
+	SongName    string    `json:"song_name"`
+	ReleaseDate time.Time `json:"release_date"`
+	Text        string    `json:"text"`
+	Link        string    `json:"link"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SongRequest тело запроса на создание или изменение песни.
+// В JSON группа и песня называются group и song, а не group_name и song_name, как в Song.
+type SongRequest struct {
+	GroupName string `json:"group"`
+	SongName  string `json:"song"`
+	Text      string `json:"text,omitempty"`
+	Link      string `json:"link,omitempty"`
+}
+
+// SongFilter фильтр и страница списка песен, пустые поля не фильтруют
+type SongFilter struct {
+	// GroupName и SongName ищутся по подстроке без учета регистра
+	GroupName string
+	SongName  string
+	// FromDate и ToDate границы даты выпуска, учитывается только дата
+	FromDate time.Time
+	ToDate   time.Time
+	Text     string
+	Link     string
+	Page     int
+	PageSize int
+	// Fields поля песен в ответе, пустой список - все
+	Fields []string
+}
+
+func (f *SongFilter) values() url.Values {
+	query := url.Values{}
+	setString(query, "group_name", f.GroupName)
+	setString(query, "song_name", f.SongName)
+	if !f.FromDate.IsZero() {
+		query.Set("from_date", f.FromDate.Format(dateLayout))
+	}
+	if !f.ToDate.IsZero() {
+		query.Set("to_date", f.ToDate.Format(dateLayout))
+	}
+	setString(query, "text", f.Text)
+	setString(query, "link", f.Link)
+	setInt(query, "page", f.Page)
+	setInt(query, "page_size", f.PageSize)
+	setList(query, "fields", f.Fields)
+	return query
+}
+
+// SongPage страница списка песен
+type SongPage struct {
+	Songs       []Song `json:"songs"`
+	CurrentPage int    `json:"current_page"`
+	TotalPages  int    `json:"total_pages"`
+	TotalItems  int    `json:"total_items"`
+	PageSize    int    `json:"page_size"`
+}
+
+// Связанные данные песни для GetSongOptions.Include
+const (
+	IncludeLyrics    = "lyrics"
+	IncludeGroup     = "group"
+	IncludeRevisions = "revisions"
+)
+
+// GetSongOptions связанные данные и поля ответа GetSong
+type GetSongOptions struct {
+	Include []string
+	Fields  []string
+}
+
+// SongDetails песня со связанными данными, запрошенными в Include
+type SongDetails struct {
+	Song
+	Lyrics        []string      `json:"lyrics,omitempty"`
+	Group         *GroupDetails `json:"group,omitempty"`
+	RevisionCount *int          `json:"revision_count,omitempty"`
+}
+
+// GroupDetails сведения о группе песни
+type GroupDetails struct {
+	Name      string `json:"name"`
+	SongCount int    `json:"song_count"`
+}
+
+// LyricsPage страница куплетов песни
+type LyricsPage struct {
+	Text        string `json:"text"`
+	CurrentPage int    `json:"current_page"`
+	TotalPages  int    `json:"total_pages"`
+	PageSize    int    `json:"page_size"`
+}
+
+// SyncResponse изменения библиотеки после токена синхронизации
+type SyncResponse struct {
+	// Songs текущие версии созданных и измененных песен
+	Songs []Song `json:"songs"`
+	// Deleted ID удаленных песен
+	Deleted []int `json:"deleted"`
+	// NextToken токен для следующего вызова Sync
+	NextToken string `json:"next_token"`
+	// HasMore в ответ вошли не все изменения
+	HasMore bool `json:"has_more"`
+}
+
+// Виды событий об изменении песен
+const (
+	EventSongCreated = "song.created"
+	EventSongUpdated = "song.updated"
+	EventSongDeleted = "song.deleted"
+)
+
+// Event событие об изменении песни
+type Event struct {
+	ID     int64  `json:"id"`
+	Type   string `json:"type"`
+	SongID int    `json:"song_id"`
+	// Data данные события в JSON
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// EventFilter фильтр потока событий, пустые поля не фильтруют
+type EventFilter struct {
+	GroupName string
+	Types     []string
+	// LastEventID продолжить поток после этого события, 0 - только новые события
+	LastEventID int64
+}
+
+// Webhook зарегистрированный вебхук
+type Webhook struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookRequest запрос на регистрацию вебхука
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Secret ключ подписи тел запросов, не короче 16 символов
+	Secret string `json:"secret"`
+	// EventTypes виды событий для доставки, пустой список - все
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+// Статусы доставки события
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery доставка одного события вебхуку и итог последней попытки
+type Delivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int        `json:"webhook_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DeliveryFilter фильтр и страница журнала доставок
+type DeliveryFilter struct {
+	// Status статус доставки, пустой - все
+	Status   string
+	Page     int
+	PageSize int
+}
+
+func (f *DeliveryFilter) values() url.Values {
+	query := url.Values{}
+	setString(query, "status", f.Status)
+	setInt(query, "page", f.Page)
+	setInt(query, "page_size", f.PageSize)
+	return query
+}
+
+// DeliveryPage страница журнала доставок
+type DeliveryPage struct {
+	Deliveries  []Delivery `json:"deliveries"`
+	CurrentPage int        `json:"current_page"`
+	TotalPages  int        `json:"total_pages"`
+	TotalItems  int        `json:"total_items"`
+	PageSize    int        `json:"page_size"`
+}
+
+func setString(query url.Values, name, value string) {
+	if value != "" {
+		query.Set(name, value)
+	}
+}
+
+func setInt(query url.Values, name string, value int) {
+	if value != 0 {
+		query.Set(name, strconv.Itoa(value))
+	}
+}
+
+func setList(query url.Values, name string, values []string) {
+	if len(values) > 0 {
+		query.Set(name, strings.Join(values, ","))
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListSongs возвращает страницу песен по фильтру. Если подходящих песен нет,
+// сервер отвечает ошибкой NotFound о первой странице.
+func (c *Client) ListSongs(ctx context.Context, filter SongFilter) (*SongPage, error) {
+	var page SongPage
+	if _, err := c.do(ctx, http.MethodGet, "/songs", filter.values(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Songs обходит все песни по фильтру, начиная со страницы filter.Page.
+// Без filter.PageSize песни читаются страницами наибольшего размера.
+func (c *Client) Songs(ctx context.Context, filter SongFilter) *Iterator[Song] {
+	if filter.PageSize == 0 {
+		filter.PageSize = maxPageSize
+	}
+	return newIterator(ctx, filter.Page, true, func(ctx context.Context, page int) ([]Song, int, error) {
+		filter.Page = page
+		response, err := c.ListSongs(ctx, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		return response.Songs, response.TotalPages, nil
+	})
+}
+
+// GetSong возвращает песню по ID со связанными данными из options.Include
+func (c *Client) GetSong(ctx context.Context, id int, options GetSongOptions) (*SongDetails, error) {
+	query := url.Values{}
+	setList(query, "include", options.Include)
+	setList(query, "fields", options.Fields)
+
+	var song SongDetails
+	if _, err := c.do(ctx, http.MethodGet, songPath(id), query, nil, &song); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+// GetLyrics возвращает страницу куплетов песни, нулевые page и pageSize берутся по умолчанию
+func (c *Client) GetLyrics(ctx context.Context, id, page, pageSize int) (*LyricsPage, error) {
+	query := url.Values{}
+	setInt(query, "page", page)
+	setInt(query, "page_size", pageSize)
+
+	var lyrics LyricsPage
+	if _, err := c.do(ctx, http.MethodGet, songPath(id)+"/lyrics", query, nil, &lyrics); err != nil {
+		return nil, err
+	}
+	return &lyrics, nil
+}
+
+// GetSongByKey возвращает песню по названию группы и песни без учета регистра
+func (c *Client) GetSongByKey(ctx context.Context, groupName, songName string) (*Song, error) {
+	query := url.Values{"group": {groupName}, "song": {songName}}
+
+	var song Song
+	if _, err := c.do(ctx, http.MethodGet, "/songs/by-key", query, nil, &song); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+// CreateSong создает песню. Если такая песня уже есть, возвращает ошибку
+// AlreadyExists с ее идентификатором в ExistingID.
+func (c *Client) CreateSong(ctx context.Context, req SongRequest) (*Song, error) {
+	var song Song
+	if _, err := c.do(ctx, http.MethodPost, "/songs", nil, req, &song); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+// UpsertSongByKey создает песню или заменяет песню с тем же названием группы и песни.
+// created сообщает, что песня была создана.
+func (c *Client) UpsertSongByKey(ctx context.Context, req SongRequest) (song *Song, created bool, err error) {
+	song = &Song{}
+	status, err := c.do(ctx, http.MethodPut, "/songs/by-key", nil, req, song)
+	if err != nil {
+		return nil, false, err
+	}
+	return song, status == http.StatusCreated, nil
+}
+
+// UpdateSong частично обновляет песню, пустые поля req не меняются
+func (c *Client) UpdateSong(ctx context.Context, id int, req SongRequest) (*Song, error) {
+	var song Song
+	if _, err := c.do(ctx, http.MethodPut, songPath(id), nil, req, &song); err != nil {
+		return nil, err
+	}
+	return &song, nil
+}
+
+// DeleteSong удаляет песню
+func (c *Client) DeleteSong(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, songPath(id), nil, nil, nil)
+	return err
+}
+
+func songPath(id int) string {
+	return "/songs/" + strconv.Itoa(id)
+}
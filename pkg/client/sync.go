@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Sync возвращает изменения песен после токена since, пустой since начинает синхронизацию
+// с нуля. NextToken ответа передается в since следующего вызова, пока HasMore не станет false.
+// Ошибка ResyncRequired значит, что токен устарел: локальную копию нужно собрать заново.
+func (c *Client) Sync(ctx context.Context, since string, limit int) (*SyncResponse, error) {
+	query := url.Values{}
+	setString(query, "since", since)
+	setInt(query, "limit", limit)
+
+	var changes SyncResponse
+	if _, err := c.do(ctx, http.MethodGet, "/sync", query, nil, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// ListWebhooks возвращает все зарегистрированные вебхуки
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if _, err := c.do(ctx, http.MethodGet, "/webhooks", nil, nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook регистрирует вебхук
+func (c *Client) CreateWebhook(ctx context.Context, req WebhookRequest) (*Webhook, error) {
+	var webhook Webhook
+	if _, err := c.do(ctx, http.MethodPost, "/webhooks", nil, req, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook удаляет вебхук вместе с его доставками
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	_, err := c.do(ctx, http.MethodDelete, webhookPath(id), nil, nil, nil)
+	return err
+}
+
+// ListDeliveries возвращает страницу журнала доставок вебхука
+func (c *Client) ListDeliveries(ctx context.Context, webhookID int, filter DeliveryFilter) (*DeliveryPage, error) {
+	var page DeliveryPage
+	if _, err := c.do(ctx, http.MethodGet, webhookPath(webhookID)+"/deliveries", filter.values(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Deliveries обходит весь журнал доставок вебхука, начиная со страницы filter.Page.
+// Без filter.PageSize доставки читаются страницами наибольшего размера.
+func (c *Client) Deliveries(ctx context.Context, webhookID int, filter DeliveryFilter) *Iterator[Delivery] {
+	if filter.PageSize == 0 {
+		filter.PageSize = maxPageSize
+	}
+	return newIterator(ctx, filter.Page, false, func(ctx context.Context, page int) ([]Delivery, int, error) {
+		filter.Page = page
+		response, err := c.ListDeliveries(ctx, webhookID, filter)
+		if err != nil {
+			return nil, 0, err
+		}
+		return response.Deliveries, response.TotalPages, nil
+	})
+}
+
+// ReplayDeliveries заново ставит в очередь доставку вебхуку всех событий, начиная
+// с fromEventID, и возвращает число поставленных доставок
+func (c *Client) ReplayDeliveries(ctx context.Context, webhookID int, fromEventID int64) (int, error) {
+	req := struct {
+		FromEventID int64 `json:"from_event_id"`
+	}{FromEventID: fromEventID}
+
+	var response struct {
+		Enqueued int `json:"enqueued"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, webhookPath(webhookID)+"/replay", nil, req, &response); err != nil {
+		return 0, err
+	}
+	return response.Enqueued, nil
+}
+
+func webhookPath(id int) string {
+	return "/webhooks/" + strconv.Itoa(id)
+}